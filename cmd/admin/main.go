@@ -0,0 +1,186 @@
+package main
+
+import (
+	"connectfour/internal/client/console/backend"
+	"connectfour/internal/model"
+	"connectfour/internal/service"
+	"connectfour/internal/tracing"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+)
+
+const usage = `Usage: admin [flags] <command> [arguments]
+
+Commands:
+  users [query]              Search the users by e-mail address or name
+  user <email>               Show a user
+  ban <email>                Ban a user, which refuses their login
+  unban <email>              Lift the ban of a user
+  role <email> <role>        Change the role of a user to player, moderator or admin (admins only)
+  bot <email> <true|false>   Make a user a bot account, or a player again (admins only)
+  revoke <email>             Revoke the tokens of a user, like a leaked bot token (admins only)
+  games [status]             List the games, optionally with a status (created, started, finished, aborted)
+  abort <key>                Abort a game that hasn't finished
+  stats                      Show the statistics of the server
+  audit [subject]            Show the audit log, optionally of a game key or e-mail address
+
+Flags:
+`
+
+func main() {
+
+	var email, password, player, actor, action string
+	flag.StringVar(&email, "email", "", "E-mail address to log in with. Without it, the JWT of the console client is used.")
+	flag.StringVar(&password, "password", "", "Password to log in with.")
+	flag.StringVar(&player, "player", "", "Only list the games of this player (with the games command).")
+	flag.StringVar(&actor, "actor", "", "Only show the audit log of this user (with the audit command).")
+	flag.StringVar(&action, "action", "", "Only show this action in the audit log, e.g. user.login_failed.")
+	flag.Usage = func() {
+		_, _ = fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	shutdown, err := tracing.Setup(context.Background(), "connectfour-admin", os.Stderr)
+	if err != nil {
+		log.Fatalf("Setting up the tracing failed: %v\n", err)
+	}
+	defer shutdown(context.Background())
+
+	backend.InitWebClient()
+	wc, err := backend.NewWebClient(
+		backend.WithBaseUrl(backend.ServerUrl),
+		backend.WithStoreInFile(email == "", backend.JwtFileName()),
+		backend.WithReAuthCallback(func() {
+			log.Println("The server rejected the credentials, log in with -email and -password.")
+		}),
+	)
+	if err != nil {
+		log.Fatalf("Could not create the web client: %v\n", err)
+	}
+	if email != "" {
+		if err = backend.Login(wc, email, password); err != nil {
+			log.Fatalf("Logging in failed: %v\n", err)
+		}
+	}
+
+	args := flag.Args()
+	arg := func(i int) string {
+		if i >= len(args) {
+			flag.Usage()
+			os.Exit(2)
+		}
+		return args[i]
+	}
+
+	switch args[0] {
+	case "users":
+		query := ""
+		if len(args) > 1 {
+			query = args[1]
+		}
+		users, err := backend.AdminUsers(wc, query)
+		check(err)
+		printUsers(users...)
+	case "user":
+		user, err := backend.AdminUser(wc, arg(1))
+		check(err)
+		printUsers(user)
+	case "ban", "unban":
+		user, err := backend.Ban(wc, arg(1), args[0] == "ban")
+		check(err)
+		printUsers(user)
+	case "role":
+		user, err := backend.SetRole(wc, arg(1), model.Role(arg(2)))
+		check(err)
+		printUsers(user)
+	case "bot":
+		bot, err := strconv.ParseBool(arg(2))
+		check(err)
+		user, err := backend.SetBot(wc, arg(1), bot)
+		check(err)
+		printUsers(user)
+	case "revoke":
+		user, err := backend.RevokeTokens(wc, arg(1))
+		check(err)
+		printUsers(user)
+	case "games":
+		status := ""
+		if len(args) > 1 {
+			status = args[1]
+		}
+		page, err := backend.AdminGames(wc, player, status)
+		check(err)
+		games := page.Games
+		for page.Next != "" {
+			page, err = backend.AdminGamesPage(wc, page.Next)
+			check(err)
+			games = append(games, page.Games...)
+		}
+		printGames(games)
+	case "abort":
+		game, err := backend.AbortGame(wc, arg(1))
+		check(err)
+		fmt.Printf("Game %s is %s\n", game.Key, game.Status)
+	case "stats":
+		stats, err := backend.Stats(wc)
+		check(err)
+		out, _ := json.MarshalIndent(stats, "", "  ")
+		fmt.Println(string(out))
+	case "audit":
+		subject := ""
+		if len(args) > 1 {
+			subject = args[1]
+		}
+		entries, err := backend.Audit(wc, actor, action, subject)
+		check(err)
+		printAudit(entries)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func check(err error) {
+	if err != nil {
+		log.Fatalf("%v\n", err)
+	}
+}
+
+func printUsers(users ...service.AdminUserResponse) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ID\tE-MAIL\tNAME\tROLE\tBOT\tVERIFIED\tBANNED")
+	for _, u := range users {
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%t\t%t\t%t\n", u.Id, u.Email, u.Name, u.Role, u.Bot, u.Verified, u.Banned)
+	}
+	_ = w.Flush()
+}
+
+func printGames(games []service.GameListingResponse) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "KEY\tSTATUS\tCREATED BY\tCREATED AT\tINVITEE")
+	for _, g := range games {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", g.Key, g.Status, g.CreatedBy, g.CreatedAt.Format("2006-01-02 15:04"), g.Invitee)
+	}
+	_ = w.Flush()
+}
+
+func printAudit(entries []service.AuditEntryResponse) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "TIME\tACTION\tACTOR\tIP\tSUBJECT\tDETAILS")
+	for _, e := range entries {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", e.Time.Format("2006-01-02 15:04:05"), e.Action, e.Actor, e.Ip, e.Subject, e.Details)
+	}
+	_ = w.Flush()
+}
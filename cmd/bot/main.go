@@ -0,0 +1,100 @@
+package main
+
+import (
+	"connectfour/internal/client/bot"
+	"connectfour/internal/client/console/backend"
+	"connectfour/internal/tracing"
+	"context"
+	"flag"
+	"log"
+	"os"
+	"time"
+)
+
+func main() {
+
+	var email, password, name, key, strategyName string
+	var register, create, public bool
+	var games int
+	flag.StringVar(&email, "email", "", "E-mail address of the bot account.")
+	flag.StringVar(&password, "password", "", "Password of the bot account.")
+	flag.StringVar(&name, "name", "", "Name of the bot account (only used with -register).")
+	flag.BoolVar(&register, "register", false, "Register the account of the bot before logging in. An admin makes it a bot account.")
+	flag.StringVar(&key, "key", "", "Play the game with this key.")
+	flag.BoolVar(&create, "create", false, "Create a new game and wait for an opponent, instead of joining one.")
+	flag.BoolVar(&public, "public", true, "Whether the games created with -create are public.")
+	flag.StringVar(&strategyName, "strategy", "greedy", "The strategy to play with (random, first or greedy).")
+	flag.IntVar(&games, "games", 1, "The number of games to play (0 means keep playing).")
+	flag.Parse()
+
+	if email == "" || password == "" {
+		log.Println("Both -email and -password are required.")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	strategy, ok := bot.Strategies[strategyName]
+	if !ok {
+		log.Fatalf("Unknown strategy '%s'\n", strategyName)
+	}
+
+	shutdown, err := tracing.Setup(context.Background(), "connectfour-bot", os.Stdout)
+	if err != nil {
+		log.Fatalf("Setting up the tracing failed: %v\n", err)
+	}
+	defer shutdown(context.Background())
+
+	backend.InitWebClient()
+	wc, err := backend.NewWebClient(
+		backend.WithBaseUrl(backend.ServerUrl),
+		backend.WithReAuthCallback(func() {
+			log.Println("The server rejected the bot's credentials.")
+		}),
+	)
+	if err != nil {
+		log.Fatalf("Could not create the web client: %v\n", err)
+	}
+
+	if register {
+		if name == "" {
+			name = email
+		}
+		if _, err = backend.Register(wc, name, email, password); err != nil {
+			log.Fatalf("Registering the bot failed: %v\n", err)
+		}
+		log.Printf("Registered %s. Until an admin makes it a bot (admin bot %s true), its tokens are valid for a day.\n", email, email)
+	}
+
+	if err = backend.Login(wc, email, password); err != nil {
+		log.Fatalf("Logging in failed: %v\n", err)
+	}
+
+	runner := bot.NewRunner(wc, strategy)
+	for played := 0; games == 0 || played < games; played++ {
+		gameKey := key
+		switch {
+		case gameKey != "":
+			key = "" // only the first game is played with the key from the command line.
+		case create:
+			if gameKey, err = runner.Create(public); err != nil {
+				log.Fatalf("%v\n", err)
+			}
+		default:
+			for gameKey == "" {
+				if gameKey, err = runner.JoinAny(); err != nil {
+					log.Fatalf("Joining a game failed: %v\n", err)
+				}
+				if gameKey == "" {
+					log.Println("No open games found, trying again in a bit...")
+					time.Sleep(10 * time.Second)
+				}
+			}
+		}
+
+		status, err := runner.Play(gameKey)
+		if err != nil {
+			log.Fatalf("Playing game %s failed: %v\n", gameKey, err)
+		}
+		log.Printf("Game %s ended with status %s\n", gameKey, status)
+	}
+}
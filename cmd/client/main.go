@@ -3,6 +3,9 @@ package main
 import (
 	"connectfour/internal/client/console/backend"
 	. "connectfour/internal/client/console/models"
+	"connectfour/internal/logging"
+	"connectfour/internal/tracing"
+	"context"
 	"flag"
 	"fmt"
 	tea "github.com/charmbracelet/bubbletea"
@@ -12,12 +15,19 @@ import (
 
 func main() {
 
-	file, err := tea.LogToFile("./connectfour.log", "cf")
+	file, err := logging.LogToFile("./connectfour.log")
 	if err != nil {
 		panic(fmt.Sprintf("Can't open log output: %v\n", err))
 	}
 	defer file.Close()
 
+	// the screen belongs to the game, so the stdout exporter writes the spans to the log instead.
+	shutdown, err := tracing.Setup(context.Background(), "connectfour-client", file)
+	if err != nil {
+		log.Fatalf("Setting up the tracing failed: %v\n", err)
+	}
+	defer shutdown(context.Background())
+
 	var key string
 	var enableJwtFileStorage bool
 	//	flag.StringVar(&m.PlayerName, "credentials", "", "Pre-specify the user's credentials to skip the first step (use the form email:password, e.g. --credentials player@email.com:thepassword).")
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"connectfour/internal/oidctest"
+	"flag"
+	"log"
+	"net/http"
+)
+
+// The mock identity provider signs everyone in as the configured identity, to try signing in with an identity
+// provider locally. Start the server with CONNECT_FOUR_OIDC_ISSUER=http://localhost:9999,
+// CONNECT_FOUR_OIDC_CLIENT_ID=connectfour and CONNECT_FOUR_OIDC_REDIRECT_URL=http://localhost:8443/oidc/callback.
+func main() {
+
+	var addr, issuer string
+	var identity oidctest.Identity
+	var unverified bool
+	flag.StringVar(&addr, "addr", ":9999", "The address to listen on.")
+	flag.StringVar(&issuer, "issuer", "http://localhost:9999", "The issuer url, as the api and browser reach it.")
+	flag.StringVar(&identity.Subject, "subject", "mock-user-1", "The subject of the identity.")
+	flag.StringVar(&identity.Email, "email", "dick@evilnerd.nl", "The e-mail address of the identity.")
+	flag.StringVar(&identity.Name, "name", "Dick", "The name of the identity.")
+	flag.BoolVar(&unverified, "unverified", false, "Report the e-mail address as not verified.")
+	flag.Parse()
+	identity.EmailVerified = !unverified
+
+	idp, err := oidctest.NewMockIdP(identity)
+	if err != nil {
+		log.Fatalf("Could not create the mock identity provider: %v\n", err)
+	}
+	idp.Issuer = issuer
+
+	log.Printf("Mock identity provider %s signs everyone in as %s (%s)\n", issuer, identity.Email, identity.Subject)
+	if err = http.ListenAndServe(addr, idp); err != nil {
+		log.Fatalf("Error while running the mock identity provider: %v\n", err)
+	}
+}
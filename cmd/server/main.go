@@ -1,35 +1,110 @@
 package main
 
 import (
+	"connectfour/internal/db"
 	"connectfour/internal/handlers"
+	"connectfour/internal/logging"
+	"connectfour/internal/server"
+	"connectfour/internal/tracing"
+	"context"
 	"fmt"
 	"github.com/go-chi/chi/v5"
 	log "github.com/sirupsen/logrus"
-	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
 )
 
 func main() {
 
-	log.SetLevel(log.DebugLevel)
+	logging.Setup()
 	log.Println("ConnectFour Server")
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrate(os.Args[2:]); err != nil {
+			log.Fatalf("Migrating the database: %v", err)
+		}
+		return
+	}
+	if os.Getenv("CONNECT_FOUR_MIGRATE_ON_START") == "true" {
+		if err := migrate([]string{"up"}); err != nil {
+			log.Fatalf("Migrating the database: %v", err)
+		}
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), "connectfour-api", os.Stdout)
+	if err != nil {
+		log.Fatalf("Setting up the tracing: %v", err)
+	}
+
+	handlers.Setup()
 	r := chi.NewRouter()
 	handlers.SetupMiddlewares(r)
 	handlers.SetupRoutes(r)
 
-	port := port()
-	log.Printf("Starting on port %s...\n", port)
-	err := http.ListenAndServe(":"+port, r)
+	// SIGTERM is sent by docker and kubernetes when the api is stopped, SIGINT by ctrl-c.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	err = server.Serve(ctx, r, server.ConfigFromEnv())
+	handlers.Stop()
+
+	if tracingErr := shutdownTracing(context.Background()); tracingErr != nil {
+		log.Warnf("Flushing the traces: %v", tracingErr)
+	}
+	if dbErr := db.Close(); dbErr != nil {
+		log.Warnf("Closing the database connections: %v", dbErr)
+	}
 	if err != nil {
-		fmt.Printf("Error while running the api: %v", err)
+		log.Fatalf("Error while running the api: %v", err)
 	}
+	log.Infoln("Stopped")
 }
 
-func port() string {
-	port, ok := os.LookupEnv("CONNECT_FOUR_SERVER_PORT")
-	if !ok {
-		port = "8443"
+// migrate runs "up", "down [steps]" or "status" on the database schema.
+func migrate(args []string) error {
+	migrator, err := db.NewMigrator()
+	if err != nil {
+		return err
+	}
+	command := "status"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up()
+		if err != nil {
+			return err
+		}
+		log.Printf("Applied %d migration(s), the schema is up to date", len(applied))
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("down needs a number of steps of at least 1, not '%s'", args[1])
+			}
+		}
+		reverted, err := migrator.Down(steps)
+		if err != nil {
+			return err
+		}
+		log.Printf("Reverted %d migration(s)", len(reverted))
+	case "status":
+		migrations, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			applied := "pending"
+			if !m.AppliedAt.IsZero() {
+				applied = "applied " + m.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d %-32s %s\n", m.Version, m.Name, applied)
+		}
+	default:
+		return fmt.Errorf("unknown command '%s', use up, down [steps] or status", command)
 	}
-	return port
+	return nil
 }
@@ -0,0 +1,88 @@
+package bot
+
+import (
+	"connectfour/internal/client/console/backend"
+	"connectfour/internal/model"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// turnWaitSeconds is how long a single long-poll request waits for the opponent.
+const turnWaitSeconds = 30
+
+// Runner plays games on behalf of a bot account, using the Strategy to pick moves.
+type Runner struct {
+	wc       *backend.WebClient
+	strategy Strategy
+}
+
+func NewRunner(wc *backend.WebClient, strategy Strategy) *Runner {
+	return &Runner{
+		wc:       wc,
+		strategy: strategy,
+	}
+}
+
+// Create starts a new game and returns its key, so it can be passed to Play.
+func (r *Runner) Create(public bool) (string, error) {
+	game := backend.CreateGame(r.wc, public, "")
+	if game.Key == "" {
+		return "", errors.New("the game could not be created")
+	}
+	log.Printf("Created game %s (public: %t)\n", game.Key, public)
+	return game.Key, nil
+}
+
+// JoinAny joins the first open public game that was created by somebody else, returning its key. It returns an
+// empty key when there is no game to join.
+func (r *Runner) JoinAny() (string, error) {
+	_, me, _ := r.wc.Identify()
+	for page := backend.JoinableGames(r.wc); ; page = backend.NextGames(r.wc, page.Next) {
+		for _, game := range page.Games {
+			if strings.EqualFold(game.CreatedBy, me) {
+				continue
+			}
+			if _, err := backend.Join(r.wc, game.Key); err != nil {
+				return "", err
+			}
+			log.Printf("Joined game %s, created by %s\n", game.Key, game.CreatedBy)
+			return game.Key, nil
+		}
+		if page.Next == "" {
+			return "", nil
+		}
+	}
+}
+
+// Play joins the game with the specified key (if needed) and plays it to the end. It returns the final state.
+func (r *Runner) Play(key string) (model.GameStatus, error) {
+	if _, err := backend.Join(r.wc, key); err != nil {
+		return model.Unknown, fmt.Errorf("joining game %s failed: %w", key, err)
+	}
+
+	for {
+		turn, err := backend.WaitForTurn(r.wc, key, turnWaitSeconds)
+		if err != nil {
+			return model.Unknown, err
+		}
+
+		switch turn.State.Status {
+		case model.Finished, model.Aborted:
+			log.Printf("Game %s is over (%s)\n", key, turn.State.Status)
+			return turn.State.Status, nil
+		}
+
+		if !turn.YourTurn {
+			continue
+		}
+
+		board := *model.FromMap(turn.State.Board)
+		col := r.strategy.NextMove(board, turn.Disc)
+		log.Printf("Game %s: playing column %d\n", key, col+1)
+		if _, err = backend.Move(r.wc, key, col+1); err != nil { // the api expects 1-7 for columns.
+			return model.Unknown, err
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package bot
+
+import (
+	"connectfour/internal/model"
+	"math/rand"
+)
+
+// Strategy decides which move a bot plays. NextMove gets the current board and the disc that the bot plays with,
+// and returns the (0-based) column to drop the disc in.
+type Strategy interface {
+	NextMove(board model.Board, me model.Disc) int
+}
+
+// StrategyFunc allows a plain func to be used as a Strategy.
+type StrategyFunc func(board model.Board, me model.Disc) int
+
+func (f StrategyFunc) NextMove(board model.Board, me model.Disc) int {
+	return f(board, me)
+}
+
+// Strategies lists the built-in strategies by name, so they can be selected on the command line.
+var Strategies = map[string]Strategy{
+	"random": StrategyFunc(Random),
+	"first":  StrategyFunc(FirstAvailable),
+	"greedy": StrategyFunc(Greedy),
+}
+
+// Random plays a random column that still has room.
+func Random(board model.Board, _ model.Disc) int {
+	moves := board.ValidMoves()
+	if len(moves) == 0 {
+		return 0
+	}
+	return moves[rand.Intn(len(moves))]
+}
+
+// FirstAvailable plays the left-most column that still has room.
+func FirstAvailable(board model.Board, _ model.Disc) int {
+	moves := board.ValidMoves()
+	if len(moves) == 0 {
+		return 0
+	}
+	return moves[0]
+}
+
+// Greedy plays a winning move when there is one, blocks the opponent's winning move when there is one and
+// plays a random move otherwise.
+func Greedy(board model.Board, me model.Disc) int {
+	opponent := model.Disc(model.RedDisc)
+	if me == model.RedDisc {
+		opponent = model.YellowDisc
+	}
+
+	for _, disc := range []model.Disc{me, opponent} {
+		for _, col := range board.ValidMoves() {
+			next := board
+			next.AddDisc(col, disc)
+			if next.HasConnectFour() {
+				return col
+			}
+		}
+	}
+
+	return Random(board, me)
+}
@@ -0,0 +1,49 @@
+package bot
+
+import (
+	"connectfour/internal/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGreedy_PlaysWinningMove(t *testing.T) {
+	// Arrange
+	b := model.Board{}
+	b.AddDisc(0, model.RedDisc)
+	b.AddDisc(1, model.RedDisc)
+	b.AddDisc(2, model.RedDisc)
+
+	// Act
+	col := Greedy(b, model.RedDisc)
+
+	// Assert
+	assert.Equal(t, 3, col, "Expected the bot to complete the row")
+}
+
+func TestGreedy_BlocksOpponent(t *testing.T) {
+	// Arrange
+	b := model.Board{}
+	b.AddDisc(4, model.RedDisc)
+	b.AddDisc(4, model.RedDisc)
+	b.AddDisc(4, model.RedDisc)
+
+	// Act
+	col := Greedy(b, model.YellowDisc)
+
+	// Assert
+	assert.Equal(t, 4, col, "Expected the bot to block the vertical connect four")
+}
+
+func TestFirstAvailable_SkipsFullColumns(t *testing.T) {
+	// Arrange
+	b := model.Board{}
+	for row := 0; row < model.BoardHeight; row++ {
+		b.AddDisc(0, model.YellowDisc)
+	}
+
+	// Act
+	col := FirstAvailable(b, model.RedDisc)
+
+	// Assert
+	assert.Equal(t, 1, col)
+}
@@ -0,0 +1,140 @@
+package backend
+
+import (
+	"connectfour/internal/model"
+	"connectfour/internal/service"
+	"net/http"
+	"net/url"
+)
+
+// AdminUsers searches the users by e-mail address or name. Moderators and admins only.
+func AdminUsers(wc *WebClient, query string) ([]service.AdminUserResponse, error) {
+	resp := make([]service.AdminUserResponse, 0)
+	err := wc.Call(
+		http.MethodGet,
+		wc.Url("admin", "users")+"?"+url.Values{"q": {query}}.Encode(),
+		&resp,
+	)
+	return resp, err
+}
+
+// AdminUser returns the user with the e-mail address. Moderators and admins only.
+func AdminUser(wc *WebClient, email string) (service.AdminUserResponse, error) {
+	var resp service.AdminUserResponse
+	err := wc.Call(
+		http.MethodGet,
+		wc.Url("admin", "users", email),
+		&resp,
+	)
+	return resp, err
+}
+
+// Ban bans the user, or lifts the ban. Moderators and admins only.
+func Ban(wc *WebClient, email string, banned bool) (service.AdminUserResponse, error) {
+	action := "ban"
+	if !banned {
+		action = "unban"
+	}
+	var resp service.AdminUserResponse
+	err := wc.Call(
+		http.MethodPost,
+		wc.Url("admin", "users", email, action),
+		&resp,
+	)
+	return resp, err
+}
+
+// SetRole changes the role of the user. Admins only.
+func SetRole(wc *WebClient, email string, role model.Role) (service.AdminUserResponse, error) {
+	var resp service.AdminUserResponse
+	err := wc.CallWithBody(
+		http.MethodPut,
+		wc.Url("admin", "users", email, "role"),
+		service.SetRoleRequest{Role: role},
+		&resp,
+	)
+	return resp, err
+}
+
+// SetBot makes the user a bot account, or a player again. Admins only.
+func SetBot(wc *WebClient, email string, bot bool) (service.AdminUserResponse, error) {
+	var resp service.AdminUserResponse
+	err := wc.CallWithBody(
+		http.MethodPut,
+		wc.Url("admin", "users", email, "bot"),
+		service.SetBotRequest{Bot: bot},
+		&resp,
+	)
+	return resp, err
+}
+
+// RevokeTokens revokes the tokens that were issued to the user so far. Admins only.
+func RevokeTokens(wc *WebClient, email string) (service.AdminUserResponse, error) {
+	var resp service.AdminUserResponse
+	err := wc.Call(
+		http.MethodPost,
+		wc.Url("admin", "users", email, "revoke"),
+		&resp,
+	)
+	return resp, err
+}
+
+// AdminGames returns the first page of the games with the status of the player, where empty means all. The next
+// pages are fetched with AdminGamesPage. Moderators and admins only.
+func AdminGames(wc *WebClient, player string, status string) (GamePage, error) {
+	params := url.Values{}
+	if player != "" {
+		params.Set("player", player)
+	}
+	if status != "" {
+		params.Set("status", status)
+	}
+	return AdminGamesPage(wc, wc.Url("admin", "games")+"?"+params.Encode())
+}
+
+// AdminGamesPage returns the page of games at the url, which is the Next url of the previous page.
+func AdminGamesPage(wc *WebClient, url string) (GamePage, error) {
+	page := GamePage{Games: make([]service.GameListingResponse, 0)}
+	next, err := wc.CallPage(url, &page.Games)
+	page.Next = next
+	return page, err
+}
+
+// AbortGame aborts a game that hasn't finished yet. Moderators and admins only.
+func AbortGame(wc *WebClient, key string) (service.GameStateResponse, error) {
+	var resp service.GameStateResponse
+	err := wc.Call(
+		http.MethodPost,
+		wc.Url("admin", "games", key, "abort"),
+		&resp,
+	)
+	return resp, err
+}
+
+// Stats returns the statistics of the server. Moderators and admins only.
+func Stats(wc *WebClient) (service.StatsResponse, error) {
+	var resp service.StatsResponse
+	err := wc.Call(
+		http.MethodGet,
+		wc.Url("admin", "stats"),
+		&resp,
+	)
+	return resp, err
+}
+
+// Audit returns the newest entries of the audit log that match the filter. Moderators and admins only.
+func Audit(wc *WebClient, actor string, action string, subject string) ([]service.AuditEntryResponse, error) {
+	resp := make([]service.AuditEntryResponse, 0)
+	params := url.Values{}
+	for name, value := range map[string]string{"actor": actor, "action": action, "subject": subject} {
+		if value != "" {
+			params.Set(name, value)
+		}
+	}
+	err := wc.Call(
+		http.MethodGet,
+		wc.Url("admin", "audit")+"?"+params.Encode(),
+		&resp,
+	)
+	return resp, err
+}
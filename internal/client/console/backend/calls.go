@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
 
 	"log"
 )
@@ -45,6 +46,19 @@ func Hello() error {
 	return nil
 }
 
+// Register creates a new account on the server. An admin can make it a bot account, which receives long-lived tokens
+// when it logs in.
+func Register(wc *WebClient, name string, email string, password string) (service.CreateUserResponse, error) {
+	req := service.RegisterRequest{
+		Name:     name,
+		Email:    email,
+		Password: password,
+	}
+	var resp service.CreateUserResponse
+	err := wc.CallRegister(wc.Url("register"), req, &resp)
+	return resp, err
+}
+
 func Login(wc *WebClient, email string, password string) (err error) {
 	req := service.LoginRequest{
 		Email:    email,
@@ -53,40 +67,52 @@ func Login(wc *WebClient, email string, password string) (err error) {
 	return wc.CallLogin(wc.Url("login"), req)
 }
 
-// JoinableGames returns a list of games that the player can join.
-func JoinableGames(wc *WebClient) []service.NewGameResponse {
-	resp := make([]service.NewGameResponse, 0)
-	err := wc.Call(
-		http.MethodGet,
-		wc.Url("games"),
-		&resp,
-	)
-	if err != nil {
-		return resp
-	}
-	return resp
+// ForgotPassword asks the server to mail a password reset token to the player.
+func ForgotPassword(wc *WebClient, email string) error {
+	var resp map[string]string
+	return wc.CallAnonymous(wc.Url("password", "forgot"), service.ForgotPasswordRequest{Email: email}, &resp)
 }
 
-// MyGames returns a list of running games that the user is a part of.
-func MyGames(wc *WebClient) []service.NewGameResponse {
-	resp := make([]service.NewGameResponse, 0)
-	err := wc.Call(
-		http.MethodGet,
-		wc.Url("games", "my"),
-		&resp,
-	)
+// ResetPassword sets a new password, using the reset token from the e-mail.
+func ResetPassword(wc *WebClient, token string, password string) error {
+	var resp map[string]string
+	return wc.CallAnonymous(wc.Url("password", "reset"), service.ResetPasswordRequest{Token: token, Password: password}, &resp)
+}
+
+// GamePage is a page of games. Next is the url of the next page, which is empty on the last page.
+type GamePage struct {
+	Games []service.GameListingResponse
+	Next  string
+}
+
+// JoinableGames returns the first page of the games that the player can join.
+func JoinableGames(wc *WebClient) GamePage {
+	return NextGames(wc, wc.Url("games"))
+}
+
+// MyGames returns the first page of the games that the user is a part of, the newest first.
+func MyGames(wc *WebClient) GamePage {
+	return NextGames(wc, wc.Url("games", "my"))
+}
+
+// NextGames returns the page of games at the url, which is the Next url of the previous page.
+func NextGames(wc *WebClient, url string) GamePage {
+	page := GamePage{Games: make([]service.GameListingResponse, 0)}
+	next, err := wc.CallPage(url, &page.Games)
 	if err != nil {
-		return resp
+		return GamePage{Games: make([]service.GameListingResponse, 0)}
 	}
-	return resp
+	page.Next = next
+	return page
 }
 
-func CreateGame(wc *WebClient, public bool) service.NewGameResponse {
+// CreateGame creates a new game. When the invitee's e-mail address is specified, only that player can join.
+func CreateGame(wc *WebClient, public bool, invitee string) service.NewGameResponse {
 	var resp service.NewGameResponse
 	err := wc.CallWithBody(
 		http.MethodPost,
 		wc.Url("games"),
-		service.NewGameRequest{Public: public},
+		service.NewGameRequest{Public: public, Invitee: invitee},
 		&resp,
 	)
 	if err != nil {
@@ -128,6 +154,21 @@ func Move(wc *WebClient, key string, column int) (service.GameStateResponse, err
 	return resp, nil
 }
 
+// WaitForTurn long-polls the api until it's our turn in the game, or the game is over, or the specified number of
+// seconds have passed.
+func WaitForTurn(wc *WebClient, key string, waitSeconds int) (service.TurnResponse, error) {
+	var resp service.TurnResponse
+	err := wc.Call(
+		http.MethodGet,
+		wc.Url("games", key, "turn")+"?wait="+strconv.Itoa(waitSeconds),
+		&resp,
+	)
+	if err != nil {
+		return service.TurnResponse{}, err
+	}
+	return resp, nil
+}
+
 // Join tells the api that the player wants to join an existing game.
 func Join(wc *WebClient, key string) (service.GameStateResponse, error) {
 	var resp service.GameStateResponse
@@ -141,3 +182,194 @@ func Join(wc *WebClient, key string) (service.GameStateResponse, error) {
 	}
 	return resp, nil
 }
+
+// Tournaments returns the list of all tournaments.
+func Tournaments(wc *WebClient) ([]service.TournamentResponse, error) {
+	resp := make([]service.TournamentResponse, 0)
+	err := wc.Call(
+		http.MethodGet,
+		wc.Url("tournaments"),
+		&resp,
+	)
+	return resp, err
+}
+
+// Tournament returns a tournament including its standings and pairings.
+func Tournament(wc *WebClient, id int64) (service.TournamentResponse, error) {
+	var resp service.TournamentResponse
+	err := wc.Call(
+		http.MethodGet,
+		wc.Url("tournaments", strconv.FormatInt(id, 10)),
+		&resp,
+	)
+	return resp, err
+}
+
+// RegisterForTournament registers the player for a tournament that hasn't started yet.
+func RegisterForTournament(wc *WebClient, id int64) (service.TournamentResponse, error) {
+	var resp service.TournamentResponse
+	err := wc.Call(
+		http.MethodPost,
+		wc.Url("tournaments", strconv.FormatInt(id, 10), "register"),
+		&resp,
+	)
+	return resp, err
+}
+
+// Invitations returns the games that the player was invited to.
+func Invitations(wc *WebClient) []service.InvitationResponse {
+	resp := make([]service.InvitationResponse, 0)
+	err := wc.Call(
+		http.MethodGet,
+		wc.Url("invitations"),
+		&resp,
+	)
+	if err != nil {
+		return resp
+	}
+	return resp
+}
+
+// AcceptInvitation joins the game that the player was invited to.
+func AcceptInvitation(wc *WebClient, key string) (service.GameStateResponse, error) {
+	var resp service.GameStateResponse
+	err := wc.Call(
+		http.MethodPost,
+		wc.Url("invitations", key, "accept"),
+		&resp,
+	)
+	if err != nil {
+		return service.GameStateResponse{}, err
+	}
+	return resp, nil
+}
+
+// DeclineInvitation refuses the invitation, which aborts the game.
+func DeclineInvitation(wc *WebClient, key string) error {
+	var resp service.GameStateResponse
+	return wc.Call(
+		http.MethodPost,
+		wc.Url("invitations", key, "decline"),
+		&resp,
+	)
+}
+
+// Friends returns the friends of the player, including the pending requests.
+func Friends(wc *WebClient) ([]service.FriendResponse, error) {
+	resp := make([]service.FriendResponse, 0)
+	err := wc.Call(
+		http.MethodGet,
+		wc.Url("friends"),
+		&resp,
+	)
+	return resp, err
+}
+
+// RequestFriend sends a friend request to the player with the given e-mail address.
+func RequestFriend(wc *WebClient, email string) ([]service.FriendResponse, error) {
+	resp := make([]service.FriendResponse, 0)
+	err := wc.CallWithBody(
+		http.MethodPost,
+		wc.Url("friends"),
+		service.FriendRequest{Email: email},
+		&resp,
+	)
+	return resp, err
+}
+
+// AcceptFriend accepts the friend request of the player with the given e-mail address.
+func AcceptFriend(wc *WebClient, email string) ([]service.FriendResponse, error) {
+	resp := make([]service.FriendResponse, 0)
+	err := wc.Call(
+		http.MethodPost,
+		wc.Url("friends", email, "accept"),
+		&resp,
+	)
+	return resp, err
+}
+
+// RemoveFriend removes the friend (or declines the friend request).
+func RemoveFriend(wc *WebClient, email string) ([]service.FriendResponse, error) {
+	resp := make([]service.FriendResponse, 0)
+	err := wc.Call(
+		http.MethodDelete,
+		wc.Url("friends", email),
+		&resp,
+	)
+	return resp, err
+}
+
+// Profile returns the profile of the player.
+func Profile(wc *WebClient) (service.ProfileResponse, error) {
+	var resp service.ProfileResponse
+	err := wc.Call(
+		http.MethodGet,
+		wc.Url("me"),
+		&resp,
+	)
+	return resp, err
+}
+
+// Rename changes the display name of the player, and continues with the new JWT that contains the new name.
+func Rename(wc *WebClient, name string) (service.ProfileResponse, error) {
+	var resp service.ProfileResponse
+	err := wc.CallWithBody(
+		http.MethodPatch,
+		wc.Url("me"),
+		service.UpdateProfileRequest{Name: name},
+		&resp,
+	)
+	if err != nil {
+		return service.ProfileResponse{}, err
+	}
+	wc.UseJwt([]byte(resp.Token))
+	return resp, nil
+}
+
+// ChangePassword changes the password of the player.
+func ChangePassword(wc *WebClient, oldPassword string, newPassword string) error {
+	var resp map[string]string
+	return wc.CallWithBody(
+		http.MethodPost,
+		wc.Url("me", "password"),
+		service.ChangePasswordRequest{OldPassword: oldPassword, NewPassword: newPassword},
+		&resp,
+	)
+}
+
+// DeleteAccount deletes the account of the player and forgets the JWT.
+func DeleteAccount(wc *WebClient, password string) error {
+	var resp map[string]string
+	err := wc.CallWithBody(
+		http.MethodDelete,
+		wc.Url("me"),
+		service.DeleteAccountRequest{Password: password},
+		&resp,
+	)
+	if err != nil {
+		return err
+	}
+	wc.Forget()
+	return nil
+}
+
+// Verify verifies the e-mail address of the player with the code from the e-mail.
+func Verify(wc *WebClient, code string) error {
+	var resp map[string]string
+	return wc.CallWithBody(
+		http.MethodPost,
+		wc.Url("verify"),
+		service.VerifyRequest{Code: code},
+		&resp,
+	)
+}
+
+// ResendVerification asks the server to mail a new verification code.
+func ResendVerification(wc *WebClient) error {
+	var resp map[string]string
+	return wc.Call(
+		http.MethodPost,
+		wc.Url("verify", "resend"),
+		&resp,
+	)
+}
@@ -0,0 +1,115 @@
+package backend
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// OidcLogin is a sign-in with the identity provider of the server. The server redirects the browser back to a
+// loopback address that this client listens on, passing the JWT.
+type OidcLogin struct {
+	Url      string // the url to open in the browser.
+	state    string
+	listener net.Listener
+	server   *http.Server
+	result   chan oidcResult
+}
+
+type oidcResult struct {
+	token string
+	err   error
+}
+
+// StartOidcLogin starts listening on a loopback address and returns the url that starts the sign-in.
+func StartOidcLogin(wc *WebClient) (*OidcLogin, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("could not listen for the sign-in callback: %w", err)
+	}
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+
+	l := &OidcLogin{
+		state:    hex.EncodeToString(b),
+		listener: listener,
+		result:   make(chan oidcResult, 1),
+	}
+	params := url.Values{}
+	params.Set("redirect_uri", fmt.Sprintf("http://%s/callback", listener.Addr().String()))
+	params.Set("state", l.state)
+	l.Url = wc.Url("oidc", "login") + "?" + params.Encode()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", l.callback)
+	l.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := l.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("The sign-in callback listener stopped: %v\n", err)
+		}
+	}()
+	return l, nil
+}
+
+func (l *OidcLogin) callback(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var res oidcResult
+	switch {
+	case q.Get("state") != l.state:
+		res.err = errors.New("the sign-in callback doesn't belong to this sign-in")
+	case q.Get("error") != "":
+		res.err = errors.New(q.Get("error"))
+	default:
+		res.token = q.Get("token")
+	}
+
+	if res.err != nil {
+		http.Error(w, "Signing in failed: "+res.err.Error(), http.StatusBadRequest)
+	} else {
+		_, _ = w.Write([]byte("You are signed in. You can close this window and return to ConnectFour."))
+	}
+	select {
+	case l.result <- res:
+	default: // only the first callback counts.
+	}
+}
+
+// Wait waits until the sign-in completes and then uses the JWT that was received.
+func (l *OidcLogin) Wait(wc *WebClient, timeout time.Duration) error {
+	defer func() { _ = l.server.Close() }()
+	select {
+	case res := <-l.result:
+		if res.err != nil {
+			return res.err
+		}
+		if res.token == "" {
+			return errors.New("no token was received")
+		}
+		wc.UseJwt([]byte(res.token))
+		return nil
+	case <-time.After(timeout):
+		return errors.New("the sign-in took too long")
+	}
+}
+
+// OpenBrowser tries to open the url in the default browser.
+func OpenBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
@@ -2,21 +2,25 @@ package backend
 
 import (
 	"bytes"
+	"connectfour/internal/tracing"
 	"encoding/json"
 	"errors"
 	"fmt"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/golang-jwt/jwt"
+	"github.com/golang-jwt/jwt/v5"
 	"io"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
 	"strings"
+	"sync"
 	"time"
 )
 
 type WebClient struct {
+	httpClient     *http.Client
+	etags          *etagCache
 	jwt            []byte
 	isValid        bool
 	reAuthCallback func()
@@ -29,13 +33,41 @@ type WebClient struct {
 
 type WebClientOption func(*WebClient) error
 
+// etagCache keeps the GET responses that had an ETag, so that they're requested again with If-None-Match, and
+// reused when the api responds with 304 Not Modified. Like the state of the game that's polled while it's played.
+type etagCache struct {
+	mu        sync.Mutex
+	responses map[string]etagResponse
+}
+
+type etagResponse struct {
+	etag string
+	body []byte
+}
+
+func (c *etagCache) load(url string) (etagResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, ok := c.responses[url]
+	return r, ok
+}
+
+func (c *etagCache) store(url string, r etagResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.responses[url] = r
+}
+
 // region Constructor
 
 // NewWebClient returns a new WebClient struct, optionally initialized with one or more WebClientOption parameters.
 // Some WebClientOption may return an error, the first error caught is returned through the error return var.
 func NewWebClient(options ...WebClientOption) (*WebClient, error) {
 	client := &WebClient{
-		baseUrl: ServerUrl,
+		// the transport passes the trace context on, so the spans of the api continue the trace of the client.
+		httpClient: &http.Client{Transport: tracing.Transport(nil)},
+		etags:      &etagCache{responses: make(map[string]etagResponse)},
+		baseUrl:    ServerUrl,
 	}
 	for _, option := range options {
 		err := option(client)
@@ -157,7 +189,7 @@ func (wc *WebClient) CallLogin(url string, body any) error {
 	buf := bytes.NewBuffer(bodyJson)
 	wc.isValid = false
 
-	response, err := http.Post(url, "application/json", buf)
+	response, err := wc.httpClient.Post(url, "application/json", buf)
 	if err != nil {
 		log.Printf("There was an error making a request to the api: %v\n", err)
 		return err
@@ -167,6 +199,10 @@ func (wc *WebClient) CallLogin(url string, body any) error {
 		log.Printf("The api responded with an error: %d - %s\n", response.StatusCode, response.Status)
 		if response.StatusCode == http.StatusUnauthorized {
 			return errors.New("invalid credentials")
+		} else if response.StatusCode == http.StatusForbidden {
+			return errors.New("this account is banned")
+		} else if response.StatusCode == http.StatusTooManyRequests {
+			return fmt.Errorf("too many failed attempts, try again in %s seconds", response.Header.Get("Retry-After"))
 		} else {
 			return errors.New(fmt.Sprintf("Login failed: %d - %s", response.StatusCode, response.Status))
 		}
@@ -195,7 +231,92 @@ func (wc *WebClient) CallLogin(url string, body any) error {
 
 }
 
+// CallRegister posts the registration request to the api. It doesn't need (or send) a JWT.
+func (wc *WebClient) CallRegister(url string, body any, output any) error {
+
+	bodyJson, _ := json.Marshal(body)
+	response, err := wc.httpClient.Post(url, "application/json", bytes.NewBuffer(bodyJson))
+	if err != nil {
+		log.Printf("There was an error making a request to the api: %v\n", err)
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusCreated {
+		log.Printf("The api responded with an error: %d - %s\n", response.StatusCode, response.Status)
+		if response.StatusCode == http.StatusConflict {
+			return errors.New("a user with that e-mail address already exists")
+		}
+		return fmt.Errorf("registration failed: %d - %s", response.StatusCode, response.Status)
+	}
+
+	err = json.NewDecoder(response.Body).Decode(output)
+	if err != nil {
+		log.Printf("Decoding the response failed: %v\n", err)
+		return fmt.Errorf("decoding the response failed %w", err)
+	}
+	return nil
+}
+
+// CallAnonymous posts the body to an api endpoint that doesn't need a JWT, like the password reset.
+func (wc *WebClient) CallAnonymous(url string, body any, output any) error {
+
+	bodyJson, _ := json.Marshal(body)
+	response, err := wc.httpClient.Post(url, "application/json", bytes.NewBuffer(bodyJson))
+	if err != nil {
+		log.Printf("There was an error making a request to the api: %v\n", err)
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		log.Printf("The api responded with an error: %d - %s\n", response.StatusCode, response.Status)
+		return fmt.Errorf("server responded with error: %d %s", response.StatusCode, response.Status)
+	}
+
+	err = json.NewDecoder(response.Body).Decode(output)
+	if err != nil {
+		log.Printf("Decoding the response failed: %v\n", err)
+		return fmt.Errorf("decoding the response failed %w", err)
+	}
+	return nil
+}
+
 func (wc *WebClient) CallWithBody(method string, url string, body any, output any) error {
+	_, err := wc.call(method, url, body, output)
+	return err
+}
+
+// CallPage gets a page of a listing into 'output', and returns the url of the next page from the Link header. It's
+// empty on the last page.
+func (wc *WebClient) CallPage(url string, output any) (next string, err error) {
+	response, err := wc.call(http.MethodGet, url, nil, output)
+	if err != nil {
+		return "", err
+	}
+	return nextLink(response), nil
+}
+
+// nextLink returns the url of the rel="next" link of the response, resolved against the url of the request.
+func nextLink(response *http.Response) string {
+	for _, link := range strings.Split(response.Header.Get("Link"), ",") {
+		target, params, found := strings.Cut(strings.TrimSpace(link), ";")
+		if !found || !strings.Contains(strings.ReplaceAll(params, " ", ""), `rel="next"`) {
+			continue
+		}
+		next, err := response.Request.URL.Parse(strings.Trim(strings.TrimSpace(target), "<>"))
+		if err != nil {
+			log.Printf("The next link %s is invalid: %v\n", target, err)
+			return ""
+		}
+		return next.String()
+	}
+	return ""
+}
+
+// call makes the request and decodes the JSON that is returned into 'output'. The body of the response that is
+// returned has been read and closed already.
+func (wc *WebClient) call(method string, url string, body any, output any) (*http.Response, error) {
 
 	if wc.IsExpired() {
 		wc.reAuth()
@@ -211,34 +332,47 @@ func (wc *WebClient) CallWithBody(method string, url string, body any, output an
 		req, _ = http.NewRequest(method, url, nil)
 	}
 	req.Header.Add("Authorization", "Bearer "+string(wc.jwt))
-	client := &http.Client{}
+	cached, hasCached := wc.etags.load(url)
+	if method == http.MethodGet && hasCached {
+		req.Header.Set("If-None-Match", cached.etag)
+	}
 	// Make the actual request
-	response, err := client.Do(req)
+	response, err := wc.httpClient.Do(req)
 	if err != nil {
 		log.Printf("Request failed: %v\n", err)
-		return fmt.Errorf("making the request to the server failed: %w", err)
+		return nil, fmt.Errorf("making the request to the server failed: %w", err)
 	}
-	if response.StatusCode != http.StatusOK {
-		if response.StatusCode == http.StatusUnauthorized {
-			// indicate that we need to (re)authenticate
-			wc.reAuth()
-			return errors.New("invalid credentials - please authenticate")
+	defer response.Body.Close()
+
+	var responseJson []byte
+	switch {
+	case response.StatusCode == http.StatusNotModified && hasCached:
+		responseJson = cached.body
+	case response.StatusCode == http.StatusOK:
+		responseJson, err = io.ReadAll(response.Body)
+		if err != nil {
+			log.Printf("Reading the response failed: %v\n", err)
+			return nil, fmt.Errorf("reading the response failed %w", err)
+		}
+		if etag := response.Header.Get("ETag"); etag != "" && method == http.MethodGet {
+			wc.etags.store(url, etagResponse{etag: etag, body: responseJson})
 		}
+	case response.StatusCode == http.StatusUnauthorized:
+		// indicate that we need to (re)authenticate
+		wc.reAuth()
+		return nil, errors.New("invalid credentials - please authenticate")
+	default:
 		log.Printf("The api responded with an error: %d - %s\n", response.StatusCode, response.Status)
-		return errors.New(fmt.Sprintf("server responded with error: %d %s", response.StatusCode, response.Status))
+		return nil, errors.New(fmt.Sprintf("server responded with error: %d %s", response.StatusCode, response.Status))
 	}
 
-	defer response.Body.Close()
-
-	dec := json.NewDecoder(response.Body)
-	err = dec.Decode(output)
-
+	err = json.Unmarshal(responseJson, output)
 	if err != nil {
 		log.Printf("Decoding the response failed: %v\n", err)
-		return fmt.Errorf("decoding the response failed %w", err)
+		return nil, fmt.Errorf("decoding the response failed %w", err)
 	}
 
-	return nil
+	return response, nil
 }
 
 // endregion
@@ -249,13 +383,33 @@ func (wc *WebClient) CallWithBody(method string, url string, body any, output an
 // app should ask the user for their credentials.
 func (wc *WebClient) reAuth() {
 	log.Printf("The login credentials were invalid and re-authentication is required.")
+	wc.Forget()
+	if wc.reAuthCallback != nil {
+		wc.reAuthCallback()
+	}
+}
+
+// UseJwt replaces the current JWT, e.g. when the api returns a new one after the profile changed.
+func (wc *WebClient) UseJwt(jwt []byte) {
+	if len(jwt) == 0 {
+		return
+	}
+	wc.jwt = jwt
+	_, _, wc.exp = wc.Identify()
+	wc.isValid = true
+	if wc.hasFilePath && wc.storeInFile {
+		wc.writeJwtToFile()
+	}
+}
+
+// Forget removes the current JWT (also from disk), without asking for the credentials again.
+func (wc *WebClient) Forget() {
 	wc.jwt = nil
 	wc.isValid = false
 	wc.exp = 0
 	if wc.storeInFile {
 		wc.removeJwtFile()
 	}
-	wc.reAuthCallback()
 }
 
 // IsValid returns whether the current JWT is considered valid.
@@ -265,7 +419,7 @@ func (wc *WebClient) IsValid() bool {
 
 // Identify reads the JWT token to extract the name, email and expiry date.
 func (wc *WebClient) Identify() (name string, email string, exp float64) {
-	token, _, err := new(jwt.Parser).ParseUnverified(string(wc.jwt), jwt.MapClaims{})
+	token, _, err := jwt.NewParser().ParseUnverified(string(wc.jwt), jwt.MapClaims{})
 	if err != nil {
 		log.Printf("ERROR: Could not parse jwt token: %v", err)
 		return "", "", 0
@@ -6,7 +6,9 @@ import (
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"log"
 	"strings"
+	"time"
 )
 
 type AskNameModel struct {
@@ -17,6 +19,20 @@ type AskNameModel struct {
 	PasswordText   textinput.Model
 	ErrorMessage   string
 	IsFailed       bool
+	IsResetting    bool            // set when the player forgot their password and a reset token was mailed.
+	TokenText      textinput.Model // the reset token from the e-mail.
+	OidcUrl        string          // set while the player signs in with the identity provider in the browser.
+}
+
+// OidcStartedMsg is sent when the sign-in with the identity provider was started.
+type OidcStartedMsg struct {
+	login        *backend.OidcLogin
+	errorMessage string
+}
+
+// ResetRequestedMsg is sent when the server was asked to mail a password reset token.
+type ResetRequestedMsg struct {
+	errorMessage string
 }
 
 func (s Step) String() string { return string(s) }
@@ -26,6 +42,7 @@ func NewAskNameModel(state *State) *AskNameModel {
 		State:        state,
 		EmailText:    textinput.New(),
 		PasswordText: textinput.New(),
+		TokenText:    textinput.New(),
 	}
 	m.EmailText.Placeholder = "Your e-mail"
 	m.EmailText.Focus()
@@ -35,6 +52,9 @@ func NewAskNameModel(state *State) *AskNameModel {
 	m.PasswordText.Focus()
 	m.PasswordText.CharLimit = 100
 	m.PasswordText.Width = 50
+	m.TokenText.Placeholder = "The token from the e-mail"
+	m.TokenText.CharLimit = 64
+	m.TokenText.Width = 50
 	return m
 }
 
@@ -65,6 +85,10 @@ func (m AskNameModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 
 	case LoginMsg:
+		if msg.isValid && m.OidcUrl != "" {
+			m.PlayerName, m.PlayerEmail, _ = m.wc.Identify()
+		}
+		m.OidcUrl = ""
 		if msg.isValid {
 			m.IsFailed = false
 			m.MustReauthenticate = false
@@ -75,9 +99,34 @@ func (m AskNameModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		break
 
+	case OidcStartedMsg:
+		if msg.errorMessage != "" {
+			m.IsFailed = true
+			m.ErrorMessage = msg.errorMessage
+			break
+		}
+		m.OidcUrl = msg.login.Url
+		return m, m.WaitForOidc(msg.login)
+
+	case ResetRequestedMsg:
+		if msg.errorMessage != "" {
+			m.IsFailed = true
+			m.ErrorMessage = msg.errorMessage
+			break
+		}
+		m.IsResetting = true
+		m.TokenText.SetValue("")
+		m.PasswordText.SetValue("")
+		m.PasswordText.Blur()
+		return m, m.TokenText.Focus()
+
 	// Is it a key press?
 	case tea.KeyMsg:
 
+		if m.IsResetting && !m.IsFailed {
+			return m.updateReset(msg)
+		}
+
 		switch msg.String() {
 		case "esc", "ctrl+c":
 			return m.PreviousModel()
@@ -103,6 +152,17 @@ func (m AskNameModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.PlayerPassword = m.PasswordText.Value()
 				return m, m.Login()
 			}
+
+		case "ctrl+o":
+			if !m.IsFailed && m.OidcUrl == "" {
+				return m, m.StartOidc()
+			}
+
+		case "ctrl+f":
+			if !m.IsFailed && isValidEmail(m.EmailText.Value()) {
+				m.PlayerEmail = m.EmailText.Value()
+				return m, m.ForgotPassword()
+			}
 		}
 	}
 
@@ -115,6 +175,39 @@ func (m AskNameModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// updateReset handles the keys while the player enters the reset token and their new password.
+func (m AskNameModel) updateReset(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "ctrl+c":
+		m.IsResetting = false
+		m.TokenText.Blur()
+		return m, m.EmailText.Focus()
+
+	case "tab", "shift+tab":
+		if m.TokenText.Focused() {
+			m.TokenText.Blur()
+			return m, m.PasswordText.Focus()
+		}
+		m.PasswordText.Blur()
+		return m, m.TokenText.Focus()
+
+	case "enter":
+		if m.TokenText.Value() != "" && isValidPassword(m.PasswordText.Value()) {
+			m.PlayerPassword = m.PasswordText.Value()
+			m.IsResetting = false
+			return m, m.ResetPassword(m.TokenText.Value())
+		}
+	}
+
+	var cmd tea.Cmd
+	if m.TokenText.Focused() {
+		m.TokenText, cmd = m.TokenText.Update(msg)
+	} else {
+		m.PasswordText, cmd = m.PasswordText.Update(msg)
+	}
+	return m, cmd
+}
+
 func isValidEmail(val string) bool {
 	l := len(strings.TrimSpace(val))
 	return l > 2 && l <= 255
@@ -138,12 +231,27 @@ func (m AskNameModel) View() string {
 			styles.Label.Render("There was a problem"),
 			styles.Value.Render(m.ErrorMessage),
 		)
+	} else if m.OidcUrl != "" {
+		view = lipgloss.JoinVertical(lipgloss.Left,
+			styles.Label.Render("Complete signing in in your browser. If it didn't open, go to:"),
+			styles.Value.Render(m.OidcUrl),
+		)
+	} else if m.IsResetting {
+		view = lipgloss.JoinVertical(lipgloss.Left,
+			styles.Label.Render("A reset token was sent to "+m.PlayerEmail+", if there's an account for it."),
+			styles.Description.Render("Enter the token from the e-mail"),
+			m.TokenText.View(),
+			styles.Description.Render("Enter your new password"),
+			m.PasswordText.View(),
+			styles.Subdued.Render("tab: next field | enter: reset password and log in | esc: cancel"),
+		)
 	} else {
 		view = lipgloss.JoinVertical(lipgloss.Left,
 			styles.Description.Render("Enter your e-mail to uniquely identify you"),
 			m.EmailText.View(),
 			styles.Description.Render("Enter the password you set"),
 			m.PasswordText.View(),
+			styles.Subdued.Render("ctrl+f: forgot password | ctrl+o: single sign-on"),
 		)
 	}
 
@@ -163,3 +271,47 @@ func (m AskNameModel) Login() tea.Cmd {
 		return out
 	}
 }
+
+// ForgotPassword asks the server to mail a reset token to the player.
+func (m AskNameModel) ForgotPassword() tea.Cmd {
+	return func() tea.Msg {
+		if err := backend.ForgotPassword(m.wc, m.PlayerEmail); err != nil {
+			return ResetRequestedMsg{errorMessage: "The reset token could not be sent: " + err.Error()}
+		}
+		return ResetRequestedMsg{}
+	}
+}
+
+// ResetPassword sets the new password with the reset token and then logs in with it.
+func (m AskNameModel) ResetPassword(token string) tea.Cmd {
+	return func() tea.Msg {
+		if err := backend.ResetPassword(m.wc, token, m.PlayerPassword); err != nil {
+			return LoginMsg{errorMessage: "The password could not be reset, the token may have expired."}
+		}
+		return m.Login()()
+	}
+}
+
+// StartOidc starts signing in with the identity provider of the server, and opens the browser for it.
+func (m AskNameModel) StartOidc() tea.Cmd {
+	return func() tea.Msg {
+		login, err := backend.StartOidcLogin(m.wc)
+		if err != nil {
+			return OidcStartedMsg{errorMessage: err.Error()}
+		}
+		if err = backend.OpenBrowser(login.Url); err != nil {
+			log.Printf("Could not open the browser: %v\n", err)
+		}
+		return OidcStartedMsg{login: login}
+	}
+}
+
+// WaitForOidc waits until the sign-in in the browser completes.
+func (m AskNameModel) WaitForOidc(login *backend.OidcLogin) tea.Cmd {
+	return func() tea.Msg {
+		if err := login.Wait(m.wc, 5*time.Minute); err != nil {
+			return LoginMsg{errorMessage: "Signing in failed: " + err.Error()}
+		}
+		return LoginMsg{isValid: true}
+	}
+}
@@ -64,7 +64,7 @@ func (m CreateGameModel) View() string {
 
 func createGame(private bool) tea.Cmd {
 	return func() tea.Msg {
-		result := backend.CreateGame(wc, !private)
+		result := backend.CreateGame(wc, !private, "")
 		if result.Key == "" {
 			// Something went wrong.
 			log.Error("Something went wrong creating the game.")
@@ -39,6 +39,10 @@ type State struct {
 	IsNewGame          bool
 	IsPrivateGame      bool
 	IsContinue         bool // When the game mode is to continue a running game.
+	IsTournament       bool // When the player wants to look at the tournaments.
+	IsInvitation       bool // When the player accepts an invitation to a game.
+	IsFriends          bool // When the player wants to see their friends.
+	IsProfile          bool // When the player wants to change their profile.
 	MustReauthenticate bool // Set when the JWT expires or is invalid somehow.
 	NoAuthStorage      bool // Set as a cmd arg flag to indicate we should not load nor save the JWT (for testing)
 	wc                 *backend.WebClient
@@ -55,6 +59,9 @@ var (
 	mainModel        MainModel
 	selectGameModel  SelectGameModel
 	startOrJoinModel StartOrJoinModel
+	tournamentsModel TournamentsModel
+	friendsModel     FriendsModel
+	profileModel     ProfileModel
 )
 
 func CreateModels(key string, storeInFile bool) *MainModel {
@@ -84,6 +91,9 @@ func CreateModels(key string, storeInFile bool) *MainModel {
 	playGameModel = *NewPlayGameModel(state)
 	selectGameModel = *NewSelectGameModel(state)
 	startOrJoinModel = *NewStartOrJoinModel(state)
+	tournamentsModel = *NewTournamentsModel(state)
+	friendsModel = *NewFriendsModel(state)
+	profileModel = *NewProfileModel(state)
 
 	state.CurrentModel = mainModel
 
@@ -120,6 +130,15 @@ func (s *State) PreviousModel() (tea.Model, tea.Cmd) {
 		prevModel = startOrJoinModel
 	case SelectGameModel:
 		prevModel = startOrJoinModel
+	case TournamentsModel:
+		prevModel = startOrJoinModel
+	case FriendsModel:
+		prevModel = startOrJoinModel
+	case ProfileModel:
+		prevModel = startOrJoinModel
+	}
+	if _, ok := prevModel.(StartOrJoinModel); ok {
+		prevCmd = loadInvitations()
 	}
 	log.Printf("[Previous] Current Model = %T, Next Model = %T\n", s.CurrentModel, prevModel)
 	s.NavigateBackward(prevModel)
@@ -137,11 +156,24 @@ func (s *State) NextModel() (tea.Model, tea.Cmd) {
 		nextModel = askNameModel
 	case AskNameModel:
 		nextModel = startOrJoinModel
+		nextCmd = loadInvitations()
 	case AskKeyModel:
 		nextModel = playGameModel
 		nextCmd = joinGame(s.Key)
 	case StartOrJoinModel:
-		if s.IsContinue {
+		if s.IsInvitation {
+			nextModel = playGameModel
+			nextCmd = acceptInvitation(s.Key)
+		} else if s.IsTournament {
+			nextModel = tournamentsModel
+			nextCmd = loadTournaments()
+		} else if s.IsFriends {
+			nextModel = friendsModel
+			nextCmd = loadFriends()
+		} else if s.IsProfile {
+			nextModel = profileModel
+			nextCmd = loadProfile()
+		} else if s.IsContinue {
 			nextModel = selectGameModel
 			nextCmd = selectGameModel.loadMyGames()
 		} else if s.IsNewGame {
@@ -162,6 +194,14 @@ func (s *State) NextModel() (tea.Model, tea.Cmd) {
 		log.Printf("Player selected game %s, starting game...\n", s.Key)
 		nextModel = playGameModel
 		nextCmd = joinGame(s.Key)
+	case TournamentsModel:
+		log.Printf("Player selected tournament game %s, starting game...\n", s.Key)
+		nextModel = playGameModel
+		nextCmd = joinGame(s.Key)
+	case FriendsModel:
+		log.Printf("Player challenged a friend in game %s, starting game...\n", s.Key)
+		nextModel = playGameModel
+		nextCmd = LoadGameInfo(s.Key)
 	}
 
 	log.Printf("[Next] Current Model = %T Next Model = %T\n", s.CurrentModel, nextModel)
@@ -0,0 +1,267 @@
+package models
+
+import (
+	"connectfour/internal/client/console"
+	"connectfour/internal/client/console/backend"
+	"connectfour/internal/model"
+	"connectfour/internal/service"
+	"fmt"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"log"
+)
+
+type FriendsFetched struct {
+	friends      []service.FriendResponse
+	errorMessage string
+}
+
+type FriendChallenged struct {
+	key          string
+	errorMessage string
+}
+
+type FriendsModel struct {
+	*State
+	Friends []service.FriendResponse
+	List    list.Model
+	Text    textinput.Model // the input box for the e-mail address of a new friend.
+	adding  bool
+	loading bool
+	message string
+}
+
+func NewFriendsModel(state *State) *FriendsModel {
+	m := &FriendsModel{
+		State:   state,
+		Text:    textinput.New(),
+		loading: true,
+	}
+	m.Text.Placeholder = "E-mail address of your friend"
+	m.Text.CharLimit = 100
+	m.Text.Width = 40
+	return m
+}
+
+func (m FriendsModel) BreadCrumb() string {
+	return "Friends"
+}
+
+func (m FriendsModel) Init() tea.Cmd {
+	return loadFriends()
+}
+
+func (m FriendsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+
+	var cmd tea.Cmd
+
+	switch msg := msg.(type) {
+	case FriendsFetched:
+		m.loading = false
+		m.Friends = msg.friends
+		m.message = msg.errorMessage
+		log.Printf("Friends fetched. Size = %d\n", len(msg.friends))
+		initFriendsList(&m)
+
+	case FriendChallenged:
+		m.loading = false
+		if msg.errorMessage != "" {
+			m.message = msg.errorMessage
+			break
+		}
+		m.Key = msg.key
+		return m.NextModel()
+
+	case tea.KeyMsg:
+		if m.adding {
+			return m.updateAdding(msg)
+		}
+		switch msg.String() {
+		case "esc", "ctrl+c", "q":
+			return m.PreviousModel()
+		case "n":
+			m.adding = true
+			m.Text.SetValue("")
+			return m, m.Text.Focus()
+		}
+		if friend, ok := m.selectedFriend(); ok && !m.loading {
+			switch msg.String() {
+			case "a":
+				if friend.Status == model.FriendRequested && friend.Incoming {
+					m.loading = true
+					return m, acceptFriend(friend.Email)
+				}
+			case "x":
+				m.loading = true
+				return m, removeFriend(friend.Email)
+			case "c", "enter":
+				if friend.Status == model.FriendAccepted {
+					m.loading = true
+					return m, challengeFriend(friend.Email)
+				}
+			}
+		}
+	}
+
+	if !m.loading && len(m.Friends) > 0 {
+		m.List, cmd = m.List.Update(msg)
+	}
+	return m, cmd
+}
+
+// updateAdding handles the keys while the e-mail address of a new friend is typed.
+func (m FriendsModel) updateAdding(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "ctrl+c":
+		m.adding = false
+		m.Text.Blur()
+		return m, nil
+	case "enter":
+		m.adding = false
+		m.loading = true
+		m.Text.Blur()
+		return m, requestFriend(m.Text.Value())
+	}
+	var cmd tea.Cmd
+	m.Text, cmd = m.Text.Update(msg)
+	return m, cmd
+}
+
+// selectedFriend returns the friend that is selected in the list.
+func (m FriendsModel) selectedFriend() (service.FriendResponse, bool) {
+	if len(m.Friends) == 0 {
+		return service.FriendResponse{}, false
+	}
+	item, ok := m.List.SelectedItem().(console.Option)
+	if !ok {
+		return service.FriendResponse{}, false
+	}
+	for _, f := range m.Friends {
+		if f.Email == item.Key() {
+			return f, true
+		}
+	}
+	return service.FriendResponse{}, false
+}
+
+func (m FriendsModel) View() string {
+
+	contents := ""
+	help := ""
+
+	switch {
+	case m.adding:
+		contents = lipgloss.JoinVertical(lipgloss.Left,
+			styles.Label.Render("Enter the e-mail address of the player you want to add as a friend"),
+			m.Text.View(),
+		)
+		help = "enter: send friend request | esc: cancel"
+	case m.loading:
+		contents = "Loading friends..."
+	case len(m.Friends) == 0:
+		contents = "You haven't added any friends yet."
+		help = "n: add a friend | esc: back"
+	default:
+		contents = m.List.View()
+		help = "c: challenge | a: accept request | x: remove | n: add a friend | esc: back"
+	}
+
+	if m.message != "" {
+		contents = lipgloss.JoinVertical(lipgloss.Left, styles.Error.Render(m.message), contents)
+	}
+
+	return m.CommonView(lipgloss.JoinVertical(lipgloss.Left,
+		styles.Description.Render("Friends"),
+		contents,
+		styles.Subdued.Render(help),
+	))
+}
+
+func initFriendsList(m *FriendsModel) {
+	options := make([]list.Item, 0)
+	for _, f := range m.Friends {
+		options = append(options, console.NewOption(f.Email, friendTitle(f), friendDescription(f)))
+	}
+
+	delegate := list.NewDefaultDelegate()
+
+	m.List = list.New(options, delegate, 80, 20)
+	m.List.SetShowHelp(false)
+	m.List.SetShowStatusBar(false)
+	m.List.SetFilteringEnabled(false)
+	m.List.SetShowPagination(true)
+	m.List.SetShowTitle(false)
+}
+
+func friendTitle(f service.FriendResponse) string {
+	title := f.Name
+	if f.Bot {
+		title += " (bot)"
+	}
+	if f.Presence != "" {
+		title += " - " + string(f.Presence)
+	}
+	return title
+}
+
+func friendDescription(f service.FriendResponse) string {
+	switch {
+	case f.Status == model.FriendRequested && f.Incoming:
+		return fmt.Sprintf("%s wants to be your friend, press 'a' to accept", f.Email)
+	case f.Status == model.FriendRequested:
+		return fmt.Sprintf("%s hasn't accepted your friend request yet", f.Email)
+	}
+	return fmt.Sprintf("%s | press 'c' to challenge", f.Email)
+}
+
+func loadFriends() tea.Cmd {
+	return func() tea.Msg {
+		friends, err := backend.Friends(wc)
+		return friendsFetched(friends, err, "The friends could not be loaded.")
+	}
+}
+
+func requestFriend(email string) tea.Cmd {
+	return func() tea.Msg {
+		friends, err := backend.RequestFriend(wc, email)
+		return friendsFetched(friends, err, "The friend request could not be sent.")
+	}
+}
+
+func acceptFriend(email string) tea.Cmd {
+	return func() tea.Msg {
+		friends, err := backend.AcceptFriend(wc, email)
+		return friendsFetched(friends, err, "The friend request could not be accepted.")
+	}
+}
+
+func removeFriend(email string) tea.Cmd {
+	return func() tea.Msg {
+		friends, err := backend.RemoveFriend(wc, email)
+		return friendsFetched(friends, err, "The friend could not be removed.")
+	}
+}
+
+// friendsFetched turns the result of a friends call into a FriendsFetched message. When the call failed, the list
+// is reloaded so that the current friends are still shown with the error message.
+func friendsFetched(friends []service.FriendResponse, err error, errorMessage string) FriendsFetched {
+	if err == nil {
+		return FriendsFetched{friends: friends}
+	}
+	log.Printf("%s %v\n", errorMessage, err)
+	friends, _ = backend.Friends(wc)
+	return FriendsFetched{friends: friends, errorMessage: errorMessage}
+}
+
+// challengeFriend creates a private game that only the friend is invited to.
+func challengeFriend(email string) tea.Cmd {
+	return func() tea.Msg {
+		game := backend.CreateGame(wc, false, email)
+		if game.Key == "" {
+			return FriendChallenged{errorMessage: "The game could not be created."}
+		}
+		return FriendChallenged{key: game.Key}
+	}
+}
@@ -0,0 +1,332 @@
+package models
+
+import (
+	"connectfour/internal/client/console/backend"
+	"connectfour/internal/service"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"log"
+)
+
+// profileForm is the form that is shown on the profile screen, if any.
+type profileForm int
+
+const (
+	noForm profileForm = iota
+	renameForm
+	passwordForm
+	deleteForm
+	verifyForm
+)
+
+type ProfileFetched struct {
+	profile      service.ProfileResponse
+	message      string
+	errorMessage string
+}
+
+type AccountDeleted struct {
+	errorMessage string
+}
+
+type ProfileModel struct {
+	*State
+	Profile      service.ProfileResponse
+	NameText     textinput.Model
+	PasswordText textinput.Model // the current password, asked to change the password or delete the account.
+	NewPassword  textinput.Model
+	CodeText     textinput.Model // the e-mail verification code.
+	form         profileForm
+	loading      bool
+	message      string
+	errorMessage string
+}
+
+func NewProfileModel(state *State) *ProfileModel {
+	m := &ProfileModel{
+		State:        state,
+		NameText:     textinput.New(),
+		PasswordText: textinput.New(),
+		NewPassword:  textinput.New(),
+		CodeText:     textinput.New(),
+		loading:      true,
+	}
+	m.NameText.Placeholder = "Your new name"
+	m.NameText.CharLimit = 40
+	m.NameText.Width = 40
+	m.PasswordText.Placeholder = "Your current password"
+	m.PasswordText.EchoMode = textinput.EchoPassword
+	m.PasswordText.CharLimit = 100
+	m.PasswordText.Width = 50
+	m.NewPassword.Placeholder = "Your new password"
+	m.NewPassword.EchoMode = textinput.EchoPassword
+	m.NewPassword.CharLimit = 100
+	m.NewPassword.Width = 50
+	m.CodeText.Placeholder = "The code from the e-mail"
+	m.CodeText.CharLimit = 64
+	m.CodeText.Width = 50
+	return m
+}
+
+func (m ProfileModel) BreadCrumb() string {
+	return "Profile"
+}
+
+func (m ProfileModel) Init() tea.Cmd {
+	return loadProfile()
+}
+
+func (m ProfileModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+
+	switch msg := msg.(type) {
+	case ProfileFetched:
+		m.loading = false
+		m.message = msg.message
+		m.errorMessage = msg.errorMessage
+		if msg.errorMessage == "" {
+			m.Profile = msg.profile
+			m.PlayerName = msg.profile.Name
+		}
+		return m, nil
+
+	case AccountDeleted:
+		if msg.errorMessage != "" {
+			m.loading = false
+			m.errorMessage = msg.errorMessage
+			return m, nil
+		}
+		return m.CantContinueModel("Your account was deleted. Thanks for playing!")
+
+	case tea.KeyMsg:
+		if m.form != noForm {
+			return m.updateForm(msg)
+		}
+		switch msg.String() {
+		case "esc", "ctrl+c", "q":
+			return m.PreviousModel()
+		case "n":
+			return m.openForm(renameForm)
+		case "p":
+			return m.openForm(passwordForm)
+		case "d":
+			return m.openForm(deleteForm)
+		case "v":
+			if !m.Profile.Verified {
+				return m.openForm(verifyForm)
+			}
+		case "r":
+			if !m.Profile.Verified {
+				m.loading = true
+				return m, resendVerification()
+			}
+		}
+	}
+	return m, nil
+}
+
+func (m ProfileModel) openForm(form profileForm) (tea.Model, tea.Cmd) {
+	m.form = form
+	m.message = ""
+	m.errorMessage = ""
+	m.NameText.SetValue("")
+	m.PasswordText.SetValue("")
+	m.NewPassword.SetValue("")
+	m.CodeText.SetValue("")
+	m.NameText.Blur()
+	m.PasswordText.Blur()
+	m.NewPassword.Blur()
+	m.CodeText.Blur()
+	switch form {
+	case renameForm:
+		return m, m.NameText.Focus()
+	case verifyForm:
+		return m, m.CodeText.Focus()
+	}
+	return m, m.PasswordText.Focus()
+}
+
+// updateForm handles the keys while one of the forms is shown.
+func (m ProfileModel) updateForm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "ctrl+c":
+		m.form = noForm
+		return m, nil
+
+	case "tab", "shift+tab":
+		if m.form == passwordForm {
+			if m.PasswordText.Focused() {
+				m.PasswordText.Blur()
+				return m, m.NewPassword.Focus()
+			}
+			m.NewPassword.Blur()
+			return m, m.PasswordText.Focus()
+		}
+
+	case "enter":
+		form := m.form
+		m.form = noForm
+		m.loading = true
+		switch form {
+		case renameForm:
+			return m, rename(m.NameText.Value())
+		case passwordForm:
+			return m, changePassword(m.PasswordText.Value(), m.NewPassword.Value())
+		case deleteForm:
+			return m, deleteAccount(m.PasswordText.Value())
+		case verifyForm:
+			return m, verify(m.CodeText.Value())
+		}
+	}
+
+	var cmd tea.Cmd
+	switch {
+	case m.NameText.Focused():
+		m.NameText, cmd = m.NameText.Update(msg)
+	case m.PasswordText.Focused():
+		m.PasswordText, cmd = m.PasswordText.Update(msg)
+	case m.NewPassword.Focused():
+		m.NewPassword, cmd = m.NewPassword.Update(msg)
+	case m.CodeText.Focused():
+		m.CodeText, cmd = m.CodeText.Update(msg)
+	}
+	return m, cmd
+}
+
+func (m ProfileModel) View() string {
+
+	contents := ""
+	help := ""
+
+	switch {
+	case m.form == renameForm:
+		contents = lipgloss.JoinVertical(lipgloss.Left,
+			styles.Description.Render("Enter the name other players will see"),
+			m.NameText.View(),
+		)
+		help = "enter: save | esc: cancel"
+	case m.form == passwordForm:
+		contents = lipgloss.JoinVertical(lipgloss.Left,
+			styles.Description.Render("Enter your current password"),
+			m.PasswordText.View(),
+			styles.Description.Render("Enter your new password"),
+			m.NewPassword.View(),
+		)
+		help = "tab: next field | enter: save | esc: cancel"
+	case m.form == deleteForm:
+		contents = lipgloss.JoinVertical(lipgloss.Left,
+			styles.Error.Render("Deleting your account can't be undone. Your games are kept, but anonymized."),
+			styles.Description.Render("Enter your password to confirm"),
+			m.PasswordText.View(),
+		)
+		help = "enter: delete my account | esc: cancel"
+	case m.form == verifyForm:
+		contents = lipgloss.JoinVertical(lipgloss.Left,
+			styles.Description.Render("Enter the verification code that was sent to "+m.Profile.Email),
+			m.CodeText.View(),
+		)
+		help = "enter: verify | esc: cancel"
+	case m.loading:
+		contents = "Loading your profile..."
+	default:
+		verified := "yes"
+		if !m.Profile.Verified {
+			verified = "no, you can't create public games yet"
+		}
+		contents = lipgloss.JoinVertical(lipgloss.Left,
+			styles.Label.Render("Name     ")+styles.Value.Render(m.Profile.Name),
+			styles.Label.Render("E-mail   ")+styles.Value.Render(m.Profile.Email),
+			styles.Label.Render("Verified ")+styles.Value.Render(verified),
+		)
+		help = "n: change name | p: change password | d: delete account | esc: back"
+		if !m.Profile.Verified {
+			help = "v: enter verification code | r: resend code | " + help
+		}
+	}
+
+	if m.errorMessage != "" {
+		contents = lipgloss.JoinVertical(lipgloss.Left, styles.Error.Render(m.errorMessage), contents)
+	} else if m.message != "" {
+		contents = lipgloss.JoinVertical(lipgloss.Left, styles.Label.Render(m.message), contents)
+	}
+
+	return m.CommonView(lipgloss.JoinVertical(lipgloss.Left,
+		styles.Description.Render("Your profile"),
+		contents,
+		styles.Subdued.Render(help),
+	))
+}
+
+func loadProfile() tea.Cmd {
+	return func() tea.Msg {
+		profile, err := backend.Profile(wc)
+		if err != nil {
+			return ProfileFetched{errorMessage: "Your profile could not be loaded."}
+		}
+		return ProfileFetched{profile: profile}
+	}
+}
+
+func rename(name string) tea.Cmd {
+	return func() tea.Msg {
+		profile, err := backend.Rename(wc, name)
+		if err != nil {
+			log.Printf("Renaming failed: %v\n", err)
+			msg := loadProfile()().(ProfileFetched)
+			msg.errorMessage = "Your name could not be changed, it may already be taken."
+			return msg
+		}
+		return ProfileFetched{profile: profile, message: "Your name was changed."}
+	}
+}
+
+func changePassword(oldPassword string, newPassword string) tea.Cmd {
+	return func() tea.Msg {
+		msg := loadProfile()().(ProfileFetched)
+		if err := backend.ChangePassword(wc, oldPassword, newPassword); err != nil {
+			log.Printf("Changing the password failed: %v\n", err)
+			msg.errorMessage = "Your password could not be changed, check your current password."
+		} else if msg.errorMessage == "" {
+			msg.message = "Your password was changed."
+		}
+		return msg
+	}
+}
+
+func deleteAccount(password string) tea.Cmd {
+	return func() tea.Msg {
+		if err := backend.DeleteAccount(wc, password); err != nil {
+			log.Printf("Deleting the account failed: %v\n", err)
+			return AccountDeleted{errorMessage: "Your account could not be deleted, check your password."}
+		}
+		return AccountDeleted{}
+	}
+}
+
+func verify(code string) tea.Cmd {
+	return func() tea.Msg {
+		err := backend.Verify(wc, code)
+		msg := loadProfile()().(ProfileFetched)
+		if err != nil {
+			log.Printf("Verifying failed: %v\n", err)
+			msg.errorMessage = "The code is invalid or has expired."
+		} else if msg.errorMessage == "" {
+			msg.message = "Your e-mail address is verified."
+		}
+		return msg
+	}
+}
+
+func resendVerification() tea.Cmd {
+	return func() tea.Msg {
+		err := backend.ResendVerification(wc)
+		msg := loadProfile()().(ProfileFetched)
+		if err != nil {
+			log.Printf("Resending the verification code failed: %v\n", err)
+			msg.errorMessage = "The code could not be sent, please wait a minute before trying again."
+		} else if msg.errorMessage == "" {
+			msg.message = "A new verification code was sent."
+		}
+		return msg
+	}
+}
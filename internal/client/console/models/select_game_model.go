@@ -3,16 +3,24 @@ package models
 import (
 	"connectfour/internal/client/console"
 	"connectfour/internal/client/console/backend"
+	"connectfour/internal/model"
 	"connectfour/internal/service"
 	"fmt"
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"io"
 	"log"
+	"slices"
+	"strings"
+	"time"
 )
 
+// GamesFetched is a page of games. When more is true, it's a next page that's added to the games that were loaded.
 type GamesFetched struct {
-	games []service.NewGameResponse
+	games []service.GameListingResponse
+	next  string
+	more  bool
 }
 
 type GameSelected struct {
@@ -21,9 +29,11 @@ type GameSelected struct {
 
 type SelectGameModel struct {
 	*State
-	Games   []service.NewGameResponse
+	Games   []service.GameListingResponse
 	List    list.Model
 	loading bool
+	next    string // the url of the next page of games, empty when all were loaded.
+	paging  bool   // true while the next page is loaded.
 }
 
 func NewSelectGameModel(state *State) *SelectGameModel {
@@ -46,8 +56,14 @@ func (m SelectGameModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case GamesFetched:
-		m.Games = msg.games
 		log.Printf("Games fetched. Size = %d\n", len(msg.games))
+		m.next = msg.next
+		if msg.more {
+			m.paging = false
+			m.Games = m.sortByTurn(append(m.Games, msg.games...))
+			return m, m.List.SetItems(m.gameOptions())
+		}
+		m.Games = m.sortByTurn(msg.games)
 		m.loading = false
 		initGamesList(&m)
 
@@ -57,7 +73,7 @@ func (m SelectGameModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m.PreviousModel()
 		case "enter":
 			if (!m.loading) && len(m.Games) > 0 {
-				m.Key = m.List.SelectedItem().(console.Option).Key()
+				m.Key = m.List.SelectedItem().(gameOption).Key()
 				return m.NextModel()
 			} else {
 				return m.PreviousModel()
@@ -67,7 +83,8 @@ func (m SelectGameModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	if !m.loading && len(m.Games) > 0 {
 		m.List, cmd = m.List.Update(msg)
-		return m, cmd
+		next := m.loadNextPage()
+		return m, tea.Batch(cmd, next)
 	} else {
 		return m, nil
 	}
@@ -95,36 +112,141 @@ func (m SelectGameModel) View() string {
 
 func (m SelectGameModel) loadOpenGames() tea.Cmd {
 	return func() tea.Msg {
-		games := backend.JoinableGames(m.wc)
-		return GamesFetched{games: games}
+		page := backend.JoinableGames(m.wc)
+		return GamesFetched{games: page.Games, next: page.Next}
 	}
 }
 
 func (m SelectGameModel) loadMyGames() tea.Cmd {
 	return func() tea.Msg {
-		games := backend.MyGames(m.wc)
-		return GamesFetched{games: games}
+		page := backend.MyGames(m.wc)
+		return GamesFetched{games: page.Games, next: page.Next}
 	}
 }
 
-func initGamesList(m *SelectGameModel) {
-	options := make([]list.Item, 0)
+// loadNextPage loads the next page of games when the last game that was loaded is selected, so the games are only
+// loaded as far as the player scrolls.
+func (m *SelectGameModel) loadNextPage() tea.Cmd {
+	if m.next == "" || m.paging || m.List.Index() < len(m.Games)-1 {
+		return nil
+	}
+	m.paging = true
+	next := m.next
+	return func() tea.Msg {
+		page := backend.NextGames(m.wc, next)
+		return GamesFetched{games: page.Games, next: page.Next, more: true}
+	}
+}
+
+// gameOption is a game in the list. The games where it's the player's turn are highlighted.
+type gameOption struct {
+	console.Option
+	yourTurn bool
+}
+
+// sortByTurn puts the games where it's the player's turn first, and keeps the order of the listing otherwise.
+func (m SelectGameModel) sortByTurn(games []service.GameListingResponse) []service.GameListingResponse {
+	_, me, _ := m.wc.Identify()
+	slices.SortStableFunc(games, func(a, b service.GameListingResponse) int {
+		aTurn, bTurn := strings.EqualFold(a.PlayerTurnEmail, me), strings.EqualFold(b.PlayerTurnEmail, me)
+		switch {
+		case aTurn && !bTurn:
+			return -1
+		case bTurn && !aTurn:
+			return 1
+		}
+		return 0
+	})
+	return games
+}
+
+func (m SelectGameModel) gameOptions() []list.Item {
+	_, me, _ := m.wc.Identify()
+	options := make([]list.Item, 0, len(m.Games))
 	for _, game := range m.Games {
-		options = append(options,
-			console.NewOption(
-				game.Key,
-				fmt.Sprintf("%s (%s)", game.CreatedBy, game.Key),
-				fmt.Sprintf("Created at %s | status: %s", game.CreatedAt, game.Status)),
-		)
+		options = append(options, gameOption{
+			Option:   console.NewOption(game.Key, gameTitle(game, me), gameDescription(game, me)),
+			yourTurn: strings.EqualFold(game.PlayerTurnEmail, me),
+		})
 	}
+	return options
+}
 
-	delegate := list.NewDefaultDelegate()
+// gameTitle names the opponent of the player, or the player that created the game when the player isn't in it.
+func gameTitle(game service.GameListingResponse, me string) string {
+	switch {
+	case strings.EqualFold(game.Player1Email, me) && game.Player2Email != "":
+		return fmt.Sprintf("vs %s (%s)", game.Player2Name, game.Key)
+	case strings.EqualFold(game.Player1Email, me) && game.Invitee != "":
+		return fmt.Sprintf("Inviting %s (%s)", game.Invitee, game.Key)
+	case strings.EqualFold(game.Player1Email, me):
+		return fmt.Sprintf("Waiting for an opponent (%s)", game.Key)
+	case strings.EqualFold(game.Player2Email, me):
+		return fmt.Sprintf("vs %s (%s)", game.Player1Name, game.Key)
+	}
+	return fmt.Sprintf("%s (%s)", game.Player1Name, game.Key)
+}
 
-	m.List = list.New(options, delegate, 80, 20)
+func gameDescription(game service.GameListingResponse, me string) string {
+	var state string
+	switch {
+	case game.Status == model.Created:
+		return fmt.Sprintf("Created %s", game.CreatedAt.Local().Format(time.DateTime))
+	case game.Outcome == "aborted":
+		state = "Aborted"
+	case game.Outcome == "draw":
+		state = "It's a draw"
+	case game.Outcome != "":
+		winner := game.Player1Email
+		if game.Outcome == "player2" {
+			winner = game.Player2Email
+		}
+		state = "You lost"
+		if strings.EqualFold(winner, me) {
+			state = "You won"
+		}
+	case strings.EqualFold(game.PlayerTurnEmail, me):
+		state = "Your turn"
+	default:
+		state = "Their turn"
+	}
+	description := fmt.Sprintf("%s | %d moves", state, game.Moves)
+	if !game.LastMoveAt.IsZero() {
+		description += " | last move " + game.LastMoveAt.Local().Format(time.DateTime)
+	}
+	return description
+}
+
+// gameDelegate renders the games where it's the player's turn in the highlighted style.
+type gameDelegate struct {
+	list.DefaultDelegate
+	highlighted list.DefaultDelegate
+}
+
+func newGameDelegate() gameDelegate {
+	highlighted := list.NewDefaultDelegate()
+	highlighted.Styles.NormalTitle = highlighted.Styles.NormalTitle.Foreground(styles.Value.GetForeground()).Bold(true)
+	highlighted.Styles.NormalDesc = highlighted.Styles.NormalDesc.Foreground(styles.Value.GetForeground())
+	highlighted.Styles.SelectedTitle = highlighted.Styles.SelectedTitle.Bold(true)
+	return gameDelegate{
+		DefaultDelegate: list.NewDefaultDelegate(),
+		highlighted:     highlighted,
+	}
+}
+
+func (d gameDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
+	if option, ok := item.(gameOption); ok && option.yourTurn {
+		d.highlighted.Render(w, m, index, item)
+		return
+	}
+	d.DefaultDelegate.Render(w, m, index, item)
+}
+
+func initGamesList(m *SelectGameModel) {
+	m.List = list.New(m.gameOptions(), newGameDelegate(), 80, 20)
 	m.List.SetShowHelp(false)
 	m.List.SetShowStatusBar(false)
 	m.List.SetFilteringEnabled(true)
 	m.List.SetShowPagination(true)
 	m.List.SetShowTitle(false)
-
 }
@@ -2,14 +2,26 @@ package models
 
 import (
 	"connectfour/internal/client/console"
+	"connectfour/internal/client/console/backend"
+	"connectfour/internal/service"
+	"fmt"
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"strings"
 )
 
+// invitationKeyPrefix marks the list options that are incoming invitations, rather than one of the start options.
+const invitationKeyPrefix = "invite:"
+
+type InvitationsFetched struct {
+	invitations []service.InvitationResponse
+}
+
 type StartOrJoinModel struct {
 	*State
-	List list.Model
+	List        list.Model
+	Invitations []service.InvitationResponse
 }
 
 func (m StartOrJoinModel) BreadCrumb() string {
@@ -17,20 +29,12 @@ func (m StartOrJoinModel) BreadCrumb() string {
 }
 
 func NewStartOrJoinModel(state *State) *StartOrJoinModel {
-	options := []list.Item{
-		console.NewOption("1", "1. List my active games", "Lists the games you are participating in, for you to continue."),
-		console.NewOption("2", "2. Create new private game", "Creates a new game that will not be public, so you must share the key."),
-		console.NewOption("3", "3. Create new public game", "Creates a new game that's going to be listed and open for anyone to join."),
-		console.NewOption("4", "4. Join a private game", "Join a game that's not listed, but that you received a key for."),
-		console.NewOption("5", "5. Join a public game", "Browse the list of games and join one (this will fetch the list of games)."),
-	}
-
 	delegate := list.NewDefaultDelegate()
-	l := list.New(options, delegate, 120, 18)
+	l := list.New(startOptions(nil), delegate, 120, 18)
 	l.Title = "Kind of game"
 	l.SetShowStatusBar(false)
 	l.SetFilteringEnabled(false)
-	l.SetShowPagination(false)
+	l.SetShowPagination(true)
 	l.SetShowHelp(false)
 	l.SetShowTitle(false)
 
@@ -40,8 +44,29 @@ func NewStartOrJoinModel(state *State) *StartOrJoinModel {
 	}
 }
 
+// startOptions returns the fixed options, followed by an option for every incoming invitation.
+func startOptions(invitations []service.InvitationResponse) []list.Item {
+	options := []list.Item{
+		console.NewOption("1", "1. List my active games", "Lists the games you are participating in, for you to continue."),
+		console.NewOption("2", "2. Create new private game", "Creates a new game that will not be public, so you must share the key."),
+		console.NewOption("3", "3. Create new public game", "Creates a new game that's going to be listed and open for anyone to join."),
+		console.NewOption("4", "4. Join a private game", "Join a game that's not listed, but that you received a key for."),
+		console.NewOption("5", "5. Join a public game", "Browse the list of games and join one (this will fetch the list of games)."),
+		console.NewOption("6", "6. Tournaments", "Look at the standings of the tournaments, register for one or play your next tournament game."),
+		console.NewOption("7", "7. Friends", "See which of your friends are online, add friends or challenge one to a game."),
+		console.NewOption("8", "8. Profile", "Change your name or password, or delete your account."),
+	}
+	for i, invitation := range invitations {
+		options = append(options, console.NewOption(
+			invitationKeyPrefix+invitation.Key,
+			fmt.Sprintf("%d. Invitation from %s", len(options)+1, invitation.FromName),
+			fmt.Sprintf("Press enter to accept and start playing, or 'd' to decline (%s).", invitations[i].FromEmail)))
+	}
+	return options
+}
+
 func (m StartOrJoinModel) Init() tea.Cmd {
-	return nil
+	return loadInvitations()
 }
 
 func (m StartOrJoinModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -49,15 +74,40 @@ func (m StartOrJoinModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	i := m.List.Index()
 
 	switch msg := msg.(type) {
+	case InvitationsFetched:
+		m.Invitations = msg.invitations
+		cmd := m.List.SetItems(startOptions(m.Invitations))
+		return m, cmd
+
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "esc", "ctrl+c", "q":
 			return m.PreviousModel()
 
+		case "d":
+			if key, ok := m.selectedInvitation(); ok {
+				return m, declineInvitation(key)
+			}
+
 		case "enter":
+			if key, ok := m.selectedInvitation(); ok {
+				m.Key = key
+				m.IsInvitation = true
+				m.IsContinue = false
+				m.IsNewGame = false
+				m.IsTournament = false
+				m.IsFriends = false
+				m.IsProfile = false
+				return m.NextModel()
+			}
+
+			m.IsInvitation = false
 			m.IsContinue = i == 0
 			m.IsNewGame = i == 1 || i == 2
 			m.IsPrivateGame = i == 1 || i == 3
+			m.IsTournament = i == 5
+			m.IsFriends = i == 6
+			m.IsProfile = i == 7
 
 			return m.NextModel()
 		}
@@ -68,10 +118,52 @@ func (m StartOrJoinModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// selectedInvitation returns the game key of the selected invitation, if an invitation is selected.
+func (m StartOrJoinModel) selectedInvitation() (string, bool) {
+	item, ok := m.List.SelectedItem().(console.Option)
+	if !ok {
+		return "", false
+	}
+	return strings.CutPrefix(item.Key(), invitationKeyPrefix)
+}
+
 func (m StartOrJoinModel) View() string {
+	description := "What would you like to do?"
+	if len(m.Invitations) == 1 {
+		description += " You have an invitation to play."
+	} else if len(m.Invitations) > 1 {
+		description += fmt.Sprintf(" You have %d invitations to play.", len(m.Invitations))
+	}
 	view := lipgloss.JoinVertical(lipgloss.Left,
-		styles.Description.Render("What would you like to do?"),
+		styles.Description.Render(description),
 		m.List.View(),
 	)
 	return m.CommonView(view)
 }
+
+func loadInvitations() tea.Cmd {
+	return func() tea.Msg {
+		return InvitationsFetched{invitations: backend.Invitations(wc)}
+	}
+}
+
+func declineInvitation(key string) tea.Cmd {
+	return func() tea.Msg {
+		_ = backend.DeclineInvitation(wc, key)
+		return loadInvitations()()
+	}
+}
+
+// acceptInvitation returns a Cmd that sends a GameInfoMsg when the invitation is accepted.
+func acceptInvitation(key string) tea.Cmd {
+	return func() tea.Msg {
+		info, err := backend.AcceptInvitation(wc, key)
+		msg := GameInfoMsg{
+			info: info,
+		}
+		if err != nil {
+			msg.errorMessage = "The invitation could not be accepted"
+		}
+		return msg
+	}
+}
@@ -0,0 +1,260 @@
+package models
+
+import (
+	"connectfour/internal/client/console"
+	"connectfour/internal/client/console/backend"
+	"connectfour/internal/model"
+	"connectfour/internal/service"
+	"fmt"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"log"
+	"strconv"
+	"strings"
+)
+
+type TournamentsFetched struct {
+	tournaments  []service.TournamentResponse
+	errorMessage string
+}
+
+type TournamentFetched struct {
+	tournament   service.TournamentResponse
+	errorMessage string
+}
+
+type TournamentsModel struct {
+	*State
+	Tournaments []service.TournamentResponse
+	Selected    *service.TournamentResponse // the tournament whose standings are shown.
+	List        list.Model
+	loading     bool
+	message     string
+}
+
+func NewTournamentsModel(state *State) *TournamentsModel {
+	return &TournamentsModel{
+		State:   state,
+		loading: true,
+	}
+}
+
+func (m TournamentsModel) BreadCrumb() string {
+	return "Tournaments"
+}
+
+func (m TournamentsModel) Init() tea.Cmd {
+	return loadTournaments()
+}
+
+func (m TournamentsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+
+	var cmd tea.Cmd
+
+	switch msg := msg.(type) {
+	case TournamentsFetched:
+		m.loading = false
+		m.Selected = nil
+		m.Tournaments = msg.tournaments
+		m.message = msg.errorMessage
+		log.Printf("Tournaments fetched. Size = %d\n", len(msg.tournaments))
+		initTournamentsList(&m)
+
+	case TournamentFetched:
+		m.loading = false
+		m.message = msg.errorMessage
+		if msg.errorMessage == "" {
+			m.Selected = &msg.tournament
+		}
+
+	case tea.KeyMsg:
+		if m.Selected != nil {
+			return m.updateDetail(msg)
+		}
+		switch msg.String() {
+		case "esc":
+			return m.PreviousModel()
+		case "enter":
+			if !m.loading && len(m.Tournaments) > 0 {
+				id, _ := strconv.ParseInt(m.List.SelectedItem().(console.Option).Key(), 10, 64)
+				m.loading = true
+				return m, loadTournament(id)
+			}
+			return m.PreviousModel()
+		}
+	}
+
+	if !m.loading && m.Selected == nil && len(m.Tournaments) > 0 {
+		m.List, cmd = m.List.Update(msg)
+	}
+	return m, cmd
+}
+
+// updateDetail handles the keys while the standings of a tournament are shown.
+func (m TournamentsModel) updateDetail(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.loading = true
+		return m, loadTournaments()
+	case "r":
+		if m.Selected.Status == model.Registering {
+			return m, registerForTournament(m.Selected.Id)
+		}
+	case "enter":
+		if key := m.myPendingGame(); key != "" {
+			m.Key = key
+			return m.NextModel()
+		}
+	}
+	return m, nil
+}
+
+// myPendingGame returns the key of the game the player still has to play in the current round.
+func (m TournamentsModel) myPendingGame() string {
+	for _, p := range m.Selected.Pairings {
+		if p.Round == m.Selected.CurrentRound && p.Result == model.Pending && p.GameKey != "" &&
+			(strings.EqualFold(p.Player1Email, m.PlayerEmail) || strings.EqualFold(p.Player2Email, m.PlayerEmail)) {
+			return p.GameKey
+		}
+	}
+	return ""
+}
+
+func (m TournamentsModel) View() string {
+
+	contents := ""
+	help := ""
+
+	switch {
+	case m.loading:
+		contents = "Loading tournaments..."
+	case m.Selected != nil:
+		contents = m.renderTournament(*m.Selected)
+		help = "esc: back to the list"
+		if m.Selected.Status == model.Registering {
+			help += " | r: register"
+		}
+		if m.myPendingGame() != "" {
+			help += " | enter: play your game"
+		}
+	case len(m.Tournaments) == 0:
+		contents = "There are no tournaments yet."
+	default:
+		contents = m.List.View()
+		help = "enter: show standings | esc: back"
+	}
+
+	if m.message != "" {
+		contents = lipgloss.JoinVertical(lipgloss.Left, styles.Error.Render(m.message), contents)
+	}
+
+	return m.CommonView(lipgloss.JoinVertical(lipgloss.Left,
+		styles.Description.Render("Tournaments"),
+		contents,
+		styles.Subdued.Render(help),
+	))
+}
+
+func (m TournamentsModel) renderTournament(t service.TournamentResponse) string {
+	b := strings.Builder{}
+
+	b.WriteString(styles.Header.Render(t.Name))
+	b.WriteString(styles.Subdued.Render(fmt.Sprintf(" (%s, %s", t.Format, t.Status)))
+	if t.Status != model.Registering {
+		b.WriteString(styles.Subdued.Render(fmt.Sprintf(", round %d of %d", t.CurrentRound, t.Rounds)))
+	}
+	b.WriteString(styles.Subdued.Render(")\n"))
+	if t.Winner != "" {
+		b.WriteString(styles.Label.Render("Winner: ") + styles.Value.Render(t.Winner) + "\n")
+	}
+
+	b.WriteString("\n" + styles.Label.Render("Standings") + "\n")
+	b.WriteString(styles.Subdued.Render(fmt.Sprintf("%-4s %-20s %3s %3s %3s %3s %6s", "#", "Player", "P", "W", "D", "L", "Pts")) + "\n")
+	for _, s := range t.Standings {
+		line := fmt.Sprintf("%-4d %-20s %3d %3d %3d %3d %6.1f", s.Rank, s.Name, s.Played, s.Wins, s.Draws, s.Losses, s.Points)
+		if strings.EqualFold(s.Email, m.PlayerEmail) {
+			line = styles.Value.Render(line)
+		}
+		b.WriteString(line + "\n")
+	}
+
+	round := 0
+	for _, p := range t.Pairings {
+		if p.Round != round {
+			round = p.Round
+			b.WriteString("\n" + styles.Label.Render(fmt.Sprintf("Round %d", round)) + "\n")
+		}
+		b.WriteString(renderPairing(p) + "\n")
+	}
+
+	return b.String()
+}
+
+func renderPairing(p service.PairingResponse) string {
+	if p.Result == model.Bye {
+		return fmt.Sprintf("  %-20s (bye)", p.Player1Name)
+	}
+	result := "playing"
+	switch p.Result {
+	case model.Player1Wins:
+		result = "1 - 0"
+	case model.Player2Wins:
+		result = "0 - 1"
+	case model.Draw:
+		result = "½ - ½"
+	}
+	return fmt.Sprintf("  %-20s vs %-20s %-8s %s", p.Player1Name, p.Player2Name, result, styles.Subdued.Render(p.GameKey))
+}
+
+func initTournamentsList(m *TournamentsModel) {
+	options := make([]list.Item, 0)
+	for _, t := range m.Tournaments {
+		options = append(options,
+			console.NewOption(
+				strconv.FormatInt(t.Id, 10),
+				fmt.Sprintf("%s (%s)", t.Name, t.Format),
+				fmt.Sprintf("%s | %d players | organized by %s", t.Status, len(t.Players), t.Organizer)),
+		)
+	}
+
+	delegate := list.NewDefaultDelegate()
+
+	m.List = list.New(options, delegate, 80, 20)
+	m.List.SetShowHelp(false)
+	m.List.SetShowStatusBar(false)
+	m.List.SetFilteringEnabled(true)
+	m.List.SetShowPagination(true)
+	m.List.SetShowTitle(false)
+}
+
+func loadTournaments() tea.Cmd {
+	return func() tea.Msg {
+		tournaments, err := backend.Tournaments(wc)
+		msg := TournamentsFetched{tournaments: tournaments}
+		if err != nil {
+			msg.errorMessage = "The tournaments could not be loaded."
+		}
+		return msg
+	}
+}
+
+func loadTournament(id int64) tea.Cmd {
+	return func() tea.Msg {
+		t, err := backend.Tournament(wc, id)
+		msg := TournamentFetched{tournament: t}
+		if err != nil {
+			msg.errorMessage = "The tournament could not be loaded."
+		}
+		return msg
+	}
+}
+
+func registerForTournament(id int64) tea.Cmd {
+	return func() tea.Msg {
+		if _, err := backend.RegisterForTournament(wc, id); err != nil {
+			return TournamentFetched{errorMessage: "Registering failed: " + err.Error()}
+		}
+		return loadTournament(id)()
+	}
+}
@@ -0,0 +1,90 @@
+package db
+
+import (
+	"connectfour/internal/model"
+	"context"
+	"database/sql"
+	log "github.com/sirupsen/logrus"
+	"strings"
+)
+
+// defaultAuditLimit is the number of entries that are returned when the filter doesn't set a limit.
+const defaultAuditLimit = 100
+
+// MariaDbAuditRepository stores the audit log. Entries can only be appended and read, never changed.
+type MariaDbAuditRepository struct {
+	db *sql.DB
+}
+
+var _ AuditRepository = MariaDbAuditRepository{}
+
+func NewMariaDbAuditRepository() *MariaDbAuditRepository {
+	return &MariaDbAuditRepository{
+		db: connect(),
+	}
+}
+
+func (r MariaDbAuditRepository) Append(ctx context.Context, e model.AuditEntry) error {
+	ctx, done := startQuery(ctx, "audit", "Append")
+	defer done()
+
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO audit_log (created_at, action, actor, ip, request_id, subject, details)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		e.Time, e.Action, e.Actor, e.Ip, e.RequestId, e.Subject, e.Details)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error appending to the audit log: %v\n", err)
+	}
+	return err
+}
+
+// List returns the entries that match the filter, the newest first.
+func (r MariaDbAuditRepository) List(ctx context.Context, f model.AuditFilter) ([]model.AuditEntry, error) {
+	ctx, done := startQuery(ctx, "audit", "List")
+	defer done()
+
+	criteria := make([]string, 0)
+	args := make([]interface{}, 0)
+	if f.Actor != "" {
+		criteria = append(criteria, "actor = ?")
+		args = append(args, f.Actor)
+	}
+	if f.Action != "" {
+		criteria = append(criteria, "action = ?")
+		args = append(args, f.Action)
+	}
+	if f.Subject != "" {
+		criteria = append(criteria, "subject = ?")
+		args = append(args, f.Subject)
+	}
+	if !f.Since.IsZero() {
+		criteria = append(criteria, "created_at >= ?")
+		args = append(args, f.Since)
+	}
+	limit := f.Limit
+	if limit <= 0 {
+		limit = defaultAuditLimit
+	}
+
+	query := "SELECT id, created_at, action, actor, ip, request_id, subject, details FROM audit_log"
+	if len(criteria) > 0 {
+		query += " WHERE " + strings.Join(criteria, " AND ")
+	}
+	rows, err := r.db.QueryContext(ctx, query+" ORDER BY id DESC LIMIT ?", append(args, limit)...)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error reading the audit log: %v\n", err)
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	entries := make([]model.AuditEntry, 0)
+	for rows.Next() {
+		var e model.AuditEntry
+		if err = rows.Scan(&e.Id, &e.Time, &e.Action, &e.Actor, &e.Ip, &e.RequestId, &e.Subject, &e.Details); err != nil {
+			log.WithContext(ctx).Errorf("Error scanning the audit log row: %v\n", err)
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
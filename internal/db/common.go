@@ -1,11 +1,18 @@
 package db
 
 import (
+	"connectfour/internal/metrics"
+	"connectfour/internal/tracing"
+	"context"
 	"database/sql"
 	log "github.com/sirupsen/logrus"
 	"os"
+	"time"
 )
 
+// queryTimeout is the longest that a single query may take, also when the request that runs it allows more time.
+var queryTimeout = 5 * time.Second
+
 // readSecret reads a Docker secret from the designated location and returns the contents of the file as a string.
 func readSecret(name string) string {
 	file := os.Getenv(name)
@@ -16,18 +23,30 @@ func readSecret(name string) string {
 	return string(b)
 }
 
-func connect() *sql.DB {
-	log.Infoln("Connecting to DB...")
-	secret := readSecret("MARIADB_PASSWORD_FILE")
-	user := os.Getenv("MARIADB_USER")
-	schema := os.Getenv("MARIADB_DATABASE")
-	address := os.Getenv("MARIADB_ADDRESS")
-	datasource := user + ":" + secret + "@" + address + "/" + schema + "?parseTime=true"
-	db, err := sql.Open("mysql", datasource)
+// nullTime converts a zero time to NULL, so that optional timestamps aren't stored as '0000-00-00'.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
 
-	if err != nil {
-		log.Fatalf("Error connecting to the database: %v\n", err)
+// nullId converts an empty id to NULL, for optional references to another table.
+func nullId(id int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: id, Valid: id != 0}
+}
+
+// withQueryTimeout returns a context for a query, which is cancelled with the request or after queryTimeout.
+func withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, queryTimeout)
+}
+
+// startQuery starts a query of the repository method: its context has the query timeout and the span of the query,
+// and done cancels that context, ends the span and records how long the query took.
+func startQuery(ctx context.Context, repository string, method string) (context.Context, func()) {
+	ctx, span := tracing.StartQuery(ctx, repository, method)
+	ctx, cancel := withQueryTimeout(ctx)
+	observe := metrics.ObserveQuery(repository, method)
+	return ctx, func() {
+		cancel()
+		span.End()
+		observe()
 	}
-	log.Infoln("Connected.")
-	return db
 }
@@ -0,0 +1,154 @@
+package db
+
+import (
+	"connectfour/internal/model"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+// slowConnector opens connections to a database that takes the delay to answer every query, unless the context of
+// the query is done first. The queries don't return any rows.
+type slowConnector struct {
+	delay time.Duration
+}
+
+func (c slowConnector) Connect(context.Context) (driver.Conn, error) {
+	return slowConn(c), nil
+}
+
+func (c slowConnector) Driver() driver.Driver {
+	return nil
+}
+
+type slowConn struct {
+	delay time.Duration
+}
+
+func (c slowConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements are not supported")
+}
+
+func (c slowConn) Close() error {
+	return nil
+}
+
+func (c slowConn) Begin() (driver.Tx, error) {
+	return c, nil
+}
+
+func (c slowConn) BeginTx(ctx context.Context, _ driver.TxOptions) (driver.Tx, error) {
+	return c, c.wait(ctx)
+}
+
+func (c slowConn) Commit() error {
+	return nil
+}
+
+func (c slowConn) Rollback() error {
+	return nil
+}
+
+func (c slowConn) QueryContext(ctx context.Context, _ string, _ []driver.NamedValue) (driver.Rows, error) {
+	if err := c.wait(ctx); err != nil {
+		return nil, err
+	}
+	return noRows{}, nil
+}
+
+func (c slowConn) ExecContext(ctx context.Context, _ string, _ []driver.NamedValue) (driver.Result, error) {
+	if err := c.wait(ctx); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c slowConn) wait(ctx context.Context) error {
+	select {
+	case <-time.After(c.delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type noRows struct{}
+
+func (noRows) Columns() []string {
+	return []string{}
+}
+
+func (noRows) Close() error {
+	return nil
+}
+
+func (noRows) Next([]driver.Value) error {
+	return io.EOF
+}
+
+func slowDb(delay time.Duration) *sql.DB {
+	return sql.OpenDB(slowConnector{delay: delay})
+}
+
+func TestMariaDbUserRepository_FindByEmail_Cancelled(t *testing.T) {
+	// Arrange
+	r := MariaDbUserRepository{db: slowDb(time.Minute)}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	start := time.Now()
+
+	// Act
+	_, err := r.FindByEmail(ctx, "dick@evilnerd.nl")
+
+	// Assert
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), time.Second, "Expected the query to stop when the request was cancelled")
+}
+
+func TestMariaDbUserRepository_Delete_Cancelled(t *testing.T) {
+	// Arrange
+	r := MariaDbUserRepository{db: slowDb(time.Minute)}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Act
+	err := r.Delete(ctx, 1)
+
+	// Assert
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestMariaDbGameRepository_QueryTimeout(t *testing.T) {
+	// Arrange
+	previous := queryTimeout
+	queryTimeout = 20 * time.Millisecond
+	t.Cleanup(func() { queryTimeout = previous })
+	r := MariaDbGameRepository{db: slowDb(time.Minute)}
+	start := time.Now()
+
+	// Act
+	_, errFetch := r.Fetch(context.Background(), "sals-nil-abri")
+	saved := r.Save(context.Background(), model.NewGame(model.User{Id: 1}, true))
+
+	// Assert
+	assert.ErrorIs(t, errFetch, context.DeadlineExceeded)
+	assert.False(t, saved)
+	assert.Less(t, time.Since(start), time.Second, "Expected every query to stop after the query timeout")
+}
+
+func TestMariaDbUserRepository_FindByEmail_InTime(t *testing.T) {
+	// Arrange
+	r := MariaDbUserRepository{db: slowDb(time.Millisecond)}
+
+	// Act
+	user, err := r.FindByEmail(context.Background(), "dick@evilnerd.nl")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, user.Empty())
+}
@@ -2,7 +2,9 @@ package db
 
 import (
 	"connectfour/internal/model"
+	"context"
 	"database/sql"
+	"fmt"
 	log "github.com/sirupsen/logrus"
 	"strings"
 )
@@ -19,30 +21,38 @@ func NewMariaDbGameRepository() *MariaDbGameRepository {
 	}
 }
 
-func (r MariaDbGameRepository) Save(g model.Game) bool {
-	_, err := r.db.Exec(
+func (r MariaDbGameRepository) Save(ctx context.Context, g model.Game) bool {
+	ctx, done := startQuery(ctx, "game", "Save")
+	defer done()
+
+	_, err := r.db.ExecContext(ctx,
 		`REPLACE INTO game (
                    game_key, 
                    player1_id, 
                    player2_id, 
+                   invitee_id, 
                    created_at, 
                    started_at, 
                    finished_at, 
+                   last_move_at, 
                    player_turn_id, 
                    public, 
                    status,
                    board_json) 
-			   VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		g.Key, g.Player1.Id, g.Player2.Id, g.CreatedAt, g.StartedAt, g.FinishedAt, g.CurrentPlayer().Id, g.Public, g.Status, g.Board.String())
+			   VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		g.Key, g.Player1.Id, nullId(g.Player2.Id), nullId(g.Invitee.Id), g.CreatedAt, nullTime(g.StartedAt), nullTime(g.FinishedAt), nullTime(g.LastMoveAt), nullId(g.CurrentPlayer().Id), g.Public, g.Status, g.Board.String())
 	if err != nil {
-		log.Errorf("Error saving the game into the database: %v\n", err)
+		log.WithContext(ctx).Errorf("Error saving the game into the database: %v\n", err)
 		return false
 	}
 	return true
 }
 
-func (r MariaDbGameRepository) Fetch(key string) (model.Game, error) {
-	row := r.db.QueryRow(`SELECT 
+func (r MariaDbGameRepository) Fetch(ctx context.Context, key string) (model.Game, error) {
+	ctx, done := startQuery(ctx, "game", "Fetch")
+	defer done()
+
+	row := r.db.QueryRowContext(ctx, `SELECT 
     game_key, 
     g.board_json, 
     u1.email as player1_email,
@@ -51,21 +61,27 @@ func (r MariaDbGameRepository) Fetch(key string) (model.Game, error) {
     ifnull(u2.email, '') as player2_email,
     ifnull(u2.id, 0) as player2_id,
     ifnull(u2.name, '') as player2_name,
-    g.player_turn_id, 
+    ifnull(u3.email, '') as invitee_email,
+    ifnull(u3.id, 0) as invitee_id,
+    ifnull(u3.name, '') as invitee_name,
+    ifnull(g.player_turn_id, 0) as player_turn_id, 
     g.created_at, 
     g.started_at, 
     g.finished_at, 
+    g.last_move_at, 
     g.status, 
     g.public 
 	FROM game g
 	JOIN user u1 ON u1.id = g.player1_id
 	LEFT JOIN user u2 ON u2.id = g.player2_id
+	LEFT JOIN user u3 ON u3.id = g.invitee_id
 	WHERE game_key = ?`, key)
 
 	var g model.Game
 	var p1 model.User
 	var p2 model.User
 	var playerTurnId int64
+	var startedAt, finishedAt, lastMoveAt sql.NullTime
 	var boardJson string
 	err := row.Scan(
 		&g.Key,
@@ -76,21 +92,28 @@ func (r MariaDbGameRepository) Fetch(key string) (model.Game, error) {
 		&p2.Email,
 		&p2.Id,
 		&p2.Name,
+		&g.Invitee.Email,
+		&g.Invitee.Id,
+		&g.Invitee.Name,
 		&playerTurnId,
 		&g.CreatedAt,
-		&g.StartedAt,
-		&g.FinishedAt,
+		&startedAt,
+		&finishedAt,
+		&lastMoveAt,
 		&g.Status,
 		&g.Public,
 	)
 
 	if err != nil {
-		log.Errorf("Error scanning the game row: %v\n", err)
+		log.WithContext(ctx).Errorf("Error scanning the game row: %v\n", err)
 		return model.Game{}, err
 	}
 
 	g.Player1 = p1
 	g.Player2 = p2
+	g.StartedAt = startedAt.Time
+	g.FinishedAt = finishedAt.Time
+	g.LastMoveAt = lastMoveAt.Time
 	if playerTurnId == p1.Id {
 		g.PlayerTurn = 1
 	} else {
@@ -101,52 +124,138 @@ func (r MariaDbGameRepository) Fetch(key string) (model.Game, error) {
 	return g, nil
 }
 
-func (r MariaDbGameRepository) List(userId int64, status string) ([]model.Game, error) {
+// List returns the games that match the filter, in its order. Games that were created at the same time are ordered by
+// their key, so a page always continues where the previous one stopped.
+func (r MariaDbGameRepository) List(ctx context.Context, f model.GameFilter) ([]model.Game, error) {
+	ctx, done := startQuery(ctx, "game", "List")
+	defer done()
+
+	criteria := make([]string, 0)
+	args := make([]interface{}, 0)
+
+	if f.Status != "" {
+		criteria = append(criteria, "(g.status = ?)")
+		args = append(args, f.Status)
+	}
+
+	if f.PlayerId > 0 {
+		criteria = append(criteria, "(g.player1_id = ? || g.player2_id = ?)")
+		args = append(args, f.PlayerId, f.PlayerId)
+	}
+
+	if f.OpponentId > 0 {
+		criteria = append(criteria, "(g.player1_id = ? || g.player2_id = ? || g.invitee_id = ?)")
+		args = append(args, f.OpponentId, f.OpponentId, f.OpponentId)
+	}
+
+	if f.Public != nil {
+		criteria = append(criteria, "(g.public = ?)")
+		args = append(args, *f.Public)
+	}
+
+	if !f.From.IsZero() {
+		criteria = append(criteria, "(g.created_at >= ?)")
+		args = append(args, f.From)
+	}
+
+	if !f.Until.IsZero() {
+		criteria = append(criteria, "(g.created_at < ?)")
+		args = append(args, f.Until)
+	}
+
+	direction, after := "DESC", "<"
+	if f.Order == model.OldestFirst {
+		direction, after = "ASC", ">"
+	}
+
+	if f.After != nil {
+		criteria = append(criteria, fmt.Sprintf("(g.created_at %[1]s ? || (g.created_at = ? && g.game_key %[1]s ?))", after))
+		args = append(args, f.After.CreatedAt, f.After.CreatedAt, f.After.Key)
+	}
+
+	suffix := fmt.Sprintf(" ORDER BY g.created_at %[1]s, g.game_key %[1]s", direction)
+	if f.Limit > 0 {
+		suffix += " LIMIT ?"
+		args = append(args, f.Limit)
+	}
+
+	return r.list(ctx, criteria, args, suffix)
+}
+
+// ListInvitations returns the games that the user was invited to and that haven't been accepted or declined yet.
+func (r MariaDbGameRepository) ListInvitations(ctx context.Context, inviteeId int64) ([]model.Game, error) {
+	ctx, done := startQuery(ctx, "game", "ListInvitations")
+	defer done()
+
+	return r.list(ctx,
+		[]string{"(g.invitee_id = ?)", "(g.status = ?)"},
+		[]interface{}{inviteeId, model.Created},
+		" ORDER BY g.created_at DESC")
+}
+
+// CountByStatus counts the games per status.
+func (r MariaDbGameRepository) CountByStatus(ctx context.Context) (map[model.GameStatus]int, error) {
+	ctx, done := startQuery(ctx, "game", "CountByStatus")
+	defer done()
+
+	rows, err := r.db.QueryContext(ctx, "SELECT status, COUNT(*) FROM game GROUP BY status")
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error counting games: %v\n", err)
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	counts := make(map[model.GameStatus]int)
+	for rows.Next() {
+		var status model.GameStatus
+		var count int
+		if err = rows.Scan(&status, &count); err != nil {
+			log.WithContext(ctx).Errorf("Error scanning the game count: %v\n", err)
+			return nil, err
+		}
+		counts[status] = count
+	}
+	return counts, rows.Err()
+}
+
+// list returns the games that match all the criteria. The suffix is added to the query, to order and limit the games.
+func (r MariaDbGameRepository) list(ctx context.Context, criteria []string, args []interface{}, suffix string) ([]model.Game, error) {
 	baseQuery := `	SELECT 
     g.game_key, 
+    ifnull(g.board_json, '') as board_json, 
     u1.email as player1_email,
     u1.id as player1_id,
     u1.name as player1_name,
     ifnull(u2.email, '') as player2_email,
     ifnull(u2.id, 0) as player2_id,
     ifnull(u2.name, '') as player2_name,
-    g.player_turn_id, 
+    ifnull(u3.email, '') as invitee_email,
+    ifnull(u3.id, 0) as invitee_id,
+    ifnull(u3.name, '') as invitee_name,
+    ifnull(g.player_turn_id, 0) as player_turn_id, 
     g.created_at, 
     g.started_at, 
     g.finished_at, 
+    g.last_move_at, 
     g.status, 
     g.public 
 	FROM game g
 	JOIN user u1 ON u1.id = g.player1_id
-	LEFT JOIN user u2 ON u2.id = g.player2_id`
-
-	args := make([]interface{}, 0)
+	LEFT JOIN user u2 ON u2.id = g.player2_id
+	LEFT JOIN user u3 ON u3.id = g.invitee_id`
 
-	if status != "" || userId > 0 {
+	if len(criteria) > 0 {
 		baseQuery = baseQuery + " WHERE "
 	}
 
-	criteria := make([]string, 0)
-
-	if status != "" {
-		criteria = append(criteria, "(status = ?)")
-		args = append(args, status)
-	}
-
-	if userId > 0 {
-		criteria = append(criteria, "(g.player1_id = ? || g.player2_id = ?)")
-		args = append(args, userId, userId)
-	}
-
-	// select games that are open
-	rows, err := r.db.Query(baseQuery+strings.Join(criteria, " AND "), args...)
+	rows, err := r.db.QueryContext(ctx, baseQuery+strings.Join(criteria, " AND ")+suffix, args...)
 
 	if err == nil {
 		err = rows.Err()
 	}
 
 	if err != nil {
-		log.Errorf("Error getting all the open games from the database: %v\n", err)
+		log.WithContext(ctx).Errorf("Error listing the games from the database: %v\n", err)
 		return nil, err
 	}
 
@@ -157,35 +266,48 @@ func (r MariaDbGameRepository) List(userId int64, status string) ([]model.Game,
 		var p1 model.User
 		var p2 model.User
 		var playerTurnId int64
+		var startedAt, finishedAt, lastMoveAt sql.NullTime
+		var boardJson string
 		err = rows.Scan(
 			&g.Key,
+			&boardJson,
 			&p1.Email,
 			&p1.Id,
 			&p1.Name,
 			&p2.Email,
 			&p2.Id,
 			&p2.Name,
+			&g.Invitee.Email,
+			&g.Invitee.Id,
+			&g.Invitee.Name,
 			&playerTurnId,
 			&g.CreatedAt,
-			&g.StartedAt,
-			&g.FinishedAt,
+			&startedAt,
+			&finishedAt,
+			&lastMoveAt,
 			&g.Status,
 			&g.Public,
 		)
 
 		if err != nil {
-			log.Errorf("Error scanning the game row: %v\n", err)
+			log.WithContext(ctx).Errorf("Error scanning the game row: %v\n", err)
 			_ = rows.Close()
 			return nil, err
 		}
 
 		g.Player1 = p1
 		g.Player2 = p2
+		g.StartedAt = startedAt.Time
+		g.FinishedAt = finishedAt.Time
+		g.LastMoveAt = lastMoveAt.Time
 		if playerTurnId == p1.Id {
 			g.PlayerTurn = 1
 		} else {
 			g.PlayerTurn = 2
 		}
+		if boardJson != "" {
+			g.Board, _ = model.BoardFromString(boardJson)
+		}
 		output = append(output, g)
 	}
 
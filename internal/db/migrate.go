@@ -0,0 +1,264 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLock is the name of the lock that keeps api instances that start at the same time from migrating twice.
+const migrationLock = "connectfour_schema_migrations"
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is a versioned change of the schema, with the SQL to apply it (up) and to revert it (down).
+type Migration struct {
+	Version int
+	Name    string
+	up      string
+	down    string
+}
+
+// MigrationStatus tells whether a migration was applied, and when.
+type MigrationStatus struct {
+	Migration
+	AppliedAt time.Time // zero when it wasn't applied.
+}
+
+// Migrator applies the migrations that are embedded in the binary, and keeps track of them in the
+// schema_migrations table. MariaDB commits every DDL statement right away, so a migration that fails halfway has to
+// be fixed by hand; the migrations use IF (NOT) EXISTS where they can, so they can be run again after that.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+func NewMigrator() (*Migrator, error) {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		db:         connect(),
+		migrations: migrations,
+	}, nil
+}
+
+// loadMigrations reads the NNNN_name.up.sql and NNNN_name.down.sql files. Every version needs both, and the versions
+// must be numbered 1, 2, 3 etc.
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.Glob(fsys, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, file := range files {
+		match := migrationFileName.FindStringSubmatch(path.Base(file))
+		if match == nil {
+			return nil, fmt.Errorf("migration %s isn't named like 0001_name.up.sql", file)
+		}
+		version, _ := strconv.Atoi(match[1])
+		b, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.up = string(b)
+		} else {
+			m.down = string(b)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migration %d is missing", i+1)
+		}
+		if strings.TrimSpace(m.up) == "" || strings.TrimSpace(m.down) == "" {
+			return nil, fmt.Errorf("migration %d (%s) needs both an up and a down file", m.Version, m.Name)
+		}
+	}
+	return migrations, nil
+}
+
+// statements splits a migration into its statements, which end with a semicolon at the end of a line. Lines that
+// start with -- are comments.
+func statements(script string) []string {
+	var output []string
+	var current strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			output = append(output, strings.TrimSuffix(strings.TrimSpace(current.String()), ";"))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		output = append(output, rest)
+	}
+	return output
+}
+
+// Up applies the migrations that weren't applied yet, and returns them. It does nothing when the schema is up to
+// date, so it can run at every start.
+func (m *Migrator) Up() ([]Migration, error) {
+	var applied []Migration
+	err := m.locked(func(conn *sql.Conn) error {
+		versions, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, ok := versions[migration.Version]; ok {
+				continue
+			}
+			log.Infof("Applying migration %d (%s)...", migration.Version, migration.Name)
+			if err = run(conn, migration.up); err != nil {
+				return fmt.Errorf("migration %d (%s): %w", migration.Version, migration.Name, err)
+			}
+			_, err = conn.ExecContext(context.Background(),
+				"INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+				migration.Version, migration.Name, time.Now())
+			if err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last applied migrations, at most steps of them, and returns them.
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.locked(func(conn *sql.Conn) error {
+		versions, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := versions[migration.Version]; !ok {
+				continue
+			}
+			log.Infof("Reverting migration %d (%s)...", migration.Version, migration.Name)
+			if err = run(conn, migration.down); err != nil {
+				return fmt.Errorf("migration %d (%s): %w", migration.Version, migration.Name, err)
+			}
+			_, err = conn.ExecContext(context.Background(), "DELETE FROM schema_migrations WHERE version = ?", migration.Version)
+			if err != nil {
+				return err
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status returns all migrations, with when they were applied.
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	var output []MigrationStatus
+	err := m.locked(func(conn *sql.Conn) error {
+		versions, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			output = append(output, MigrationStatus{Migration: migration, AppliedAt: versions[migration.Version]})
+		}
+		return nil
+	})
+	return output, err
+}
+
+// locked runs the func on a connection that holds the migration lock, after creating the schema_migrations table.
+func (m *Migrator) locked(fn func(conn *sql.Conn) error) error {
+	ctx := context.Background()
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("connecting to the database: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	var acquired sql.NullInt64
+	if err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 60)", migrationLock).Scan(&acquired); err != nil {
+		return err
+	}
+	if acquired.Int64 != 1 {
+		return fmt.Errorf("another instance is migrating the schema")
+	}
+	defer func() { _, _ = conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", migrationLock) }()
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations
+		(
+			version    INT          NOT NULL,
+			name       VARCHAR(255) NOT NULL,
+			applied_at DATETIME     NOT NULL,
+			PRIMARY KEY (version)
+		)`)
+	if err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func appliedVersions(conn *sql.Conn) (map[int]time.Time, error) {
+	rows, err := conn.QueryContext(context.Background(), "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	versions := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err = rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		versions[version] = appliedAt
+	}
+	return versions, rows.Err()
+}
+
+func run(conn *sql.Conn, script string) error {
+	for _, statement := range statements(script) {
+		if _, err := conn.ExecContext(context.Background(), statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadMigrations_Embedded(t *testing.T) {
+	// Act
+	migrations, err := loadMigrations(migrationFiles)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.NotEmpty(t, migrations)
+	for i, m := range migrations {
+		assert.Equal(t, i+1, m.Version)
+		assert.NotEmpty(t, statements(m.up), "Expected migration %d to have statements to apply", m.Version)
+		assert.NotEmpty(t, statements(m.down), "Expected migration %d to have statements to revert", m.Version)
+	}
+}
+
+func TestLoadMigrations_Invalid(t *testing.T) {
+	// Arrange
+	sql := &fstest.MapFile{Data: []byte("SELECT 1;")}
+	missingDown := fstest.MapFS{
+		"migrations/0001_first.up.sql": sql,
+	}
+	gap := fstest.MapFS{
+		"migrations/0001_first.up.sql":    sql,
+		"migrations/0001_first.down.sql":  sql,
+		"migrations/0003_third.up.sql":    sql,
+		"migrations/0003_third.down.sql":  sql,
+		"migrations/0002_second.down.sql": sql,
+	}
+	badName := fstest.MapFS{
+		"migrations/first.up.sql": sql,
+	}
+
+	// Act
+	_, errMissingDown := loadMigrations(missingDown)
+	_, errGap := loadMigrations(gap)
+	_, errBadName := loadMigrations(badName)
+
+	// Assert
+	assert.Error(t, errMissingDown)
+	assert.Error(t, errGap)
+	assert.Error(t, errBadName)
+}
+
+func TestStatements(t *testing.T) {
+	// Arrange
+	script := `-- A comment; with a semicolon
+CREATE TABLE a
+(
+    id INT -- the id
+);
+
+INSERT INTO a VALUES (1);
+UPDATE a SET id = 2`
+
+	// Act
+	output := statements(script)
+
+	// Assert
+	assert.Equal(t, []string{
+		"CREATE TABLE a\n(\n    id INT -- the id\n)",
+		"INSERT INTO a VALUES (1)",
+		"UPDATE a SET id = 2",
+	}, output)
+}
@@ -1,6 +1,7 @@
-USE connectfour;
+-- The schema as it was created by sql/00_initialize.sql, so existing databases are adopted as they are. The tables
+-- and columns that were added since then are added by the migrations after this one.
 
-CREATE TABLE user
+CREATE TABLE IF NOT EXISTS user
 (
     id    BIGINT UNSIGNED AUTO_INCREMENT NOT NULL,
     email VARCHAR(255)                   NOT NULL,
@@ -9,10 +10,9 @@ CREATE TABLE user
     PRIMARY KEY (id)
 );
 
-CREATE INDEX idx_user_email ON user (email);
+CREATE INDEX IF NOT EXISTS idx_user_email ON user (email);
 
-
-CREATE TABLE game
+CREATE TABLE IF NOT EXISTS game
 (
     game_key       VARCHAR(20) NOT NULL,
     player1_id     BIGINT      NOT NULL,
@@ -25,5 +25,4 @@ CREATE TABLE game
     status         VARCHAR(20) NOT NULL,
     board_json     TEXT        NULL,
     PRIMARY KEY (game_key)
-)
-
+);
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS password_reset;
+
+DROP TABLE IF EXISTS user_identity;
+
+DROP TABLE IF EXISTS friend;
+
+ALTER TABLE user
+    DROP COLUMN IF EXISTS banned,
+    DROP COLUMN IF EXISTS role,
+    DROP COLUMN IF EXISTS verified,
+    DROP COLUMN IF EXISTS bot;
@@ -0,0 +1,43 @@
+-- Bots, verified e-mail addresses, roles and bans, and the friends, identities and password resets of the users.
+
+ALTER TABLE user
+    ADD COLUMN IF NOT EXISTS bot      BOOL        NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS verified BOOL        NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS role     VARCHAR(20) NOT NULL DEFAULT 'player',
+    ADD COLUMN IF NOT EXISTS banned   BOOL        NOT NULL DEFAULT FALSE;
+
+-- The users from before the e-mail addresses were verified keep creating public games, like they always could. The
+-- api doesn't serve until the migrations are applied, so there are no new users yet.
+UPDATE user SET verified = TRUE;
+
+CREATE TABLE IF NOT EXISTS friend
+(
+    user_id    BIGINT      NOT NULL,
+    friend_id  BIGINT      NOT NULL,
+    status     VARCHAR(20) NOT NULL,
+    created_at DATETIME    NOT NULL,
+    PRIMARY KEY (user_id, friend_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_friend_friend_id ON friend (friend_id);
+
+CREATE TABLE IF NOT EXISTS user_identity
+(
+    issuer  VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    user_id BIGINT       NOT NULL,
+    PRIMARY KEY (issuer, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identity_user_id ON user_identity (user_id);
+
+CREATE TABLE IF NOT EXISTS password_reset
+(
+    token_hash VARCHAR(64) NOT NULL,
+    user_id    BIGINT      NOT NULL,
+    expires_at DATETIME    NOT NULL,
+    used_at    DATETIME    NULL,
+    PRIMARY KEY (token_hash)
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_reset_user_id ON password_reset (user_id);
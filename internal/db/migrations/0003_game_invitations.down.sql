@@ -0,0 +1,2 @@
+ALTER TABLE game
+    DROP COLUMN IF EXISTS invitee_id;
@@ -0,0 +1,4 @@
+-- A game can be created for an invited player, who is the only one that can join it.
+
+ALTER TABLE game
+    ADD COLUMN IF NOT EXISTS invitee_id BIGINT NULL AFTER player2_id;
@@ -0,0 +1,38 @@
+-- Tournaments, their players and the pairings of every round.
+
+CREATE TABLE IF NOT EXISTS tournament
+(
+    id            BIGINT UNSIGNED AUTO_INCREMENT NOT NULL,
+    name          VARCHAR(255)                   NOT NULL,
+    format        VARCHAR(20)                    NOT NULL,
+    status        VARCHAR(20)                    NOT NULL,
+    organizer_id  BIGINT                         NOT NULL,
+    rounds        INT                            NOT NULL DEFAULT 0,
+    current_round INT                            NOT NULL DEFAULT 0,
+    created_at    DATETIME                       NOT NULL,
+    started_at    DATETIME                       NULL,
+    finished_at   DATETIME                       NULL,
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS tournament_player
+(
+    tournament_id BIGINT   NOT NULL,
+    user_id       BIGINT   NOT NULL,
+    registered_at DATETIME NOT NULL,
+    PRIMARY KEY (tournament_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS tournament_pairing
+(
+    id            BIGINT UNSIGNED AUTO_INCREMENT NOT NULL,
+    tournament_id BIGINT                         NOT NULL,
+    round         INT                            NOT NULL,
+    game_key      VARCHAR(20)                    NULL,
+    player1_id    BIGINT                         NOT NULL,
+    player2_id    BIGINT                         NULL,
+    result        VARCHAR(20)                    NOT NULL DEFAULT '',
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_tournament_pairing_game_key ON tournament_pairing (game_key);
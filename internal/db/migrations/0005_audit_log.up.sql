@@ -0,0 +1,18 @@
+-- The audit log of security and game events.
+
+CREATE TABLE IF NOT EXISTS audit_log
+(
+    id         BIGINT UNSIGNED AUTO_INCREMENT NOT NULL,
+    created_at DATETIME(3)                    NOT NULL,
+    action     VARCHAR(40)                    NOT NULL,
+    actor      VARCHAR(255)                   NOT NULL,
+    ip         VARCHAR(45)                    NOT NULL,
+    request_id VARCHAR(100)                   NOT NULL,
+    subject    VARCHAR(255)                   NOT NULL,
+    details    VARCHAR(1000)                  NOT NULL,
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log (actor, created_at);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_subject ON audit_log (subject, created_at);
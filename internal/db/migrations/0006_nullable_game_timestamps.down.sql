@@ -0,0 +1,6 @@
+-- MariaDB refuses zero dates in its default (strict) SQL mode, so the games that haven't finished get the lowest date
+-- that it accepts, which the up migration turns into NULL again.
+
+UPDATE game SET finished_at = '1000-01-01 00:00:00' WHERE finished_at IS NULL;
+
+ALTER TABLE game MODIFY finished_at DATETIME NOT NULL;
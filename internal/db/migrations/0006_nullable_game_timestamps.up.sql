@@ -0,0 +1,7 @@
+-- A game that hasn't finished has no finished_at, instead of a zero date (or the lowest date, after a down migration).
+
+ALTER TABLE game MODIFY finished_at DATETIME NULL;
+
+UPDATE game SET started_at = NULL WHERE started_at < '1000-01-01';
+
+UPDATE game SET finished_at = NULL WHERE finished_at <= '1000-01-01';
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_game_invitee_id ON game;
+
+DROP INDEX IF EXISTS idx_game_player2_id ON game;
+
+DROP INDEX IF EXISTS idx_game_player1_id ON game;
@@ -0,0 +1,7 @@
+-- The games of a player are listed on every screen, so the player columns need an index.
+
+CREATE INDEX IF NOT EXISTS idx_game_player1_id ON game (player1_id);
+
+CREATE INDEX IF NOT EXISTS idx_game_player2_id ON game (player2_id);
+
+CREATE INDEX IF NOT EXISTS idx_game_invitee_id ON game (invitee_id, status);
@@ -0,0 +1,11 @@
+ALTER TABLE game
+    DROP FOREIGN KEY IF EXISTS fk_game_player1,
+    DROP FOREIGN KEY IF EXISTS fk_game_player2,
+    DROP FOREIGN KEY IF EXISTS fk_game_invitee,
+    DROP FOREIGN KEY IF EXISTS fk_game_player_turn;
+
+ALTER TABLE game
+    MODIFY player1_id     BIGINT NOT NULL,
+    MODIFY player2_id     BIGINT NULL,
+    MODIFY invitee_id     BIGINT NULL,
+    MODIFY player_turn_id BIGINT NULL;
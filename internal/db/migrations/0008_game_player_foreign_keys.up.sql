@@ -0,0 +1,20 @@
+-- The players of a game must exist. Users are never deleted (only anonymized), so the games keep their players.
+-- The columns have to match the unsigned user.id, and a missing player is NULL rather than 0.
+
+UPDATE game SET player2_id = NULL WHERE player2_id = 0;
+
+UPDATE game SET invitee_id = NULL WHERE invitee_id = 0;
+
+UPDATE game SET player_turn_id = NULL WHERE player_turn_id = 0;
+
+ALTER TABLE game
+    MODIFY player1_id     BIGINT UNSIGNED NOT NULL,
+    MODIFY player2_id     BIGINT UNSIGNED NULL,
+    MODIFY invitee_id     BIGINT UNSIGNED NULL,
+    MODIFY player_turn_id BIGINT UNSIGNED NULL;
+
+ALTER TABLE game
+    ADD CONSTRAINT fk_game_player1 FOREIGN KEY IF NOT EXISTS (player1_id) REFERENCES user (id),
+    ADD CONSTRAINT fk_game_player2 FOREIGN KEY IF NOT EXISTS (player2_id) REFERENCES user (id),
+    ADD CONSTRAINT fk_game_invitee FOREIGN KEY IF NOT EXISTS (invitee_id) REFERENCES user (id),
+    ADD CONSTRAINT fk_game_player_turn FOREIGN KEY IF NOT EXISTS (player_turn_id) REFERENCES user (id);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_game_status_created_at ON game;
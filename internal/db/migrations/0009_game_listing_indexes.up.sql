@@ -0,0 +1,3 @@
+-- The game listings are paged by created_at and game_key, so the open games can be read from the index in that order.
+
+CREATE INDEX IF NOT EXISTS idx_game_status_created_at ON game (status, created_at, game_key);
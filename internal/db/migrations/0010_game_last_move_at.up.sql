@@ -0,0 +1,4 @@
+-- The game listings show when the last move was played, so players can see which games are still going on.
+
+ALTER TABLE game
+    ADD COLUMN IF NOT EXISTS last_move_at DATETIME NULL AFTER finished_at;
@@ -0,0 +1,26 @@
+-- The inbox of notifications of every user, and the channels that the user wants to be notified on.
+
+CREATE TABLE IF NOT EXISTS notification
+(
+    id         BIGINT UNSIGNED AUTO_INCREMENT NOT NULL,
+    user_id    BIGINT UNSIGNED                NOT NULL,
+    kind       VARCHAR(40)                    NOT NULL,
+    game_key   VARCHAR(20)                    NOT NULL,
+    message    VARCHAR(255)                   NOT NULL,
+    created_at DATETIME(3)                    NOT NULL,
+    read_at    DATETIME(3)                    NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_notification_user FOREIGN KEY (user_id) REFERENCES user (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_user_id ON notification (user_id, read_at);
+
+CREATE TABLE IF NOT EXISTS notification_preferences
+(
+    user_id     BIGINT UNSIGNED NOT NULL,
+    channels    VARCHAR(100)    NOT NULL,
+    muted       VARCHAR(255)    NOT NULL DEFAULT '',
+    webhook_url VARCHAR(2048)   NOT NULL DEFAULT '',
+    PRIMARY KEY (user_id),
+    CONSTRAINT fk_notification_preferences_user FOREIGN KEY (user_id) REFERENCES user (id)
+);
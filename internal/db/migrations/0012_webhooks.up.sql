@@ -0,0 +1,36 @@
+-- The webhooks that game events are posted to, and the log of their deliveries.
+
+CREATE TABLE IF NOT EXISTS webhook
+(
+    id         BIGINT UNSIGNED AUTO_INCREMENT NOT NULL,
+    owner_id   BIGINT UNSIGNED                NOT NULL,
+    url        VARCHAR(2048)                  NOT NULL,
+    secret     VARCHAR(64)                    NOT NULL,
+    events     VARCHAR(255)                   NOT NULL DEFAULT '',
+    all_games  BOOL                           NOT NULL DEFAULT FALSE,
+    created_at DATETIME(3)                    NOT NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_webhook_owner FOREIGN KEY (owner_id) REFERENCES user (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_owner_id ON webhook (owner_id);
+
+CREATE TABLE IF NOT EXISTS webhook_delivery
+(
+    id              BIGINT UNSIGNED AUTO_INCREMENT NOT NULL,
+    webhook_id      BIGINT UNSIGNED                NOT NULL,
+    event           VARCHAR(40)                    NOT NULL,
+    game_key        VARCHAR(20)                    NOT NULL,
+    payload         TEXT                           NOT NULL,
+    status          VARCHAR(20)                    NOT NULL,
+    attempts        INT                            NOT NULL DEFAULT 0,
+    response_status INT                            NOT NULL DEFAULT 0,
+    error           VARCHAR(1000)                  NOT NULL DEFAULT '',
+    created_at      DATETIME(3)                    NOT NULL,
+    last_attempt_at DATETIME(3)                    NULL,
+    next_attempt_at DATETIME(3)                    NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_webhook_delivery_webhook FOREIGN KEY (webhook_id) REFERENCES webhook (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_webhook_id ON webhook_delivery (webhook_id, id);
@@ -0,0 +1,4 @@
+-- The version of the tokens of a user. Raising it revokes the tokens that were issued before.
+
+ALTER TABLE user
+    ADD COLUMN IF NOT EXISTS token_version INT NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+ALTER TABLE notification_preferences
+    ADD COLUMN IF NOT EXISTS webhook_url VARCHAR(2048) NOT NULL DEFAULT '';
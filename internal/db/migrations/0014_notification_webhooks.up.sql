@@ -0,0 +1,5 @@
+-- The webhook channel of the notifications posts to the registered webhooks that want the notification event, so
+-- the webhook url of the preferences isn't used anymore.
+
+ALTER TABLE notification_preferences
+    DROP COLUMN IF EXISTS webhook_url;
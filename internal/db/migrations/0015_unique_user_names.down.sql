@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_user_name_key ON user;
+
+ALTER TABLE user
+    DROP COLUMN IF EXISTS name_key;
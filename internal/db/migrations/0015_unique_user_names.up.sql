@@ -0,0 +1,17 @@
+-- Display names are unique, ignoring the case. Deleted users all have the same name, so they're left out. The names
+-- that are already used more than once are kept by the first user, and get the id appended for the others.
+
+UPDATE user u
+    JOIN (SELECT *
+          FROM (SELECT LOWER(name) AS name_key, MIN(id) AS first_id
+                FROM user
+                WHERE email NOT LIKE 'deleted-%@connectfour.invalid'
+                GROUP BY LOWER(name)) n) f ON LOWER(u.name) = f.name_key AND u.id <> f.first_id
+SET u.name = CONCAT(LEFT(u.name, 230), ' #', u.id)
+WHERE u.email NOT LIKE 'deleted-%@connectfour.invalid';
+
+ALTER TABLE user
+    ADD COLUMN IF NOT EXISTS name_key VARCHAR(255)
+        AS (IF(email LIKE 'deleted-%@connectfour.invalid', NULL, LOWER(name))) PERSISTENT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_name_key ON user (name_key);
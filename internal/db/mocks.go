@@ -2,7 +2,9 @@ package db
 
 import (
 	"connectfour/internal/model"
+	"context"
 	"github.com/stretchr/testify/mock"
+	"time"
 )
 
 type MockUserRepository struct {
@@ -13,16 +15,106 @@ func NewMockUserRepository() *MockUserRepository {
 	return &MockUserRepository{}
 }
 
-func (m *MockUserRepository) Create(u model.User) (model.User, error) {
-	args := m.Called(u)
+func (m *MockUserRepository) Create(ctx context.Context, u model.User) (model.User, error) {
+	args := m.Called(ctx, u)
 	return args.Get(0).(model.User), args.Error(1)
 }
 
-func (m *MockUserRepository) FindByEmail(email string) (model.User, error) {
-	args := m.Called(email)
+func (m *MockUserRepository) FindByEmail(ctx context.Context, email string) (model.User, error) {
+	args := m.Called(ctx, email)
 	return args.Get(0).(model.User), args.Error(1)
 }
 
+func (m *MockUserRepository) FindByName(ctx context.Context, name string) (model.User, error) {
+	args := m.Called(ctx, name)
+	return args.Get(0).(model.User), args.Error(1)
+}
+
+func (m *MockUserRepository) FindByIdentity(ctx context.Context, issuer string, subject string) (model.User, error) {
+	args := m.Called(ctx, issuer, subject)
+	return args.Get(0).(model.User), args.Error(1)
+}
+
+func (m *MockUserRepository) AddIdentity(ctx context.Context, userId int64, issuer string, subject string) error {
+	args := m.Called(ctx, userId, issuer, subject)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) Update(ctx context.Context, u model.User) error {
+	args := m.Called(ctx, u)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) SetVerified(ctx context.Context, userId int64) error {
+	args := m.Called(ctx, userId)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) SetRole(ctx context.Context, userId int64, role model.Role) error {
+	args := m.Called(ctx, userId, role)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) SetBanned(ctx context.Context, userId int64, banned bool) error {
+	args := m.Called(ctx, userId, banned)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) SetBot(ctx context.Context, userId int64, bot bool) error {
+	args := m.Called(ctx, userId, bot)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) RevokeTokens(ctx context.Context, userId int64) error {
+	args := m.Called(ctx, userId)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) Search(ctx context.Context, query string, limit int) ([]model.User, error) {
+	args := m.Called(ctx, query, limit)
+	return args.Get(0).([]model.User), args.Error(1)
+}
+
+func (m *MockUserRepository) Stats(ctx context.Context) (model.UserStats, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(model.UserStats), args.Error(1)
+}
+
+func (m *MockUserRepository) Delete(ctx context.Context, userId int64) error {
+	args := m.Called(ctx, userId)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) CreateResetToken(ctx context.Context, userId int64, tokenHash string, expiresAt time.Time) error {
+	args := m.Called(ctx, userId, tokenHash, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) ConsumeResetToken(ctx context.Context, tokenHash string) (model.User, error) {
+	args := m.Called(ctx, tokenHash)
+	return args.Get(0).(model.User), args.Error(1)
+}
+
+func (m *MockUserRepository) AddFriend(ctx context.Context, userId int64, friendId int64) error {
+	args := m.Called(ctx, userId, friendId)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) AcceptFriend(ctx context.Context, userId int64, friendId int64) error {
+	args := m.Called(ctx, userId, friendId)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) RemoveFriend(ctx context.Context, userId int64, friendId int64) error {
+	args := m.Called(ctx, userId, friendId)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) ListFriends(ctx context.Context, userId int64) ([]model.Friend, error) {
+	args := m.Called(ctx, userId)
+	return args.Get(0).([]model.Friend), args.Error(1)
+}
+
 type MockGameRepository struct {
 	mock.Mock
 }
@@ -31,17 +123,173 @@ func NewMockGameRepository() *MockGameRepository {
 	return &MockGameRepository{}
 }
 
-func (m *MockGameRepository) Save(game model.Game) bool {
-	args := m.Called(game)
+func (m *MockGameRepository) Save(ctx context.Context, game model.Game) bool {
+	args := m.Called(ctx, game)
 	return args.Bool(0)
 }
 
-func (m *MockGameRepository) Fetch(key string) (model.Game, error) {
-	args := m.Called(key)
+func (m *MockGameRepository) Fetch(ctx context.Context, key string) (model.Game, error) {
+	args := m.Called(ctx, key)
 	return args.Get(0).(model.Game), args.Error(1)
 }
 
-func (m *MockGameRepository) List(userId int64, status string) ([]model.Game, error) {
-	args := m.Called(userId, status)
+func (m *MockGameRepository) List(ctx context.Context, f model.GameFilter) ([]model.Game, error) {
+	args := m.Called(ctx, f)
 	return args.Get(0).([]model.Game), args.Error(1)
 }
+
+func (m *MockGameRepository) ListInvitations(ctx context.Context, inviteeId int64) ([]model.Game, error) {
+	args := m.Called(ctx, inviteeId)
+	return args.Get(0).([]model.Game), args.Error(1)
+}
+
+func (m *MockGameRepository) CountByStatus(ctx context.Context) (map[model.GameStatus]int, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(map[model.GameStatus]int), args.Error(1)
+}
+
+type MockTournamentRepository struct {
+	mock.Mock
+}
+
+func NewMockTournamentRepository() *MockTournamentRepository {
+	return &MockTournamentRepository{}
+}
+
+func (m *MockTournamentRepository) Create(ctx context.Context, t model.Tournament) (model.Tournament, error) {
+	args := m.Called(ctx, t)
+	return args.Get(0).(model.Tournament), args.Error(1)
+}
+
+func (m *MockTournamentRepository) Update(ctx context.Context, t model.Tournament) error {
+	args := m.Called(ctx, t)
+	return args.Error(0)
+}
+
+func (m *MockTournamentRepository) Fetch(ctx context.Context, id int64) (model.Tournament, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(model.Tournament), args.Error(1)
+}
+
+func (m *MockTournamentRepository) FetchByGameKey(ctx context.Context, key string) (model.Tournament, error) {
+	args := m.Called(ctx, key)
+	return args.Get(0).(model.Tournament), args.Error(1)
+}
+
+func (m *MockTournamentRepository) List(ctx context.Context) ([]model.Tournament, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]model.Tournament), args.Error(1)
+}
+
+func (m *MockTournamentRepository) AddPlayer(ctx context.Context, tournamentId int64, player model.User) error {
+	args := m.Called(ctx, tournamentId, player)
+	return args.Error(0)
+}
+
+func (m *MockTournamentRepository) SavePairing(ctx context.Context, tournamentId int64, p model.Pairing) (model.Pairing, error) {
+	args := m.Called(ctx, tournamentId, p)
+	// allow tests to echo the pairing, since its game key is generated.
+	if fn, ok := args.Get(0).(func(int64, model.Pairing) (model.Pairing, error)); ok {
+		return fn(tournamentId, p)
+	}
+	return args.Get(0).(model.Pairing), args.Error(1)
+}
+
+type MockAuditRepository struct {
+	mock.Mock
+}
+
+func NewMockAuditRepository() *MockAuditRepository {
+	return &MockAuditRepository{}
+}
+
+func (m *MockAuditRepository) Append(ctx context.Context, e model.AuditEntry) error {
+	args := m.Called(ctx, e)
+	return args.Error(0)
+}
+
+func (m *MockAuditRepository) List(ctx context.Context, f model.AuditFilter) ([]model.AuditEntry, error) {
+	args := m.Called(ctx, f)
+	return args.Get(0).([]model.AuditEntry), args.Error(1)
+}
+
+type MockNotificationRepository struct {
+	mock.Mock
+}
+
+func NewMockNotificationRepository() *MockNotificationRepository {
+	return &MockNotificationRepository{}
+}
+
+func (m *MockNotificationRepository) Add(ctx context.Context, n model.Notification) (model.Notification, error) {
+	args := m.Called(ctx, n)
+	return args.Get(0).(model.Notification), args.Error(1)
+}
+
+func (m *MockNotificationRepository) List(ctx context.Context, userId int64, unreadOnly bool, limit int) ([]model.Notification, error) {
+	args := m.Called(ctx, userId, unreadOnly, limit)
+	return args.Get(0).([]model.Notification), args.Error(1)
+}
+
+func (m *MockNotificationRepository) MarkRead(ctx context.Context, userId int64, id int64, at time.Time) error {
+	args := m.Called(ctx, userId, id, at)
+	return args.Error(0)
+}
+
+func (m *MockNotificationRepository) Preferences(ctx context.Context, userId int64) (model.NotificationPreferences, error) {
+	args := m.Called(ctx, userId)
+	return args.Get(0).(model.NotificationPreferences), args.Error(1)
+}
+
+func (m *MockNotificationRepository) SavePreferences(ctx context.Context, userId int64, p model.NotificationPreferences) error {
+	args := m.Called(ctx, userId, p)
+	return args.Error(0)
+}
+
+type MockWebhookRepository struct {
+	mock.Mock
+}
+
+func NewMockWebhookRepository() *MockWebhookRepository {
+	return &MockWebhookRepository{}
+}
+
+func (m *MockWebhookRepository) Create(ctx context.Context, w model.Webhook) (model.Webhook, error) {
+	args := m.Called(ctx, w)
+	return args.Get(0).(model.Webhook), args.Error(1)
+}
+
+func (m *MockWebhookRepository) Delete(ctx context.Context, ownerId int64, id int64) (bool, error) {
+	args := m.Called(ctx, ownerId, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockWebhookRepository) ListByOwner(ctx context.Context, ownerId int64) ([]model.Webhook, error) {
+	args := m.Called(ctx, ownerId)
+	return args.Get(0).([]model.Webhook), args.Error(1)
+}
+
+func (m *MockWebhookRepository) ListForPlayers(ctx context.Context, playerIds ...int64) ([]model.Webhook, error) {
+	args := m.Called(ctx, playerIds)
+	return args.Get(0).([]model.Webhook), args.Error(1)
+}
+
+func (m *MockWebhookRepository) AddDelivery(ctx context.Context, d model.WebhookDelivery) (model.WebhookDelivery, error) {
+	args := m.Called(ctx, d)
+	return args.Get(0).(model.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockWebhookRepository) UpdateDelivery(ctx context.Context, d model.WebhookDelivery) error {
+	args := m.Called(ctx, d)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) ListDeliveries(ctx context.Context, webhookId int64, limit int) ([]model.WebhookDelivery, error) {
+	args := m.Called(ctx, webhookId, limit)
+	return args.Get(0).([]model.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockWebhookRepository) ListPending(ctx context.Context) ([]model.PendingDelivery, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]model.PendingDelivery), args.Error(1)
+}
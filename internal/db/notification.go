@@ -0,0 +1,141 @@
+package db
+
+import (
+	"connectfour/internal/model"
+	"context"
+	"database/sql"
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+// MariaDbNotificationRepository stores the inbox and the notification preferences of the users.
+type MariaDbNotificationRepository struct {
+	db *sql.DB
+}
+
+var _ NotificationRepository = MariaDbNotificationRepository{}
+
+func NewMariaDbNotificationRepository() *MariaDbNotificationRepository {
+	return &MariaDbNotificationRepository{
+		db: connect(),
+	}
+}
+
+func (r MariaDbNotificationRepository) Add(ctx context.Context, n model.Notification) (model.Notification, error) {
+	ctx, done := startQuery(ctx, "notification", "Add")
+	defer done()
+
+	result, err := r.db.ExecContext(ctx,
+		"INSERT INTO notification (user_id, kind, game_key, message, created_at) VALUES (?, ?, ?, ?, ?)",
+		n.UserId, n.Kind, n.GameKey, n.Message, n.CreatedAt)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error inserting the notification for user %d: %v\n", n.UserId, err)
+		return model.Notification{}, err
+	}
+	n.Id, err = result.LastInsertId()
+	return n, err
+}
+
+// List returns the newest notifications of the user first, at most limit of them.
+func (r MariaDbNotificationRepository) List(ctx context.Context, userId int64, unreadOnly bool, limit int) ([]model.Notification, error) {
+	ctx, done := startQuery(ctx, "notification", "List")
+	defer done()
+
+	query := "SELECT id, user_id, kind, game_key, message, created_at, read_at FROM notification WHERE user_id = ?"
+	if unreadOnly {
+		query += " AND read_at IS NULL"
+	}
+	rows, err := r.db.QueryContext(ctx, query+" ORDER BY id DESC LIMIT ?", userId, limit)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error reading the notifications of user %d: %v\n", userId, err)
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	output := make([]model.Notification, 0)
+	for rows.Next() {
+		var n model.Notification
+		var readAt sql.NullTime
+		if err = rows.Scan(&n.Id, &n.UserId, &n.Kind, &n.GameKey, &n.Message, &n.CreatedAt, &readAt); err != nil {
+			log.WithContext(ctx).Errorf("Error scanning the notification row: %v\n", err)
+			return nil, err
+		}
+		n.ReadAt = readAt.Time
+		output = append(output, n)
+	}
+	return output, rows.Err()
+}
+
+// MarkRead marks the notification of the user as read, or all of their notifications when the id is 0.
+func (r MariaDbNotificationRepository) MarkRead(ctx context.Context, userId int64, id int64, at time.Time) error {
+	ctx, done := startQuery(ctx, "notification", "MarkRead")
+	defer done()
+
+	query := "UPDATE notification SET read_at = ? WHERE user_id = ? AND read_at IS NULL"
+	args := []any{at, userId}
+	if id != 0 {
+		query += " AND id = ?"
+		args = append(args, id)
+	}
+	if _, err := r.db.ExecContext(ctx, query, args...); err != nil {
+		log.WithContext(ctx).Errorf("Error marking the notifications of user %d as read: %v\n", userId, err)
+		return err
+	}
+	return nil
+}
+
+// Preferences returns the notification preferences of the user, or the defaults when the user didn't set any.
+func (r MariaDbNotificationRepository) Preferences(ctx context.Context, userId int64) (model.NotificationPreferences, error) {
+	ctx, done := startQuery(ctx, "notification", "Preferences")
+	defer done()
+
+	var channels, muted string
+	var p model.NotificationPreferences
+	err := r.db.QueryRowContext(ctx,
+		"SELECT channels, muted FROM notification_preferences WHERE user_id = ?", userId).
+		Scan(&channels, &muted)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.DefaultNotificationPreferences(), nil
+	}
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error reading the notification preferences of user %d: %v\n", userId, err)
+		return model.NotificationPreferences{}, err
+	}
+	p.Channels = splitList[model.NotificationChannel](channels)
+	p.Muted = splitList[model.NotificationKind](muted)
+	return p, nil
+}
+
+func (r MariaDbNotificationRepository) SavePreferences(ctx context.Context, userId int64, p model.NotificationPreferences) error {
+	ctx, done := startQuery(ctx, "notification", "SavePreferences")
+	defer done()
+
+	_, err := r.db.ExecContext(ctx,
+		"REPLACE INTO notification_preferences (user_id, channels, muted) VALUES (?, ?, ?)",
+		userId, joinList(p.Channels), joinList(p.Muted))
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error saving the notification preferences of user %d: %v\n", userId, err)
+	}
+	return err
+}
+
+// splitList reads a comma separated column into a slice, which is empty for an empty column.
+func splitList[T ~string](s string) []T {
+	output := make([]T, 0)
+	for _, value := range strings.Split(s, ",") {
+		if value != "" {
+			output = append(output, T(value))
+		}
+	}
+	return output
+}
+
+func joinList[T ~string](values []T) string {
+	s := make([]string, len(values))
+	for i, value := range values {
+		s[i] = string(value)
+	}
+	return strings.Join(s, ",")
+}
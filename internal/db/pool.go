@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// PoolConfig sizes the pool of database connections that all repositories share.
+type PoolConfig struct {
+	MaxOpenConns    int           // at most this many connections, in use or idle (0 is unlimited).
+	MaxIdleConns    int           // keep at most this many idle connections around.
+	ConnMaxLifetime time.Duration // close connections after this time, so they're spread over restarted nodes.
+	ConnMaxIdleTime time.Duration // close connections that weren't used for this time.
+	ConnectTimeout  time.Duration // how long to keep trying to reach the database at startup.
+}
+
+// DefaultPoolConfig suits a single api instance in front of a MariaDB with the default max_connections of 151.
+var DefaultPoolConfig = PoolConfig{
+	MaxOpenConns:    25,
+	MaxIdleConns:    10,
+	ConnMaxLifetime: 30 * time.Minute,
+	ConnMaxIdleTime: 5 * time.Minute,
+	ConnectTimeout:  time.Minute,
+}
+
+// the backoff between the pings at startup, which doubles after every failure.
+var (
+	initialBackoff = 250 * time.Millisecond
+	maxBackoff     = 5 * time.Second
+)
+
+var pool struct {
+	once sync.Once
+	db   *sql.DB
+}
+
+// PoolConfigFromEnv returns the DefaultPoolConfig, with the values that are set in the CONNECT_FOUR_DB_* environment
+// variables. Invalid values are logged and ignored.
+func PoolConfigFromEnv() PoolConfig {
+	cfg := DefaultPoolConfig
+	envInt("CONNECT_FOUR_DB_MAX_OPEN_CONNS", &cfg.MaxOpenConns)
+	envInt("CONNECT_FOUR_DB_MAX_IDLE_CONNS", &cfg.MaxIdleConns)
+	envDuration("CONNECT_FOUR_DB_CONN_MAX_LIFETIME", &cfg.ConnMaxLifetime)
+	envDuration("CONNECT_FOUR_DB_CONN_MAX_IDLE_TIME", &cfg.ConnMaxIdleTime)
+	envDuration("CONNECT_FOUR_DB_CONNECT_TIMEOUT", &cfg.ConnectTimeout)
+	return cfg
+}
+
+// connect returns the pool of database connections, which is opened (and pinged) by the first repository that
+// needs it. It stops the api when the database can't be reached within the connect timeout.
+func connect() *sql.DB {
+	pool.once.Do(func() {
+		cfg := PoolConfigFromEnv()
+		log.Infoln("Connecting to DB...")
+		secret := readSecret("MARIADB_PASSWORD_FILE")
+		user := os.Getenv("MARIADB_USER")
+		schema := os.Getenv("MARIADB_DATABASE")
+		address := os.Getenv("MARIADB_ADDRESS")
+		datasource := user + ":" + secret + "@" + address + "/" + schema + "?parseTime=true"
+		db, err := sql.Open("mysql", datasource)
+		if err != nil {
+			log.Fatalf("Error connecting to the database: %v\n", err)
+		}
+		db.SetMaxOpenConns(cfg.MaxOpenConns)
+		db.SetMaxIdleConns(cfg.MaxIdleConns)
+		db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+		db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+		if err = pingWithBackoff(db.PingContext, cfg.ConnectTimeout); err != nil {
+			log.Fatalf("Error connecting to the database: %v\n", err)
+		}
+		log.Infof("Connected (at most %d connections).", cfg.MaxOpenConns)
+		pool.db = db
+	})
+	return pool.db
+}
+
+// Ping checks that the database can be reached.
+func Ping(ctx context.Context) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+	return connect().PingContext(ctx)
+}
+
+// Close closes the connections in the pool, when it was opened. The repositories can't be used afterwards.
+func Close() error {
+	if pool.db == nil {
+		return nil
+	}
+	return pool.db.Close()
+}
+
+// PoolStats returns the numbers of connections in the pool.
+func PoolStats() sql.DBStats {
+	return connect().Stats()
+}
+
+// pingWithBackoff pings until it succeeds or the timeout has passed, waiting longer after every failure.
+func pingWithBackoff(ping func(ctx context.Context) error, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	backoff := initialBackoff
+	for attempt := 1; ; attempt++ {
+		err := ping(ctx)
+		if err == nil {
+			return nil
+		}
+		log.Warnf("The database can't be reached yet (attempt %d): %v", attempt, err)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return fmt.Errorf("the database couldn't be reached within %s: %w", timeout, err)
+		}
+		backoff = min(2*backoff, maxBackoff)
+	}
+}
+
+func envInt(name string, value *int) {
+	if s, ok := os.LookupEnv(name); ok {
+		if i, err := strconv.Atoi(s); err == nil && i >= 0 {
+			*value = i
+		} else {
+			log.Warnf("Ignoring %s=%s, it must be a number of at least 0", name, s)
+		}
+	}
+}
+
+func envDuration(name string, value *time.Duration) {
+	if s, ok := os.LookupEnv(name); ok {
+		if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+			*value = d
+		} else {
+			log.Warnf("Ignoring %s=%s, it must be a duration like 30s or 5m", name, s)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func fastBackoff(t *testing.T) {
+	previousInitial, previousMax := initialBackoff, maxBackoff
+	initialBackoff, maxBackoff = time.Millisecond, 4*time.Millisecond
+	t.Cleanup(func() { initialBackoff, maxBackoff = previousInitial, previousMax })
+}
+
+func TestPingWithBackoff_Retries(t *testing.T) {
+	// Arrange
+	fastBackoff(t)
+	attempts := 0
+	ping := func(context.Context) error {
+		attempts++
+		if attempts < 4 {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+
+	// Act
+	err := pingWithBackoff(ping, time.Second)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 4, attempts)
+}
+
+func TestPingWithBackoff_GivesUp(t *testing.T) {
+	// Arrange
+	fastBackoff(t)
+	refused := errors.New("connection refused")
+	ping := func(context.Context) error { return refused }
+
+	// Act
+	err := pingWithBackoff(ping, 20*time.Millisecond)
+
+	// Assert
+	assert.ErrorIs(t, err, refused)
+}
+
+func TestPoolConfigFromEnv(t *testing.T) {
+	// Arrange
+	t.Setenv("CONNECT_FOUR_DB_MAX_OPEN_CONNS", "50")
+	t.Setenv("CONNECT_FOUR_DB_CONN_MAX_LIFETIME", "1h")
+	t.Setenv("CONNECT_FOUR_DB_MAX_IDLE_CONNS", "lots")
+
+	// Act
+	cfg := PoolConfigFromEnv()
+
+	// Assert
+	assert.Equal(t, 50, cfg.MaxOpenConns)
+	assert.Equal(t, time.Hour, cfg.ConnMaxLifetime)
+	assert.Equal(t, DefaultPoolConfig.MaxIdleConns, cfg.MaxIdleConns, "Expected an invalid value to be ignored")
+	assert.Equal(t, DefaultPoolConfig.ConnMaxIdleTime, cfg.ConnMaxIdleTime)
+}
@@ -2,15 +2,74 @@ package db
 
 import (
 	"connectfour/internal/model"
+	"context"
+	"time"
 )
 
 type UserRepository interface {
-	Create(u model.User) (model.User, error)
-	FindByEmail(email string) (model.User, error)
+	Create(ctx context.Context, u model.User) (model.User, error)
+	FindByEmail(ctx context.Context, email string) (model.User, error)
+	FindByName(ctx context.Context, name string) (model.User, error)
+	FindByIdentity(ctx context.Context, issuer string, subject string) (model.User, error)
+	AddIdentity(ctx context.Context, userId int64, issuer string, subject string) error
+	Update(ctx context.Context, u model.User) error
+	SetVerified(ctx context.Context, userId int64) error
+	SetRole(ctx context.Context, userId int64, role model.Role) error
+	SetBanned(ctx context.Context, userId int64, banned bool) error
+	SetBot(ctx context.Context, userId int64, bot bool) error
+	RevokeTokens(ctx context.Context, userId int64) error
+	Search(ctx context.Context, query string, limit int) ([]model.User, error)
+	Stats(ctx context.Context) (model.UserStats, error)
+	Delete(ctx context.Context, userId int64) error
+	CreateResetToken(ctx context.Context, userId int64, tokenHash string, expiresAt time.Time) error
+	ConsumeResetToken(ctx context.Context, tokenHash string) (model.User, error)
+	AddFriend(ctx context.Context, userId int64, friendId int64) error
+	AcceptFriend(ctx context.Context, userId int64, friendId int64) error
+	RemoveFriend(ctx context.Context, userId int64, friendId int64) error
+	ListFriends(ctx context.Context, userId int64) ([]model.Friend, error)
 }
 
 type GameRepository interface {
-	Save(game model.Game) bool
-	Fetch(key string) (model.Game, error)
-	List(userId int64, status string) ([]model.Game, error)
+	Save(ctx context.Context, game model.Game) bool
+	Fetch(ctx context.Context, key string) (model.Game, error)
+	List(ctx context.Context, f model.GameFilter) ([]model.Game, error)
+	ListInvitations(ctx context.Context, inviteeId int64) ([]model.Game, error)
+	CountByStatus(ctx context.Context) (map[model.GameStatus]int, error)
+}
+
+type TournamentRepository interface {
+	Create(ctx context.Context, t model.Tournament) (model.Tournament, error)
+	Update(ctx context.Context, t model.Tournament) error
+	Fetch(ctx context.Context, id int64) (model.Tournament, error)
+	FetchByGameKey(ctx context.Context, key string) (model.Tournament, error)
+	List(ctx context.Context) ([]model.Tournament, error)
+	AddPlayer(ctx context.Context, tournamentId int64, player model.User) error
+	SavePairing(ctx context.Context, tournamentId int64, p model.Pairing) (model.Pairing, error)
+}
+
+// AuditRepository stores the audit log, which is append-only.
+type AuditRepository interface {
+	Append(ctx context.Context, e model.AuditEntry) error
+	List(ctx context.Context, f model.AuditFilter) ([]model.AuditEntry, error)
+}
+
+// NotificationRepository stores the inbox of notifications of every user, and the channels they want to be notified on.
+type NotificationRepository interface {
+	Add(ctx context.Context, n model.Notification) (model.Notification, error)
+	List(ctx context.Context, userId int64, unreadOnly bool, limit int) ([]model.Notification, error)
+	MarkRead(ctx context.Context, userId int64, id int64, at time.Time) error
+	Preferences(ctx context.Context, userId int64) (model.NotificationPreferences, error)
+	SavePreferences(ctx context.Context, userId int64, p model.NotificationPreferences) error
+}
+
+// WebhookRepository stores the webhooks that game events are posted to, and the log of their deliveries.
+type WebhookRepository interface {
+	Create(ctx context.Context, w model.Webhook) (model.Webhook, error)
+	Delete(ctx context.Context, ownerId int64, id int64) (bool, error)
+	ListByOwner(ctx context.Context, ownerId int64) ([]model.Webhook, error)
+	ListForPlayers(ctx context.Context, playerIds ...int64) ([]model.Webhook, error)
+	AddDelivery(ctx context.Context, d model.WebhookDelivery) (model.WebhookDelivery, error)
+	UpdateDelivery(ctx context.Context, d model.WebhookDelivery) error
+	ListDeliveries(ctx context.Context, webhookId int64, limit int) ([]model.WebhookDelivery, error)
+	ListPending(ctx context.Context) ([]model.PendingDelivery, error)
 }
@@ -0,0 +1,279 @@
+package db
+
+import (
+	"connectfour/internal/model"
+	"context"
+	"database/sql"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+type MariaDbTournamentRepository struct {
+	db *sql.DB
+}
+
+var _ TournamentRepository = MariaDbTournamentRepository{}
+
+func NewMariaDbTournamentRepository() *MariaDbTournamentRepository {
+	return &MariaDbTournamentRepository{
+		db: connect(),
+	}
+}
+
+const tournamentQuery = `SELECT
+    t.id,
+    t.name,
+    t.format,
+    t.status,
+    u.id as organizer_id,
+    u.email as organizer_email,
+    u.name as organizer_name,
+    t.rounds,
+    t.current_round,
+    t.created_at,
+    t.started_at,
+    t.finished_at
+	FROM tournament t
+	JOIN user u ON u.id = t.organizer_id`
+
+func (r MariaDbTournamentRepository) Create(ctx context.Context, t model.Tournament) (model.Tournament, error) {
+	ctx, done := startQuery(ctx, "tournament", "Create")
+	defer done()
+
+	result, err := r.db.ExecContext(ctx,
+		`INSERT INTO tournament (name, format, status, organizer_id, rounds, current_round, created_at)
+			   VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		t.Name, t.Format, t.Status, t.Organizer.Id, t.Rounds, t.CurrentRound, t.CreatedAt)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error inserting tournament into the database: %v\n", err)
+		return model.Tournament{}, err
+	}
+	t.Id, err = result.LastInsertId()
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error getting last insert ID: %v\n", err)
+		return model.Tournament{}, err
+	}
+	return t, nil
+}
+
+func (r MariaDbTournamentRepository) Update(ctx context.Context, t model.Tournament) error {
+	ctx, done := startQuery(ctx, "tournament", "Update")
+	defer done()
+
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE tournament SET status = ?, rounds = ?, current_round = ?, started_at = ?, finished_at = ? WHERE id = ?`,
+		t.Status, t.Rounds, t.CurrentRound, nullTime(t.StartedAt), nullTime(t.FinishedAt), t.Id)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error updating tournament %d: %v\n", t.Id, err)
+	}
+	return err
+}
+
+func (r MariaDbTournamentRepository) Fetch(ctx context.Context, id int64) (model.Tournament, error) {
+	ctx, done := startQuery(ctx, "tournament", "Fetch")
+	defer done()
+
+	t, err := scanTournament(r.db.QueryRowContext(ctx, tournamentQuery+" WHERE t.id = ?", id))
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error scanning the tournament row: %v\n", err)
+		return model.Tournament{}, err
+	}
+
+	if t.Players, err = r.players(ctx, id); err != nil {
+		return model.Tournament{}, err
+	}
+	if t.Pairings, err = r.pairings(ctx, id); err != nil {
+		return model.Tournament{}, err
+	}
+	return t, nil
+}
+
+func (r MariaDbTournamentRepository) FetchByGameKey(ctx context.Context, key string) (model.Tournament, error) {
+	ctx, done := startQuery(ctx, "tournament", "FetchByGameKey")
+	defer done()
+
+	var id int64
+	err := r.db.QueryRowContext(ctx, "SELECT tournament_id FROM tournament_pairing WHERE game_key = ?", key).Scan(&id)
+	if err != nil {
+		return model.Tournament{}, err
+	}
+	return r.Fetch(ctx, id)
+}
+
+func (r MariaDbTournamentRepository) List(ctx context.Context) ([]model.Tournament, error) {
+	ctx, done := startQuery(ctx, "tournament", "List")
+	defer done()
+
+	rows, err := r.db.QueryContext(ctx, tournamentQuery+" ORDER BY t.created_at DESC")
+	if err == nil {
+		err = rows.Err()
+	}
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error getting the tournaments from the database: %v\n", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	output := make([]model.Tournament, 0)
+	for rows.Next() {
+		t, err := scanTournament(rows)
+		if err != nil {
+			log.WithContext(ctx).Errorf("Error scanning the tournament row: %v\n", err)
+			return nil, err
+		}
+		output = append(output, t)
+	}
+
+	for i := range output {
+		if output[i].Players, err = r.players(ctx, output[i].Id); err != nil {
+			return nil, err
+		}
+	}
+	return output, nil
+}
+
+func (r MariaDbTournamentRepository) AddPlayer(ctx context.Context, tournamentId int64, player model.User) error {
+	ctx, done := startQuery(ctx, "tournament", "AddPlayer")
+	defer done()
+
+	_, err := r.db.ExecContext(ctx,
+		"INSERT IGNORE INTO tournament_player (tournament_id, user_id, registered_at) VALUES (?, ?, ?)",
+		tournamentId, player.Id, time.Now())
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error registering user %d for tournament %d: %v\n", player.Id, tournamentId, err)
+	}
+	return err
+}
+
+func (r MariaDbTournamentRepository) SavePairing(ctx context.Context, tournamentId int64, p model.Pairing) (model.Pairing, error) {
+	ctx, done := startQuery(ctx, "tournament", "SavePairing")
+	defer done()
+
+	var player2Id sql.NullInt64
+	if !p.Player2.Empty() {
+		player2Id = sql.NullInt64{Int64: p.Player2.Id, Valid: true}
+	}
+	var gameKey sql.NullString
+	if p.GameKey != "" {
+		gameKey = sql.NullString{String: p.GameKey, Valid: true}
+	}
+
+	if p.Id > 0 {
+		_, err := r.db.ExecContext(ctx,
+			"UPDATE tournament_pairing SET game_key = ?, result = ? WHERE id = ?",
+			gameKey, p.Result, p.Id)
+		if err != nil {
+			log.WithContext(ctx).Errorf("Error updating pairing %d: %v\n", p.Id, err)
+		}
+		return p, err
+	}
+
+	result, err := r.db.ExecContext(ctx,
+		`INSERT INTO tournament_pairing (tournament_id, round, game_key, player1_id, player2_id, result)
+			   VALUES (?, ?, ?, ?, ?, ?)`,
+		tournamentId, p.Round, gameKey, p.Player1.Id, player2Id, p.Result)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error inserting pairing for tournament %d: %v\n", tournamentId, err)
+		return model.Pairing{}, err
+	}
+	p.Id, err = result.LastInsertId()
+	return p, err
+}
+
+func (r MariaDbTournamentRepository) players(ctx context.Context, tournamentId int64) ([]model.User, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT u.id, u.email, u.name
+	FROM tournament_player tp
+	JOIN user u ON u.id = tp.user_id
+	WHERE tp.tournament_id = ?
+	ORDER BY tp.registered_at, u.id`, tournamentId)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error getting the players of tournament %d: %v\n", tournamentId, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	output := make([]model.User, 0)
+	for rows.Next() {
+		var u model.User
+		if err = rows.Scan(&u.Id, &u.Email, &u.Name); err != nil {
+			log.WithContext(ctx).Errorf("Error scanning the player row: %v\n", err)
+			return nil, err
+		}
+		output = append(output, u)
+	}
+	return output, rows.Err()
+}
+
+func (r MariaDbTournamentRepository) pairings(ctx context.Context, tournamentId int64) ([]model.Pairing, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT
+    p.id,
+    p.round,
+    ifnull(p.game_key, ''),
+    u1.id,
+    u1.email,
+    u1.name,
+    ifnull(u2.id, 0),
+    ifnull(u2.email, ''),
+    ifnull(u2.name, ''),
+    p.result
+	FROM tournament_pairing p
+	JOIN user u1 ON u1.id = p.player1_id
+	LEFT JOIN user u2 ON u2.id = p.player2_id
+	WHERE p.tournament_id = ?
+	ORDER BY p.round, p.id`, tournamentId)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error getting the pairings of tournament %d: %v\n", tournamentId, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	output := make([]model.Pairing, 0)
+	for rows.Next() {
+		var p model.Pairing
+		err = rows.Scan(
+			&p.Id,
+			&p.Round,
+			&p.GameKey,
+			&p.Player1.Id,
+			&p.Player1.Email,
+			&p.Player1.Name,
+			&p.Player2.Id,
+			&p.Player2.Email,
+			&p.Player2.Name,
+			&p.Result,
+		)
+		if err != nil {
+			log.WithContext(ctx).Errorf("Error scanning the pairing row: %v\n", err)
+			return nil, err
+		}
+		output = append(output, p)
+	}
+	return output, rows.Err()
+}
+
+// scanner is implemented by both sql.Row and sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTournament(row scanner) (model.Tournament, error) {
+	var t model.Tournament
+	var startedAt, finishedAt sql.NullTime
+	err := row.Scan(
+		&t.Id,
+		&t.Name,
+		&t.Format,
+		&t.Status,
+		&t.Organizer.Id,
+		&t.Organizer.Email,
+		&t.Organizer.Name,
+		&t.Rounds,
+		&t.CurrentRound,
+		&t.CreatedAt,
+		&startedAt,
+		&finishedAt,
+	)
+	t.StartedAt = startedAt.Time
+	t.FinishedAt = finishedAt.Time
+	return t, err
+}
@@ -2,12 +2,22 @@ package db
 
 import (
 	"connectfour/internal/model"
+	"context"
 	"database/sql"
 	"errors"
-	_ "github.com/go-sql-driver/mysql"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
 	log "github.com/sirupsen/logrus"
+	"strings"
+	"time"
 )
 
+// ErrNameTaken is returned by Create and Update when another user already has the name, ignoring the case.
+var ErrNameTaken = errors.New("the name is already taken")
+
+// duplicateEntry is the error number of MariaDB for a value that violates a unique index.
+const duplicateEntry = 1062
+
 type MariaDbUserRepository struct {
 	db *sql.DB
 }
@@ -18,32 +28,384 @@ func NewMariaDbUserRepository() *MariaDbUserRepository {
 	}
 }
 
-func (r MariaDbUserRepository) Create(u model.User) (model.User, error) {
-	result, err := r.db.Exec("INSERT INTO user (email, name, token) VALUES (?, ?, ?)", u.Email, u.Name, u.Token)
+func (r MariaDbUserRepository) Create(ctx context.Context, u model.User) (model.User, error) {
+	ctx, done := startQuery(ctx, "user", "Create")
+	defer done()
+
+	if u.Role == "" {
+		u.Role = model.RolePlayer
+	}
+	result, err := r.db.ExecContext(ctx, "INSERT INTO user (email, name, token, bot, verified, role) VALUES (?, ?, ?, ?, ?, ?)",
+		u.Email, u.Name, u.Token, u.Bot, u.Verified, u.Role)
+	if isNameTaken(err) {
+		return model.User{}, ErrNameTaken
+	}
 	if err != nil {
-		log.Errorf("Error inserting user into the database: %v\n", err)
+		log.WithContext(ctx).Errorf("Error inserting user into the database: %v\n", err)
 		return model.User{}, err
 	}
 	id, err := result.LastInsertId()
 	if err != nil {
-		log.Errorf("Error getting last insert ID: %v\n", err)
+		log.WithContext(ctx).Errorf("Error getting last insert ID: %v\n", err)
 		return model.User{}, err
 	}
 	u.Id = id
 	return u, nil
 }
 
-func (r MariaDbUserRepository) FindByEmail(email string) (model.User, error) {
-	row := r.db.QueryRow("SELECT id, email, name, token FROM user WHERE email = ?", email)
+func (r MariaDbUserRepository) FindByEmail(ctx context.Context, email string) (model.User, error) {
+	ctx, done := startQuery(ctx, "user", "FindByEmail")
+	defer done()
+
+	row := r.db.QueryRowContext(ctx, "SELECT id, email, name, token, bot, verified, role, banned, token_version FROM user WHERE email = ?", email)
 	u := model.User{}
-	if err := row.Scan(&u.Id, &u.Email, &u.Name, &u.Token); err != nil {
+	if err := row.Scan(&u.Id, &u.Email, &u.Name, &u.Token, &u.Bot, &u.Verified, &u.Role, &u.Banned, &u.TokenVersion); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			log.Debugf("Requested user '%s' not found (%v)\n", email, err)
+			log.WithContext(ctx).Debugf("Requested user '%s' not found (%v)\n", email, err)
 			return model.User{}, nil
 		} else {
-			log.Errorf("Error getting user values from the database result: %v\n", err)
+			log.WithContext(ctx).Errorf("Error getting user values from the database result: %v\n", err)
 			return model.User{}, err
 		}
 	}
 	return u, nil
 }
+
+// FindByName returns the user with the display name, ignoring the case. An empty user is returned when there's none.
+func (r MariaDbUserRepository) FindByName(ctx context.Context, name string) (model.User, error) {
+	ctx, done := startQuery(ctx, "user", "FindByName")
+	defer done()
+
+	row := r.db.QueryRowContext(ctx, "SELECT id, email, name, token, bot, verified, role, banned, token_version FROM user WHERE LOWER(name) = LOWER(?) LIMIT 1", name)
+	u := model.User{}
+	if err := row.Scan(&u.Id, &u.Email, &u.Name, &u.Token, &u.Bot, &u.Verified, &u.Role, &u.Banned, &u.TokenVersion); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.User{}, nil
+		}
+		log.WithContext(ctx).Errorf("Error getting user values from the database result: %v\n", err)
+		return model.User{}, err
+	}
+	return u, nil
+}
+
+// FindByIdentity returns the user that the identity of the external identity provider is linked to. An empty user
+// is returned when it isn't linked yet.
+func (r MariaDbUserRepository) FindByIdentity(ctx context.Context, issuer string, subject string) (model.User, error) {
+	ctx, done := startQuery(ctx, "user", "FindByIdentity")
+	defer done()
+
+	row := r.db.QueryRowContext(ctx, `SELECT u.id, u.email, u.name, u.token, u.bot, u.verified, u.role, u.banned, u.token_version
+		FROM user_identity i
+		JOIN user u ON u.id = i.user_id
+		WHERE i.issuer = ? AND i.subject = ?`, issuer, subject)
+	u := model.User{}
+	if err := row.Scan(&u.Id, &u.Email, &u.Name, &u.Token, &u.Bot, &u.Verified, &u.Role, &u.Banned, &u.TokenVersion); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.User{}, nil
+		}
+		log.WithContext(ctx).Errorf("Error getting user values from the database result: %v\n", err)
+		return model.User{}, err
+	}
+	return u, nil
+}
+
+// AddIdentity links the identity of the external identity provider to the user.
+func (r MariaDbUserRepository) AddIdentity(ctx context.Context, userId int64, issuer string, subject string) error {
+	ctx, done := startQuery(ctx, "user", "AddIdentity")
+	defer done()
+
+	_, err := r.db.ExecContext(ctx, "INSERT INTO user_identity (issuer, subject, user_id) VALUES (?, ?, ?)", issuer, subject, userId)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error linking identity %s of %s to user %d: %v\n", subject, issuer, userId, err)
+	}
+	return err
+}
+
+// Update stores the display name and password hash of the user.
+func (r MariaDbUserRepository) Update(ctx context.Context, u model.User) error {
+	ctx, done := startQuery(ctx, "user", "Update")
+	defer done()
+
+	_, err := r.db.ExecContext(ctx, "UPDATE user SET name = ?, token = ? WHERE id = ?", u.Name, u.Token, u.Id)
+	if isNameTaken(err) {
+		return ErrNameTaken
+	}
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error updating user %d: %v\n", u.Id, err)
+	}
+	return err
+}
+
+// SetVerified marks the e-mail address of the user as verified.
+func (r MariaDbUserRepository) SetVerified(ctx context.Context, userId int64) error {
+	ctx, done := startQuery(ctx, "user", "SetVerified")
+	defer done()
+
+	_, err := r.db.ExecContext(ctx, "UPDATE user SET verified = TRUE WHERE id = ?", userId)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error verifying user %d: %v\n", userId, err)
+	}
+	return err
+}
+
+// SetRole changes what the user is allowed to do.
+func (r MariaDbUserRepository) SetRole(ctx context.Context, userId int64, role model.Role) error {
+	ctx, done := startQuery(ctx, "user", "SetRole")
+	defer done()
+
+	_, err := r.db.ExecContext(ctx, "UPDATE user SET role = ? WHERE id = ?", role, userId)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error changing the role of user %d: %v\n", userId, err)
+	}
+	return err
+}
+
+// SetBanned bans the user, or lifts the ban.
+func (r MariaDbUserRepository) SetBanned(ctx context.Context, userId int64, banned bool) error {
+	ctx, done := startQuery(ctx, "user", "SetBanned")
+	defer done()
+
+	_, err := r.db.ExecContext(ctx, "UPDATE user SET banned = ? WHERE id = ?", banned, userId)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error banning user %d: %v\n", userId, err)
+	}
+	return err
+}
+
+// SetBot makes the user a bot account, which gets long-lived tokens, or a player again.
+func (r MariaDbUserRepository) SetBot(ctx context.Context, userId int64, bot bool) error {
+	ctx, done := startQuery(ctx, "user", "SetBot")
+	defer done()
+
+	_, err := r.db.ExecContext(ctx, "UPDATE user SET bot = ? WHERE id = ?", bot, userId)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error setting bot to %t for user %d: %v\n", bot, userId, err)
+	}
+	return err
+}
+
+// RevokeTokens raises the token version of the user, so the tokens that were issued before are refused.
+func (r MariaDbUserRepository) RevokeTokens(ctx context.Context, userId int64) error {
+	ctx, done := startQuery(ctx, "user", "RevokeTokens")
+	defer done()
+
+	_, err := r.db.ExecContext(ctx, "UPDATE user SET token_version = token_version + 1 WHERE id = ?", userId)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error revoking the tokens of user %d: %v\n", userId, err)
+	}
+	return err
+}
+
+// Search returns the users whose e-mail address or name contains the query, ordered by id.
+func (r MariaDbUserRepository) Search(ctx context.Context, query string, limit int) ([]model.User, error) {
+	ctx, done := startQuery(ctx, "user", "Search")
+	defer done()
+
+	like := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(query) + "%"
+	rows, err := r.db.QueryContext(ctx, `SELECT id, email, name, token, bot, verified, role, banned, token_version FROM user
+		WHERE email LIKE ? OR name LIKE ?
+		ORDER BY id
+		LIMIT ?`, like, like, limit)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error searching users: %v\n", err)
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	users := make([]model.User, 0)
+	for rows.Next() {
+		u := model.User{}
+		if err = rows.Scan(&u.Id, &u.Email, &u.Name, &u.Token, &u.Bot, &u.Verified, &u.Role, &u.Banned, &u.TokenVersion); err != nil {
+			log.WithContext(ctx).Errorf("Error scanning the user row: %v\n", err)
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// Stats counts the users.
+func (r MariaDbUserRepository) Stats(ctx context.Context) (model.UserStats, error) {
+	ctx, done := startQuery(ctx, "user", "Stats")
+	defer done()
+
+	stats := model.UserStats{}
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*), IFNULL(SUM(bot), 0), IFNULL(SUM(verified), 0), IFNULL(SUM(banned), 0)
+		FROM user`).Scan(&stats.Total, &stats.Bots, &stats.Verified, &stats.Banned)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error counting users: %v\n", err)
+	}
+	return stats, err
+}
+
+// Delete removes the personal data of the user. The user row itself is kept, but anonymized, so the games they
+// played still have both players. The friendships are removed; the games that haven't finished yet must be aborted
+// before, with GamesService.AbortGamesOf.
+func (r MariaDbUserRepository) Delete(ctx context.Context, userId int64) error {
+	ctx, done := startQuery(ctx, "user", "Delete")
+	defer done()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error starting the transaction to delete user %d: %v\n", userId, err)
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	statements := []struct {
+		query string
+		args  []any
+	}{
+		{"DELETE FROM friend WHERE user_id = ? OR friend_id = ?", []any{userId, userId}},
+		{"DELETE FROM user_identity WHERE user_id = ?", []any{userId}},
+		{"DELETE FROM password_reset WHERE user_id = ?", []any{userId}},
+		{"DELETE FROM notification WHERE user_id = ?", []any{userId}},
+		{"DELETE FROM notification_preferences WHERE user_id = ?", []any{userId}},
+		{"DELETE FROM webhook WHERE owner_id = ?", []any{userId}},
+		{"UPDATE user SET email = ?, name = ?, token = '', bot = FALSE, verified = FALSE, role = 'player' WHERE id = ?",
+			[]any{fmt.Sprintf("deleted-%d@connectfour.invalid", userId), model.DeletedUserName, userId}},
+	}
+	for _, st := range statements {
+		if _, err = tx.ExecContext(ctx, st.query, st.args...); err != nil {
+			log.WithContext(ctx).Errorf("Error deleting user %d: %v\n", userId, err)
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// CreateResetToken stores the hash of a password reset token. Earlier tokens of the user can no longer be used.
+func (r MariaDbUserRepository) CreateResetToken(ctx context.Context, userId int64, tokenHash string, expiresAt time.Time) error {
+	ctx, done := startQuery(ctx, "user", "CreateResetToken")
+	defer done()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error starting the transaction to store the reset token of user %d: %v\n", userId, err)
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM password_reset WHERE user_id = ?", userId); err != nil {
+		log.WithContext(ctx).Errorf("Error removing the old reset tokens of user %d: %v\n", userId, err)
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO password_reset (token_hash, user_id, expires_at) VALUES (?, ?, ?)",
+		tokenHash, userId, expiresAt)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error inserting the reset token of user %d: %v\n", userId, err)
+		return err
+	}
+	return tx.Commit()
+}
+
+// ConsumeResetToken marks the reset token as used and returns its user. It returns sql.ErrNoRows when the token
+// doesn't exist, was used before or has expired.
+func (r MariaDbUserRepository) ConsumeResetToken(ctx context.Context, tokenHash string) (model.User, error) {
+	ctx, done := startQuery(ctx, "user", "ConsumeResetToken")
+	defer done()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error starting the transaction to consume a reset token: %v\n", err)
+		return model.User{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	u := model.User{}
+	err = tx.QueryRowContext(ctx, `SELECT u.id, u.email, u.name, u.token, u.bot, u.verified, u.role, u.banned, u.token_version
+		FROM password_reset p
+		JOIN user u ON u.id = p.user_id
+		WHERE p.token_hash = ? AND p.used_at IS NULL AND p.expires_at > ?
+		FOR UPDATE`,
+		tokenHash, time.Now()).Scan(&u.Id, &u.Email, &u.Name, &u.Token, &u.Bot, &u.Verified, &u.Role, &u.Banned, &u.TokenVersion)
+	if err != nil {
+		return model.User{}, err
+	}
+	if _, err = tx.ExecContext(ctx, "UPDATE password_reset SET used_at = ? WHERE token_hash = ?", time.Now(), tokenHash); err != nil {
+		log.WithContext(ctx).Errorf("Error marking the reset token of user %d as used: %v\n", u.Id, err)
+		return model.User{}, err
+	}
+	return u, tx.Commit()
+}
+
+// AddFriend stores a friend request from the user to the friend.
+func (r MariaDbUserRepository) AddFriend(ctx context.Context, userId int64, friendId int64) error {
+	ctx, done := startQuery(ctx, "user", "AddFriend")
+	defer done()
+
+	_, err := r.db.ExecContext(ctx,
+		"INSERT IGNORE INTO friend (user_id, friend_id, status, created_at) VALUES (?, ?, ?, ?)",
+		userId, friendId, model.FriendRequested, time.Now())
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error inserting friend request into the database: %v\n", err)
+	}
+	return err
+}
+
+// AcceptFriend accepts the friend request that the friend sent to the user.
+func (r MariaDbUserRepository) AcceptFriend(ctx context.Context, userId int64, friendId int64) error {
+	ctx, done := startQuery(ctx, "user", "AcceptFriend")
+	defer done()
+
+	result, err := r.db.ExecContext(ctx,
+		"UPDATE friend SET status = ? WHERE user_id = ? AND friend_id = ?",
+		model.FriendAccepted, friendId, userId)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error accepting friend request: %v\n", err)
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return errors.New("there is no friend request to accept")
+	}
+	return nil
+}
+
+// RemoveFriend removes the friendship (or request) between the two users, whoever sent the request.
+func (r MariaDbUserRepository) RemoveFriend(ctx context.Context, userId int64, friendId int64) error {
+	ctx, done := startQuery(ctx, "user", "RemoveFriend")
+	defer done()
+
+	_, err := r.db.ExecContext(ctx,
+		"DELETE FROM friend WHERE (user_id = ? AND friend_id = ?) OR (user_id = ? AND friend_id = ?)",
+		userId, friendId, friendId, userId)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error removing friend: %v\n", err)
+	}
+	return err
+}
+
+// ListFriends returns the friends of the user, including the requests that are still open in either direction.
+func (r MariaDbUserRepository) ListFriends(ctx context.Context, userId int64) ([]model.Friend, error) {
+	ctx, done := startQuery(ctx, "user", "ListFriends")
+	defer done()
+
+	rows, err := r.db.QueryContext(ctx, `SELECT u.id, u.email, u.name, u.bot, f.status, f.friend_id = ? as incoming
+	FROM friend f
+	JOIN user u ON u.id = IF(f.user_id = ?, f.friend_id, f.user_id)
+	WHERE f.user_id = ? OR f.friend_id = ?
+	ORDER BY u.name`, userId, userId, userId, userId)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error getting friends from the database: %v\n", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	output := make([]model.Friend, 0)
+	for rows.Next() {
+		var f model.Friend
+		if err = rows.Scan(&f.User.Id, &f.User.Email, &f.User.Name, &f.User.Bot, &f.Status, &f.Incoming); err != nil {
+			log.WithContext(ctx).Errorf("Error scanning the friend row: %v\n", err)
+			return nil, err
+		}
+		output = append(output, f)
+	}
+	return output, rows.Err()
+}
+
+// isNameTaken returns true when the error is a violation of the unique index on the names of the users.
+func isNameTaken(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == duplicateEntry &&
+		strings.Contains(mysqlErr.Message, "idx_user_name_key")
+}
@@ -0,0 +1,205 @@
+package db
+
+import (
+	"connectfour/internal/model"
+	"context"
+	"database/sql"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"strings"
+)
+
+// MariaDbWebhookRepository stores the webhooks and their delivery log.
+type MariaDbWebhookRepository struct {
+	db *sql.DB
+}
+
+var _ WebhookRepository = MariaDbWebhookRepository{}
+
+func NewMariaDbWebhookRepository() *MariaDbWebhookRepository {
+	return &MariaDbWebhookRepository{
+		db: connect(),
+	}
+}
+
+func (r MariaDbWebhookRepository) Create(ctx context.Context, w model.Webhook) (model.Webhook, error) {
+	ctx, done := startQuery(ctx, "webhook", "Create")
+	defer done()
+
+	result, err := r.db.ExecContext(ctx,
+		"INSERT INTO webhook (owner_id, url, secret, events, all_games, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		w.OwnerId, w.Url, w.Secret, strings.Join(w.Events, ","), w.AllGames, w.CreatedAt)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error inserting the webhook of user %d: %v\n", w.OwnerId, err)
+		return model.Webhook{}, err
+	}
+	w.Id, err = result.LastInsertId()
+	return w, err
+}
+
+// Delete removes the webhook of the owner, with its delivery log. It returns false when the owner has no such webhook.
+func (r MariaDbWebhookRepository) Delete(ctx context.Context, ownerId int64, id int64) (bool, error) {
+	ctx, done := startQuery(ctx, "webhook", "Delete")
+	defer done()
+
+	result, err := r.db.ExecContext(ctx, "DELETE FROM webhook WHERE id = ? AND owner_id = ?", id, ownerId)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error deleting webhook %d: %v\n", id, err)
+		return false, err
+	}
+	deleted, err := result.RowsAffected()
+	return deleted > 0, err
+}
+
+// ListByOwner returns the webhooks that the user registered.
+func (r MariaDbWebhookRepository) ListByOwner(ctx context.Context, ownerId int64) ([]model.Webhook, error) {
+	ctx, done := startQuery(ctx, "webhook", "ListByOwner")
+	defer done()
+
+	return r.list(ctx, "WHERE owner_id = ?", ownerId)
+}
+
+// ListForPlayers returns the webhooks that get the events of a game of the players: the webhooks of the players and
+// the webhooks for all games.
+func (r MariaDbWebhookRepository) ListForPlayers(ctx context.Context, playerIds ...int64) ([]model.Webhook, error) {
+	ctx, done := startQuery(ctx, "webhook", "ListForPlayers")
+	defer done()
+
+	where := "WHERE all_games"
+	args := make([]any, 0, len(playerIds))
+	if len(playerIds) > 0 {
+		where += fmt.Sprintf(" OR owner_id IN (?%s)", strings.Repeat(", ?", len(playerIds)-1))
+		for _, id := range playerIds {
+			args = append(args, id)
+		}
+	}
+	return r.list(ctx, where, args...)
+}
+
+func (r MariaDbWebhookRepository) list(ctx context.Context, where string, args ...any) ([]model.Webhook, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT id, owner_id, url, secret, events, all_games, created_at FROM webhook "+where+" ORDER BY id", args...)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error listing the webhooks: %v\n", err)
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	output := make([]model.Webhook, 0)
+	for rows.Next() {
+		var w model.Webhook
+		var events string
+		if err = rows.Scan(&w.Id, &w.OwnerId, &w.Url, &w.Secret, &events, &w.AllGames, &w.CreatedAt); err != nil {
+			log.WithContext(ctx).Errorf("Error scanning the webhook row: %v\n", err)
+			return nil, err
+		}
+		w.Events = splitList[string](events)
+		output = append(output, w)
+	}
+	return output, rows.Err()
+}
+
+func (r MariaDbWebhookRepository) AddDelivery(ctx context.Context, d model.WebhookDelivery) (model.WebhookDelivery, error) {
+	ctx, done := startQuery(ctx, "webhook", "AddDelivery")
+	defer done()
+
+	result, err := r.db.ExecContext(ctx,
+		`INSERT INTO webhook_delivery (webhook_id, event, game_key, payload, status, created_at, next_attempt_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		d.WebhookId, d.Event, d.GameKey, d.Payload, d.Status, d.CreatedAt, nullTime(d.NextAttemptAt))
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error inserting the delivery for webhook %d: %v\n", d.WebhookId, err)
+		return model.WebhookDelivery{}, err
+	}
+	d.Id, err = result.LastInsertId()
+	return d, err
+}
+
+// UpdateDelivery records the outcome of an attempt to deliver.
+func (r MariaDbWebhookRepository) UpdateDelivery(ctx context.Context, d model.WebhookDelivery) error {
+	ctx, done := startQuery(ctx, "webhook", "UpdateDelivery")
+	defer done()
+
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE webhook_delivery 
+		SET status = ?, attempts = ?, response_status = ?, error = ?, last_attempt_at = ?, next_attempt_at = ?
+		WHERE id = ?`,
+		d.Status, d.Attempts, d.ResponseStatus, d.Error, nullTime(d.LastAttemptAt), nullTime(d.NextAttemptAt), d.Id)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error updating delivery %d: %v\n", d.Id, err)
+	}
+	return err
+}
+
+// ListDeliveries returns the newest deliveries of the webhook first, at most limit of them.
+func (r MariaDbWebhookRepository) ListDeliveries(ctx context.Context, webhookId int64, limit int) ([]model.WebhookDelivery, error) {
+	ctx, done := startQuery(ctx, "webhook", "ListDeliveries")
+	defer done()
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, webhook_id, event, game_key, payload, status, attempts, response_status, error, created_at, 
+       last_attempt_at, next_attempt_at
+		FROM webhook_delivery WHERE webhook_id = ? ORDER BY id DESC LIMIT ?`, webhookId, limit)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error listing the deliveries of webhook %d: %v\n", webhookId, err)
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	output := make([]model.WebhookDelivery, 0)
+	for rows.Next() {
+		var d model.WebhookDelivery
+		var lastAttemptAt, nextAttemptAt sql.NullTime
+		err = rows.Scan(&d.Id, &d.WebhookId, &d.Event, &d.GameKey, &d.Payload, &d.Status, &d.Attempts,
+			&d.ResponseStatus, &d.Error, &d.CreatedAt, &lastAttemptAt, &nextAttemptAt)
+		if err != nil {
+			log.WithContext(ctx).Errorf("Error scanning the delivery row: %v\n", err)
+			return nil, err
+		}
+		d.LastAttemptAt = lastAttemptAt.Time
+		d.NextAttemptAt = nextAttemptAt.Time
+		output = append(output, d)
+	}
+	return output, rows.Err()
+}
+
+// ListPending returns the deliveries that will be attempted (again), with their webhooks, the ones that are due
+// first.
+func (r MariaDbWebhookRepository) ListPending(ctx context.Context) ([]model.PendingDelivery, error) {
+	ctx, done := startQuery(ctx, "webhook", "ListPending")
+	defer done()
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT w.id, w.owner_id, w.url, w.secret, w.events, w.all_games, w.created_at,
+       d.id, d.webhook_id, d.event, d.game_key, d.payload, d.status, d.attempts, d.response_status, d.error,
+       d.created_at, d.last_attempt_at, d.next_attempt_at
+		FROM webhook_delivery d
+		JOIN webhook w ON w.id = d.webhook_id
+		WHERE d.status = ?
+		ORDER BY d.next_attempt_at, d.id`, model.DeliveryPending)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error listing the pending deliveries: %v\n", err)
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	output := make([]model.PendingDelivery, 0)
+	for rows.Next() {
+		var p model.PendingDelivery
+		var events string
+		var lastAttemptAt, nextAttemptAt sql.NullTime
+		w, d := &p.Webhook, &p.Delivery
+		err = rows.Scan(&w.Id, &w.OwnerId, &w.Url, &w.Secret, &events, &w.AllGames, &w.CreatedAt,
+			&d.Id, &d.WebhookId, &d.Event, &d.GameKey, &d.Payload, &d.Status, &d.Attempts, &d.ResponseStatus, &d.Error,
+			&d.CreatedAt, &lastAttemptAt, &nextAttemptAt)
+		if err != nil {
+			log.WithContext(ctx).Errorf("Error scanning the pending delivery row: %v\n", err)
+			return nil, err
+		}
+		w.Events = splitList[string](events)
+		d.LastAttemptAt = lastAttemptAt.Time
+		d.NextAttemptAt = nextAttemptAt.Time
+		output = append(output, p)
+	}
+	return output, rows.Err()
+}
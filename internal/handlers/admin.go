@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"connectfour/internal/model"
+	"connectfour/internal/service"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+)
+
+// AdminUsersHandler searches the users by e-mail address or name, with the `q` query parameter.
+func AdminUsersHandler(response http.ResponseWriter, request *http.Request) {
+	users, err := adminService.Users(request.Context(), request.URL.Query().Get("q"))
+	if handleError(err, response) {
+		marshal(users, response)
+	}
+}
+
+func AdminUserHandler(response http.ResponseWriter, request *http.Request) {
+	user, err := adminService.User(request.Context(), chi.URLParam(request, "email"))
+	if handleError(err, response) {
+		marshal(user, response)
+	}
+}
+
+func BanUserHandler(response http.ResponseWriter, request *http.Request) {
+	banUser(response, request, true)
+}
+
+func UnbanUserHandler(response http.ResponseWriter, request *http.Request) {
+	banUser(response, request, false)
+}
+
+func banUser(response http.ResponseWriter, request *http.Request, banned bool) {
+	user, err := adminService.Ban(request.Context(), emailFromContext(request), chi.URLParam(request, "email"), banned)
+	if handleAdminError(err, response) {
+		action := model.AuditAdminBan
+		if !banned {
+			action = model.AuditAdminUnban
+		}
+		audit(request, action, "", user.Email, "")
+		marshal(user, response)
+	}
+}
+
+func SetRoleHandler(response http.ResponseWriter, request *http.Request) {
+	if req, ok := unmarshal[service.SetRoleRequest](response, request); ok {
+		user, err := adminService.SetRole(request.Context(), emailFromContext(request), chi.URLParam(request, "email"), req.Role)
+		if handleAdminError(err, response) {
+			audit(request, model.AuditAdminRole, "", user.Email, string(user.Role))
+			marshal(user, response)
+		}
+	}
+}
+
+// SetBotHandler makes the user a bot account, or a player again.
+func SetBotHandler(response http.ResponseWriter, request *http.Request) {
+	if req, ok := unmarshal[service.SetBotRequest](response, request); ok {
+		user, err := adminService.SetBot(request.Context(), emailFromContext(request), chi.URLParam(request, "email"), req.Bot)
+		if handleAdminError(err, response) {
+			audit(request, model.AuditAdminBot, "", user.Email, fmt.Sprintf("bot: %t", user.Bot))
+			marshal(user, response)
+		}
+	}
+}
+
+// RevokeTokensHandler revokes the tokens that were issued to the user so far.
+func RevokeTokensHandler(response http.ResponseWriter, request *http.Request) {
+	user, err := adminService.RevokeTokens(request.Context(), emailFromContext(request), chi.URLParam(request, "email"))
+	if handleAdminError(err, response) {
+		audit(request, model.AuditAdminRevoke, "", user.Email, "")
+		marshal(user, response)
+	}
+}
+
+// AdminGamesHandler lists a page of the games, filtered by the `player` (e-mail address) query parameter and the
+// parameters of the other game listings, like `status`, `cursor` and `limit`.
+func AdminGamesHandler(response http.ResponseWriter, request *http.Request) {
+	req, ok := gameListRequest(response, request)
+	if !ok {
+		return
+	}
+	page, err := adminService.Games(request.Context(), request.URL.Query().Get("player"), req)
+	if handleError(err, response) {
+		marshalPage(page, response, request)
+	}
+}
+
+func AbortGameHandler(response http.ResponseWriter, request *http.Request) {
+	if key, ok := parseAndCheck(response, request); ok {
+		game, err := adminService.AbortGame(request.Context(), emailFromContext(request), key)
+		if handleError(err, response) {
+			audit(request, model.AuditAdminAbort, "", key, "")
+			marshal(game, response)
+		}
+	}
+}
+
+func StatsHandler(response http.ResponseWriter, request *http.Request) {
+	stats, err := adminService.Stats(request.Context())
+	if handleError(err, response) {
+		marshal(stats, response)
+	}
+}
+
+// handleAdminError returns 403 when the role of the user doesn't allow what they tried, like handleError does for
+// the other errors.
+func handleAdminError(err error, response http.ResponseWriter) bool {
+	if errors.As(err, &service.NotAllowedError{}) {
+		errorResponse(response, err.Error(), http.StatusForbidden)
+		return false
+	}
+	return handleError(err, response)
+}
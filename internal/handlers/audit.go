@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"connectfour/internal/model"
+	"context"
+	"github.com/go-chi/chi/v5/middleware"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// audit records the action in the audit log, with the ip and request id of the request. When the actor is empty,
+// it's the authenticated user. The entry is stored also when the client has gone away.
+func audit(r *http.Request, action model.AuditAction, actor string, subject string, details string) {
+	if actor == "" {
+		actor = emailFromContext(r)
+	}
+	auditService.Record(context.WithoutCancel(r.Context()), model.AuditEntry{
+		Action:    action,
+		Actor:     actor,
+		Ip:        clientIp(r),
+		RequestId: middleware.GetReqID(r.Context()),
+		Subject:   subject,
+		Details:   details,
+	})
+}
+
+// clientIp returns the ip address of the client, without the port.
+func clientIp(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+// AuditHandler returns the audit log, filtered by the `actor`, `action`, `subject`, `since` (RFC 3339) and `limit`
+// query parameters.
+func AuditHandler(response http.ResponseWriter, request *http.Request) {
+	q := request.URL.Query()
+	filter := model.AuditFilter{
+		Actor:   q.Get("actor"),
+		Action:  model.AuditAction(q.Get("action")),
+		Subject: q.Get("subject"),
+	}
+	if since := q.Get("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			errorResponse(response, "The since parameter must be a RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		filter.Since = t
+	}
+	if limit := q.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			errorResponse(response, "The limit parameter must be a positive number", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = n
+	}
+
+	entries, err := auditService.Entries(request.Context(), filter)
+	if handleError(err, response) {
+		marshal(entries, response)
+	}
+}
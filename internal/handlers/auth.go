@@ -1,26 +1,97 @@
 package handlers
 
 import (
+	"connectfour/internal/keyring"
+	"connectfour/internal/model"
 	"connectfour/internal/service"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"github.com/golang-jwt/jwt"
+	"github.com/golang-jwt/jwt/v5"
+	log "github.com/sirupsen/logrus"
 	"net/http"
+	"os"
 	"time"
 )
 
-var secretKey = []byte("connectfour is the ultimate game")
+// tokenClaims are the claims in the JWTs of the api.
+type tokenClaims struct {
+	Email   string     `json:"email"`
+	Name    string     `json:"name"`
+	Bot     bool       `json:"bot"`
+	Role    model.Role `json:"role"`
+	Version int        `json:"ver"` // the token version of the user; raising it revokes the token.
+	// AuthTime is when the user signed in, with a password or the identity provider. Tokens that are issued again,
+	// like after a rename, keep it.
+	AuthTime *jwt.NumericDate `json:"auth_time,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// newKeyRing loads the keys that sign the JWTs from the directory in CONNECT_FOUR_JWT_KEY_DIR. The key named by
+// CONNECT_FOUR_JWT_ACTIVE_KEY signs the new tokens, or the last key in alphabetical order when it's not set. The
+// keys before it retire when the tokens they signed have expired. Without a directory, a key is generated that only
+// lives as long as the process.
+func newKeyRing() *keyring.KeyRing {
+	dir, ok := os.LookupEnv("CONNECT_FOUR_JWT_KEY_DIR")
+	if ok {
+		ring, err := keyring.LoadKeyRing(dir, os.Getenv("CONNECT_FOUR_JWT_ACTIVE_KEY"), botTokenLifetime)
+		if err != nil {
+			log.Fatalf("Loading the JWT keys: %v\n", err)
+		}
+		return ring
+	}
+
+	log.Warnln("No CONNECT_FOUR_JWT_KEY_DIR set, signing JWTs with a generated key. Every token is invalid after a " +
+		"restart, and other instances refuse them. Don't run without a key directory in production.")
+	key, err := keyring.GenerateKey("generated-" + time.Now().UTC().Format("20060102150405"))
+	if err != nil {
+		log.Fatalf("Generating a JWT key: %v\n", err)
+	}
+	ring := keyring.NewKeyRing()
+	if err = ring.Add(key, true); err != nil {
+		log.Fatalf("Generating a JWT key: %v\n", err)
+	}
+	return ring
+}
+
+// verificationSecret returns the secret that signs the e-mail verification codes. It's read from the file in
+// CONNECT_FOUR_VERIFICATION_SECRET_FILE (a Docker secret), and generated when it's not set.
+func verificationSecret() []byte {
+	file, ok := os.LookupEnv("CONNECT_FOUR_VERIFICATION_SECRET_FILE")
+	if !ok {
+		log.Warnln("No CONNECT_FOUR_VERIFICATION_SECRET_FILE set, verification codes are invalid after a restart.")
+		secret := make([]byte, 32)
+		_, _ = rand.Read(secret)
+		return secret
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		log.Fatalf("Reading secret: %v\n", err)
+	}
+	return b
+}
+
+const (
+	tokenLifetime    = time.Hour * 24
+	botTokenLifetime = time.Hour * 24 * 365 // bots play unattended, so they shouldn't have to log in every day.
+)
 
 func LoginHandler(w http.ResponseWriter, r *http.Request) {
 	var req service.LoginRequest
 	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	user, err := userService.FindUserByEmail(req.Email)
+	key := limitKey(r, req.Email)
+	if !allowAttempt(w, key) {
+		return
+	}
+
+	user, err := userService.FindUserByEmail(r.Context(), req.Email)
 
 	if err != nil {
 		errorResponse(w, "Could not load user", http.StatusInternalServerError)
@@ -28,17 +99,27 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if verifyPassword(req.Password, user.Token) {
-		tokenString, err := createToken(user.Email, user.Name)
+		setRateLimitHeaders(w, loginLimiter.Success(key))
+		if user.Banned {
+			audit(r, model.AuditLoginFailed, req.Email, "", "banned")
+			errorResponse(w, "This account is banned", http.StatusForbidden)
+			return
+		}
+
+		tokenString, err := createToken(user, time.Now())
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			_, _ = w.Write([]byte("Internal api error while creating JWT"))
 			return
 		}
 
+		audit(r, model.AuditLogin, user.Email, "", "password")
 		w.WriteHeader(http.StatusOK)
 		_, _ = fmt.Fprint(w, tokenString)
 		return
 	} else {
+		audit(r, model.AuditLoginFailed, req.Email, "", "invalid credentials")
+		setRateLimitHeaders(w, loginLimiter.Failure(key))
 		w.WriteHeader(http.StatusUnauthorized)
 		_, _ = w.Write([]byte("Invalid credentials"))
 	}
@@ -49,19 +130,41 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Bot {
+		errorResponse(w, "Bot accounts can't be registered, ask an admin to make your account a bot", http.StatusForbidden)
+		return
+	}
+
+	key := limitKey(r, req.Email)
+	if !allowAttempt(w, key) {
+		return
 	}
 
 	// All is good, let's create the user.
-	if user, err := userService.CreateUser(req.Email, req.Name, hashPassword(req.Password)); err != nil {
-		// User creation failed
+	if user, err := userService.CreateUser(r.Context(), req.Email, req.Name, hashPassword(req.Password)); err != nil {
+		// User creation failed, which counts as a failed attempt, so it can't be used to find the known addresses.
+		setRateLimitHeaders(w, loginLimiter.Failure(key))
+		audit(r, model.AuditRegisterFailed, req.Email, "", err.Error())
 		if errors.Is(err, service.UserExistsError{}) {
 			errorResponse(w, "User already exists", http.StatusConflict)
 			return
 		}
+		if errors.As(err, &service.NameTakenError{}) {
+			errorResponse(w, err.Error(), http.StatusConflict)
+			return
+		}
 		errorResponse(w, "User creation failed", http.StatusInternalServerError)
 		return
 	} else {
 		// User creation succeeded
+		audit(r, model.AuditRegister, user.Email, "", "")
+		if err = verificationService.Send(user); err != nil {
+			log.WithContext(r.Context()).Errorf("Error sending the verification code to %s: %v", user.Email, err)
+		}
+		setRateLimitHeaders(w, loginLimiter.Allow(key))
 		w.WriteHeader(http.StatusCreated)
 		userService.Cache(&user)
 		_ = json.NewEncoder(w).Encode(service.NewCreateUserResponse(user))
@@ -79,20 +182,58 @@ func hashPassword(password string) string {
 	return fmt.Sprintf("%x", h)
 }
 
-func createToken(email string, name string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256,
-		jwt.MapClaims{
-			"email": email,
-			"name":  name,
-			"exp":   time.Now().Add(time.Hour * 24).Unix(),
-		})
+// createToken signs a JWT for the user, who signed in at authTime. Banned users don't get one, whichever way they
+// sign in.
+func createToken(user model.User, authTime time.Time) (string, error) {
+	if user.Banned {
+		return "", errors.New("this account is banned")
+	}
+	role := user.Role
+	if role == "" {
+		role = model.RolePlayer
+	}
+	lifetime := tokenLifetime
+	if user.Bot {
+		lifetime = botTokenLifetime
+	}
+	now := time.Now()
+	claims := tokenClaims{
+		Email:   user.Email,
+		Name:    user.Name,
+		Bot:     user.Bot,
+		Role:    role,
+		Version: user.TokenVersion,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(lifetime)),
+		},
+	}
+	// Tokens from before the auth_time claim existed don't know when the user signed in.
+	if !authTime.IsZero() {
+		claims.AuthTime = jwt.NewNumericDate(authTime)
+	}
+	return keyRing.Sign(claims)
+}
 
-	tokenString, err := token.SignedString(secretKey)
-	if err != nil {
-		return "", err
+// JwksHandler publishes the public keys that verify the JWTs.
+func JwksHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/jwk-set+json")
+	_ = json.NewEncoder(w).Encode(keyRing.Jwks())
+}
+
+func roleFromContext(r *http.Request) model.Role {
+	if role, ok := r.Context().Value("role").(model.Role); ok {
+		return role
 	}
+	return ""
+}
 
-	return tokenString, nil
+// authTimeFromContext returns when the user of the request signed in, or the zero time when it's unknown.
+func authTimeFromContext(r *http.Request) time.Time {
+	if authTime, ok := r.Context().Value("auth_time").(time.Time); ok {
+		return authTime
+	}
+	return time.Time{}
 }
 
 func emailFromContext(r *http.Request) string {
@@ -1,28 +1,111 @@
 package handlers
 
 import (
+	"bytes"
 	"connectfour/internal/db"
+	"connectfour/internal/keyring"
+	"connectfour/internal/logging"
+	"connectfour/internal/mail"
+	"connectfour/internal/metrics"
+	"connectfour/internal/model"
+	"connectfour/internal/ratelimit"
 	"connectfour/internal/service"
+	"connectfour/internal/tracing"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
-	"github.com/golang-jwt/jwt"
 	log "github.com/sirupsen/logrus"
 	"net/http"
+	"os"
 	"strings"
 	"time"
 )
 
 var (
-	userService  *service.UserService
-	gamesService *service.GamesService
+	userService          *service.UserService
+	gamesService         *service.GamesService
+	tournamentService    *service.TournamentService
+	mailer               mail.Mailer
+	passwordResetService *service.PasswordResetService
+	verificationService  *service.VerificationService
+	loginLimiter         ratelimit.Limiter
+	oidcService          *service.OidcService // nil when no identity provider is configured.
+	adminService         *service.AdminService
+	auditService         *service.AuditService
+	notificationService  *service.NotificationService
+	webhookService       *service.WebhookService
+	webhookQueue         *service.WebhookQueue
+	keyRing              *keyring.KeyRing
 )
 
-func init() {
+// Setup builds the services that the handlers use. The services read the database, so call it after migrating the
+// schema and before serving the routes.
+func Setup() {
+	keyRing = newKeyRing()
+	mailer = mail.NewMailerFromEnv()
+	loginLimiter = ratelimit.NewMemoryLimiter(ratelimit.DefaultPolicy)
 	userService = service.NewUserService(db.NewMariaDbUserRepository(), time.Minute*2)
+	gameRepository := service.NewCachingGameRepository(db.NewMariaDbGameRepository(), gameCacheTtl())
+	userService.OnInvalidate(gameRepository.InvalidateUser)
 	gamesService = service.NewGamesService(
 		userService,
-		db.NewMariaDbGameRepository())
+		gameRepository)
+	tournamentService = service.NewTournamentService(
+		db.NewMariaDbTournamentRepository(),
+		userService,
+		gamesService)
+	passwordResetService = service.NewPasswordResetService(userService, mailer)
+	verificationService = service.NewVerificationService(userService, mailer, verificationSecret())
+	oidcService = newOidcService()
+	adminService = service.NewAdminService(userService, gamesService)
+	auditService = service.NewAuditService(db.NewMariaDbAuditRepository())
+	webhooks := db.NewMariaDbWebhookRepository()
+	webhookQueue = service.NewWebhookQueue(webhooks,
+		&http.Client{Timeout: webhookTimeout, Transport: tracing.Transport(service.WebhookTransport())}, webhookBackoff)
+	if err := webhookQueue.Resume(context.Background()); err != nil {
+		log.Warnf("Could not resume the pending webhook deliveries: %v", err)
+	}
+	notifications := db.NewMariaDbNotificationRepository()
+	notificationService = service.NewNotificationService(userService, notifications,
+		service.NewInboxNotifier(notifications),
+		service.NewEmailNotifier(mailer),
+		service.NewWebhookNotifier(webhooks, webhookQueue))
+	gamesService.Subscribe(notificationService.OnGameEvent)
+	webhookService = service.NewWebhookService(userService, webhooks, webhookQueue)
+	gamesService.Subscribe(webhookService.OnGameEvent)
+	metrics.RegisterActiveUsers(userService.Presence().Online)
+	metrics.RegisterGamesByStatus(gamesService.CountByStatus)
+}
+
+// Stop stops the work that the services do in the background: the deliveries to the webhooks. Call it after the api
+// stopped serving, and before the database is closed, since the attempts in flight are recorded in it.
+func Stop() {
+	if webhookQueue != nil {
+		webhookQueue.Close()
+	}
+}
+
+const (
+	// webhookTimeout is the longest that a webhook may take to accept a notification or game event.
+	webhookTimeout = 10 * time.Second
+	// webhookBackoff is the wait before a game event is posted again, which doubles with every attempt.
+	webhookBackoff = 10 * time.Second
+)
+
+// gameCacheTtl is how long a game may be served from memory. Other api instances don't see the saves of this one, so
+// with more than one instance, they may return a game that's this old.
+func gameCacheTtl() time.Duration {
+	ttl := 10 * time.Second
+	if s, ok := os.LookupEnv("CONNECT_FOUR_GAME_CACHE_TTL"); ok {
+		if d, err := time.ParseDuration(s); err == nil && d > 0 {
+			ttl = d
+		} else {
+			log.Warnf("Ignoring CONNECT_FOUR_GAME_CACHE_TTL=%s, it must be a duration like 10s", s)
+		}
+	}
+	return ttl
 }
 
 func marshal(obj interface{}, response http.ResponseWriter) bool {
@@ -32,9 +115,42 @@ func marshal(obj interface{}, response http.ResponseWriter) bool {
 	return handleError(err, response)
 }
 
+// marshalWithETag writes obj like marshal, with an ETag of its contents. When the request has that ETag in its
+// If-None-Match header, the client already has the latest version, so only 304 Not Modified is returned.
+func marshalWithETag(obj interface{}, response http.ResponseWriter, request *http.Request) bool {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(obj); err != nil {
+		return handleError(err, response)
+	}
+	sum := sha256.Sum256(body.Bytes())
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	// the client may store the response, as long as it checks with the api before using it.
+	response.Header().Set("Cache-Control", "private, no-cache")
+	response.Header().Set("ETag", etag)
+	if etagMatches(request.Header.Get("If-None-Match"), etag) {
+		response.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	response.Header().Set("Content-Type", "application/json")
+	_, _ = response.Write(body.Bytes())
+	return true
+}
+
+// etagMatches checks the If-None-Match header, which is a list of ETags (weak ones start with W/) or *.
+func etagMatches(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
 func unmarshal[T interface{}](response http.ResponseWriter, request *http.Request) (T, bool) {
 	var req T
-	log.Debugf("Unmarshalling request to %s", request.RequestURI)
+	log.WithContext(request.Context()).Debugf("Unmarshalling request to %s", request.RequestURI)
 	decoder := json.NewDecoder(request.Body)
 	decoder.DisallowUnknownFields()
 	err := decoder.Decode(&req)
@@ -71,51 +187,71 @@ func errorResponse(w http.ResponseWriter, message string, httpStatusCode int) {
 
 func JwtValidation(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		auth := r.Header.Get("Authorization")
-		log.Debugf("Auth header: %s", auth)
-
-		tokenString, found := strings.CutPrefix(auth, "Bearer ")
-		if !found || tokenString == "" {
-			log.Warnf("No bearer token found in request to: %s", r.URL.Path)
-			errorResponse(w, "No bearer token found", http.StatusUnauthorized)
-			return
-		}
-
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return secretKey, nil
-		})
-
-		if err != nil {
-			log.Warnf("Invalid bearer token for request to %s: %v", r.URL.Path, err)
-			errorResponse(w, "Invalid bearer token", http.StatusUnauthorized)
+		claims, user, ok := validateJwt(w, r)
+		if !ok {
 			return
 		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			exp, ok := claims["exp"].(float64)
-			if !ok {
-				log.Warnf("Invalid expiration time in token for request to %s", r.URL.Path)
-				errorResponse(w, "Invalid expiration time", http.StatusUnauthorized)
-				return
-			}
-			if expired(exp) {
-				log.Warnf("Token expired for request to %s", r.URL.Path)
-				errorResponse(w, "Token is expired", http.StatusUnauthorized)
-				return
-			}
-
-			// Valid token, proceed
-			email := claims["email"].(string)
-			log.Debugf("Valid JWT for user %s, accessing %s", email, r.URL.Path)
-			ctx := context.WithValue(r.Context(), "email", email)
-			next.ServeHTTP(w, r.WithContext(ctx))
-		} else {
-			log.Warnf("Invalid token claims for request to %s", r.URL.Path)
-			errorResponse(w, "Invalid token", http.StatusUnauthorized)
+		// Valid token, proceed
+		log.WithContext(r.Context()).Debugf("Valid JWT for user %s, accessing %s", claims.Email, r.URL.Path)
+		logging.Identify(r.Context(), claims.Email)
+		ctx := context.WithValue(r.Context(), "email", claims.Email)
+		// the role of the user as it is now, not as it was when the token was issued.
+		ctx = context.WithValue(ctx, "role", user.Role)
+		if claims.AuthTime != nil {
+			ctx = context.WithValue(ctx, "auth_time", claims.AuthTime.Time)
 		}
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-func expired(exp float64) bool {
-	return time.Unix(int64(exp), 0).Before(time.Now())
+// validateJwt checks the bearer token of the request, in a span of its own, and returns its claims with the user
+// they're about. It writes the error response when the token isn't accepted.
+func validateJwt(w http.ResponseWriter, r *http.Request) (claims tokenClaims, user model.User, ok bool) {
+	ctx, span := tracing.Start(r.Context(), "JwtValidation")
+	defer span.End()
+
+	auth := r.Header.Get("Authorization")
+	tokenString, found := strings.CutPrefix(auth, "Bearer ")
+	if !found || tokenString == "" {
+		log.WithContext(r.Context()).Warnf("No bearer token found in request to: %s", r.URL.Path)
+		errorResponse(w, "No bearer token found", http.StatusUnauthorized)
+		return claims, user, false
+	}
+
+	if _, err := keyRing.Parse(tokenString, &claims); err != nil {
+		log.WithContext(r.Context()).Warnf("Invalid bearer token for request to %s: %v", r.URL.Path, err)
+		errorResponse(w, "Invalid bearer token", http.StatusUnauthorized)
+		return claims, user, false
+	}
+	if claims.Email == "" {
+		log.WithContext(r.Context()).Warnf("Invalid token claims for request to %s", r.URL.Path)
+		errorResponse(w, "Invalid token", http.StatusUnauthorized)
+		return claims, user, false
+	}
+
+	// Tokens of users that were deleted (and so anonymized) or banned after the token was issued aren't accepted
+	// either, and neither are the tokens that were revoked. When the user can't be looked up, the token isn't trusted.
+	user, err := userService.FindUserByEmail(ctx, claims.Email)
+	if err != nil {
+		log.WithContext(r.Context()).Errorf("Could not look up user %s of the token for %s: %v", claims.Email, r.URL.Path, err)
+		errorResponse(w, "Could not validate the token", http.StatusServiceUnavailable)
+		return claims, user, false
+	}
+	if user.Empty() {
+		log.WithContext(r.Context()).Warnf("Token of unknown or deleted user %s used for %s", claims.Email, r.URL.Path)
+		errorResponse(w, "Invalid token", http.StatusUnauthorized)
+		return claims, user, false
+	}
+	if user.Banned {
+		log.WithContext(r.Context()).Warnf("Banned user %s tried to access %s", claims.Email, r.URL.Path)
+		errorResponse(w, "This account is banned", http.StatusForbidden)
+		return claims, user, false
+	}
+	if claims.Version != user.TokenVersion {
+		log.WithContext(r.Context()).Warnf("Revoked token of user %s used for %s", claims.Email, r.URL.Path)
+		errorResponse(w, "This token was revoked", http.StatusUnauthorized)
+		return claims, user, false
+	}
+	return claims, user, true
 }
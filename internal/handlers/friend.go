@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"connectfour/internal/service"
+	"errors"
+	"github.com/Masterminds/goutils"
+	"github.com/go-chi/chi/v5"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+)
+
+func FriendsHandler(response http.ResponseWriter, request *http.Request) {
+	log.WithContext(request.Context()).Debug("Listing my friends")
+	email := emailFromContext(request)
+	friends, err := userService.Friends(request.Context(), email)
+	if handleError(err, response) {
+		marshal(friends, response)
+	}
+}
+
+func RequestFriendHandler(response http.ResponseWriter, request *http.Request) {
+	if req, ok := unmarshal[service.FriendRequest](response, request); ok {
+		email := emailFromContext(request)
+		if handleError(userService.RequestFriend(request.Context(), email, req.Email), response) {
+			FriendsHandler(response, request)
+		}
+	}
+}
+
+func AcceptFriendHandler(response http.ResponseWriter, request *http.Request) {
+	if friendEmail, ok := parseFriendEmail(response, request); ok {
+		email := emailFromContext(request)
+		if handleError(userService.AcceptFriend(request.Context(), email, friendEmail), response) {
+			FriendsHandler(response, request)
+		}
+	}
+}
+
+func RemoveFriendHandler(response http.ResponseWriter, request *http.Request) {
+	if friendEmail, ok := parseFriendEmail(response, request); ok {
+		email := emailFromContext(request)
+		if handleError(userService.RemoveFriend(request.Context(), email, friendEmail), response) {
+			FriendsHandler(response, request)
+		}
+	}
+}
+
+func parseFriendEmail(response http.ResponseWriter, request *http.Request) (string, bool) {
+	email := chi.URLParam(request, "email")
+	if goutils.IsBlank(email) {
+		return "", handleError(errors.New("the friend's e-mail address is missing from the uri"), response)
+	}
+	return email, true
+}
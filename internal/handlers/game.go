@@ -2,37 +2,116 @@ package handlers
 
 import (
 	"connectfour/internal/model"
+	"connectfour/internal/server"
 	"connectfour/internal/service"
+	"context"
 	"errors"
+	"fmt"
 	"github.com/Masterminds/goutils"
 	"github.com/go-chi/chi/v5"
 	log "github.com/sirupsen/logrus"
 	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultTurnWait = 30 * time.Second
+	maxTurnWait     = 55 * time.Second // stay below the timeout middleware.
 )
 
 func GameStateHandler(response http.ResponseWriter, request *http.Request) {
 	if key, ok := parseAndCheck(response, request); ok {
-		marshal(gamesService.GetGameState(key), response)
+		marshalWithETag(gamesService.GetGameState(request.Context(), key), response, request)
 	}
 }
 
+// OpenGamesHandler returns a page of the public games that can be joined. See gameListRequest for the filters.
 func OpenGamesHandler(response http.ResponseWriter, request *http.Request) {
-	log.Debug("Listing all games")
-	email := emailFromContext(request)
-	marshal(gamesService.AllOpenGames(email), response)
+	log.WithContext(request.Context()).Debug("Listing the open games")
+	req, ok := gameListRequest(response, request)
+	if !ok {
+		return
+	}
+	page, err := gamesService.OpenGames(request.Context(), req)
+	if handleError(err, response) {
+		marshalPage(page, response, request)
+	}
 }
 
+// MyGamesHandler returns a page of the games of the user. See gameListRequest for the filters.
 func MyGamesHandler(response http.ResponseWriter, request *http.Request) {
-	log.Debug("Listing all my games")
-	email := emailFromContext(request)
-	marshal(gamesService.AllMyGames(email), response)
+	log.WithContext(request.Context()).Debug("Listing my games")
+	req, ok := gameListRequest(response, request)
+	if !ok {
+		return
+	}
+	page, err := gamesService.MyGames(request.Context(), emailFromContext(request), req)
+	if handleError(err, response) {
+		marshalPage(page, response, request)
+	}
+}
+
+// gameListRequest reads the `status`, `opponent` (e-mail address), `public` (true or false), `from` and `until`
+// (RFC 3339), `order` (newest or oldest), `cursor` and `limit` query parameters of a game listing.
+func gameListRequest(response http.ResponseWriter, request *http.Request) (service.GameListRequest, bool) {
+	q := request.URL.Query()
+	req := service.GameListRequest{
+		Status:   model.GameStatus(q.Get("status")),
+		Opponent: q.Get("opponent"),
+		Order:    model.GameOrder(q.Get("order")),
+		Cursor:   q.Get("cursor"),
+	}
+	if public := q.Get("public"); public != "" {
+		b, err := strconv.ParseBool(public)
+		if err != nil {
+			errorResponse(response, "The public parameter must be true or false", http.StatusBadRequest)
+			return service.GameListRequest{}, false
+		}
+		req.Public = &b
+	}
+	for name, t := range map[string]*time.Time{"from": &req.From, "until": &req.Until} {
+		if value := q.Get(name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				errorResponse(response, "The "+name+" parameter must be a RFC 3339 timestamp", http.StatusBadRequest)
+				return service.GameListRequest{}, false
+			}
+			*t = parsed
+		}
+	}
+	if limit := q.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			errorResponse(response, "The limit parameter must be a positive number", http.StatusBadRequest)
+			return service.GameListRequest{}, false
+		}
+		req.Limit = n
+	}
+	return req, true
+}
+
+// marshalPage writes the games of the page, and links to the next page in the Link header, with the same query
+// parameters and the cursor of the next page.
+func marshalPage(page service.GamePage, response http.ResponseWriter, request *http.Request) {
+	if page.NextCursor != "" {
+		next := *request.URL
+		q := next.Query()
+		q.Set("cursor", page.NextCursor)
+		next.RawQuery = q.Encode()
+		response.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"next\"", next.RequestURI()))
+	}
+	marshal(page.Games, response)
 }
 
 func NewGameHandler(response http.ResponseWriter, request *http.Request) {
 	if req, ok := unmarshal[service.NewGameRequest](response, request); ok {
 		email := emailFromContext(request)
-		game := gamesService.NewGame(email, req.Public)
-		marshal(game, response)
+		game, err := gamesService.NewGame(request.Context(), email, req.Public, req.Invitee)
+		if handleError(err, response) {
+			audit(request, model.AuditGameCreate, email, game.Key, fmt.Sprintf("public: %t, invitee: %s", req.Public, req.Invitee))
+			marshal(game, response)
+		}
 	}
 }
 
@@ -43,26 +122,60 @@ func JoinGameHandler(response http.ResponseWriter, request *http.Request) {
 	}
 
 	email := emailFromContext(request)
-	err := gamesService.JoinGame(key, email)
+	err := gamesService.JoinGame(request.Context(), key, email)
 	if handleError(err, response) {
-		marshal(gamesService.GetGameState(key), response)
+		audit(request, model.AuditGameJoin, email, key, "")
+		marshal(gamesService.GetGameState(request.Context(), key), response)
 	}
 }
 
 func PlayMoveHandler(response http.ResponseWriter, request *http.Request) {
 	key := parseGameKey(response, request)
-	if !checkGame(key, response) {
+	if !checkGame(request.Context(), key, response) {
 		return
 	}
 	if req, ok := unmarshal[service.PlayMoveRequest](response, request); ok {
 		email := emailFromContext(request)
-		err := gamesService.PlayMove(key, email, req.Column)
+		err := gamesService.PlayMove(request.Context(), key, email, req.Column)
 		if handleError(err, response) {
-			marshal(gamesService.GetGameState(key), response)
+			state := gamesService.GetGameState(request.Context(), key)
+			audit(request, model.AuditGameMove, email, key, fmt.Sprintf("column: %d, status: %s", req.Column, state.Status))
+			marshal(state, response)
 		}
 	}
 }
 
+// WaitForTurnHandler is the long-poll endpoint for bots (and other headless clients). It responds as soon as it's
+// the caller's turn or the game is over, or with the current state when the 'wait' seconds have passed.
+func WaitForTurnHandler(response http.ResponseWriter, request *http.Request) {
+	key, ok := parseAndCheck(response, request)
+	if !ok {
+		return
+	}
+
+	wait := defaultTurnWait
+	if value := request.URL.Query().Get("wait"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 0 {
+			handleError(errors.New("the wait parameter should be a positive number of seconds"), response)
+			return
+		}
+		wait = min(time.Duration(seconds)*time.Second, maxTurnWait)
+	}
+
+	email := emailFromContext(request)
+	ctx, cancel := server.UntilStopping(request.Context())
+	defer cancel()
+	turn, err := gamesService.WaitForTurn(ctx, key, email, wait)
+	if err != nil && request.Context().Err() == nil && server.Stopping(request.Context()) {
+		// The api is stopping, so the client gets the state as it is now, like when the wait is over, and polls again.
+		turn, err = gamesService.WaitForTurn(request.Context(), key, email, 0)
+	}
+	if handleError(err, response) {
+		marshal(turn, response)
+	}
+}
+
 func parseGameKey(response http.ResponseWriter, request *http.Request) string {
 	key := chi.URLParam(request, "key")
 	if goutils.IsBlank(key) {
@@ -72,8 +185,8 @@ func parseGameKey(response http.ResponseWriter, request *http.Request) string {
 	return key
 }
 
-func checkGame(key string, response http.ResponseWriter) bool {
-	if gamesService.GameExists(key) {
+func checkGame(ctx context.Context, key string, response http.ResponseWriter) bool {
+	if gamesService.GameExists(ctx, key) {
 		return true
 	}
 
@@ -82,5 +195,5 @@ func checkGame(key string, response http.ResponseWriter) bool {
 
 func parseAndCheck(response http.ResponseWriter, request *http.Request) (string, bool) {
 	key := parseGameKey(response, request)
-	return key, checkGame(key, response)
+	return key, checkGame(request.Context(), key, response)
 }
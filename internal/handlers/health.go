@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"connectfour/internal/db"
+	"connectfour/internal/service"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+)
+
+// HealthzHandler tells that the api is alive. It reports whether the database can be reached, but always responds
+// with 200, so that an orchestrator doesn't restart the api while the database is down.
+func HealthzHandler(response http.ResponseWriter, request *http.Request) {
+	marshal(health(request), response)
+}
+
+// ReadyzHandler responds with 503 while the database can't be reached, so that no traffic is sent to this instance.
+func ReadyzHandler(response http.ResponseWriter, request *http.Request) {
+	resp := health(request)
+	if resp.Status != "ok" {
+		response.Header().Set("Content-Type", "application/json")
+		response.WriteHeader(http.StatusServiceUnavailable)
+	}
+	marshal(resp, response)
+}
+
+func health(request *http.Request) service.HealthResponse {
+	resp := service.HealthResponse{Status: "ok", Database: "ok"}
+	if err := db.Ping(request.Context()); err != nil {
+		log.WithContext(request.Context()).Warnf("Health check: the database can't be reached: %v", err)
+		resp.Status = "unavailable"
+		resp.Database = "unreachable"
+	}
+	stats := db.PoolStats()
+	resp.Connections.Open = stats.OpenConnections
+	resp.Connections.InUse = stats.InUse
+	resp.Connections.Idle = stats.Idle
+	resp.Connections.Max = stats.MaxOpenConnections
+	return resp
+}
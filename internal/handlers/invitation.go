@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"connectfour/internal/model"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+)
+
+func InvitationsHandler(response http.ResponseWriter, request *http.Request) {
+	log.WithContext(request.Context()).Debug("Listing my invitations")
+	email := emailFromContext(request)
+	marshal(gamesService.Invitations(request.Context(), email), response)
+}
+
+func AcceptInvitationHandler(response http.ResponseWriter, request *http.Request) {
+	key, ok := parseAndCheck(response, request)
+	if !ok {
+		return
+	}
+
+	email := emailFromContext(request)
+	err := gamesService.AcceptInvitation(request.Context(), key, email)
+	if handleError(err, response) {
+		audit(request, model.AuditGameJoin, email, key, "invitation")
+		marshal(gamesService.GetGameState(request.Context(), key), response)
+	}
+}
+
+func DeclineInvitationHandler(response http.ResponseWriter, request *http.Request) {
+	key, ok := parseAndCheck(response, request)
+	if !ok {
+		return
+	}
+
+	email := emailFromContext(request)
+	err := gamesService.DeclineInvitation(request.Context(), key, email)
+	if handleError(err, response) {
+		audit(request, model.AuditInvitationDecline, email, key, "")
+		marshal(gamesService.GetGameState(request.Context(), key), response)
+	}
+}
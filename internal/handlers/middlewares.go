@@ -1,9 +1,15 @@
 package handlers
 
 import (
+	"connectfour/internal/logging"
+	"connectfour/internal/metrics"
+	"connectfour/internal/model"
+	"connectfour/internal/tracing"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
+	log "github.com/sirupsen/logrus"
+	"net/http"
 	"time"
 )
 
@@ -12,19 +18,21 @@ func SetupMiddlewares(r *chi.Mux) {
 		// AllowedOrigins:   []string{"https://foo.com"}, // Use this to allow specific origin hosts
 		AllowedOrigins: []string{"https://*", "http://*"},
 		// AllowOriginFunc:  func(r *http.Request, origin string) bool { return true },
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "If-None-Match", "traceparent", "tracestate"},
+		ExposedHeaders:   []string{"ETag", "Link", "Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "X-Request-Id"},
 		AllowCredentials: false,
 		MaxAge:           300, // Maximum value not ignored by any of major browsers
 	}))
 
 	// A good base middleware stack
-	r.Use(middleware.Logger)
+	r.Use(tracing.Middleware)
+	r.Use(metrics.Middleware)
 	r.Use(middleware.RequestID)
+	r.Use(logging.Middleware)
 	//r.Use(middleware.RealIP)
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.NoCache)
+	r.Use(noCache)
 	r.Use(middleware.Throttle(100))
 
 	// Set a timeout value on the request context (ctx), that will signal
@@ -32,3 +40,42 @@ func SetupMiddlewares(r *chi.Mux) {
 	// processing should be stopped.
 	r.Use(middleware.Timeout(60 * time.Second))
 }
+
+// TrackPresence registers the activity of the authenticated user, so that friends can see they're online. It
+// must be used after JwtValidation.
+func TrackPresence(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if email := emailFromContext(r); email != "" {
+			userService.Presence().Touch(email)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// noCache tells the clients and proxies not to cache the responses, like middleware.NoCache. Unlike that, it leaves
+// the If-None-Match header of the request alone, so that a handler can tell a client that has the latest version of
+// a response that it's not modified. Those handlers set a Cache-Control of their own.
+func noCache(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-cache, no-store, no-transform, must-revalidate, private, max-age=0")
+		w.Header().Set("Expires", time.Unix(0, 0).UTC().Format(http.TimeFormat))
+		w.Header().Set("Pragma", "no-cache")
+		w.Header().Set("X-Accel-Expires", "0")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireRole refuses the request when the role of the user doesn't grant everything the role does. It must be used
+// after JwtValidation, which looks up the current role, so a demoted user loses the role before their token expires.
+func RequireRole(role model.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !roleFromContext(r).AtLeast(role) {
+				log.WithContext(r.Context()).Warnf("User %s needs to be %s to access %s", emailFromContext(r), role, r.URL.Path)
+				errorResponse(w, "You are not allowed to do this", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
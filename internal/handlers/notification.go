@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"connectfour/internal/service"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+)
+
+// NotificationsHandler returns the newest notifications in the inbox, only the unread ones with `?unread=true`.
+func NotificationsHandler(response http.ResponseWriter, request *http.Request) {
+	log.WithContext(request.Context()).Debug("Listing my notifications")
+	unread, _ := strconv.ParseBool(request.URL.Query().Get("unread"))
+	notifications, err := notificationService.Inbox(request.Context(), emailFromContext(request), unread)
+	if handleError(err, response) {
+		marshal(notifications, response)
+	}
+}
+
+func ReadNotificationHandler(response http.ResponseWriter, request *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(request, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		handleError(errors.New("the notification id in the uri is not valid"), response)
+		return
+	}
+	if handleError(notificationService.MarkRead(request.Context(), emailFromContext(request), id), response) {
+		NotificationsHandler(response, request)
+	}
+}
+
+func ReadAllNotificationsHandler(response http.ResponseWriter, request *http.Request) {
+	if handleError(notificationService.MarkRead(request.Context(), emailFromContext(request), 0), response) {
+		NotificationsHandler(response, request)
+	}
+}
+
+func NotificationPreferencesHandler(response http.ResponseWriter, request *http.Request) {
+	prefs, err := notificationService.Preferences(request.Context(), emailFromContext(request))
+	if handleError(err, response) {
+		marshal(prefs, response)
+	}
+}
+
+func SetNotificationPreferencesHandler(response http.ResponseWriter, request *http.Request) {
+	if req, ok := unmarshal[service.NotificationPreferencesRequest](response, request); ok {
+		prefs, err := notificationService.SetPreferences(request.Context(), emailFromContext(request), req)
+		if handleError(err, response) {
+			marshal(prefs, response)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"connectfour/internal/model"
+	"connectfour/internal/service"
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// oidcConfigFromEnv reads the identity provider configuration. Signing in with an identity provider is only enabled
+// when CONNECT_FOUR_OIDC_ISSUER is set.
+func oidcConfigFromEnv() (service.OidcConfig, bool) {
+	issuer, ok := os.LookupEnv("CONNECT_FOUR_OIDC_ISSUER")
+	if !ok {
+		return service.OidcConfig{}, false
+	}
+	cfg := service.OidcConfig{
+		Issuer:      issuer,
+		ClientId:    os.Getenv("CONNECT_FOUR_OIDC_CLIENT_ID"),
+		RedirectUrl: os.Getenv("CONNECT_FOUR_OIDC_REDIRECT_URL"),
+	}
+	if file, ok := os.LookupEnv("CONNECT_FOUR_OIDC_CLIENT_SECRET_FILE"); ok {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			log.Fatalf("Reading secret: %v\n", err)
+		}
+		cfg.ClientSecret = strings.TrimSpace(string(b))
+	}
+	return cfg, true
+}
+
+// newOidcService returns nil when no identity provider is configured, or when it can't be reached.
+func newOidcService() *service.OidcService {
+	cfg, ok := oidcConfigFromEnv()
+	if !ok {
+		log.Infoln("No identity provider configured. Use CONNECT_FOUR_OIDC_ISSUER to enable signing in with one.")
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	s, err := service.NewOidcService(ctx, userService, cfg)
+	if err != nil {
+		log.Errorf("Signing in with identity provider %s is disabled: %v", cfg.Issuer, err)
+		return nil
+	}
+	log.Infof("Users can sign in with identity provider %s", cfg.Issuer)
+	return s
+}
+
+// OidcLoginHandler sends the user to the identity provider. A client can pass its loopback `redirect_uri` and
+// `state`, to receive the JWT when the sign-in completes.
+func OidcLoginHandler(response http.ResponseWriter, request *http.Request) {
+	if oidcService == nil {
+		errorResponse(response, "Signing in with an identity provider is not configured", http.StatusNotFound)
+		return
+	}
+	q := request.URL.Query()
+	authUrl, err := oidcService.AuthCodeUrl(q.Get("redirect_uri"), q.Get("state"))
+	if err != nil {
+		errorResponse(response, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Redirect(response, request, authUrl, http.StatusFound)
+}
+
+// OidcCallbackHandler completes the sign-in and issues the same JWT as the LoginHandler. It's passed to the loopback
+// redirect of the client, or returned in the body when the sign-in was started without one.
+func OidcCallbackHandler(response http.ResponseWriter, request *http.Request) {
+	if oidcService == nil {
+		errorResponse(response, "Signing in with an identity provider is not configured", http.StatusNotFound)
+		return
+	}
+	q := request.URL.Query()
+	if idpError := q.Get("error"); idpError != "" {
+		log.WithContext(request.Context()).Warnf("The identity provider returned an error: %s (%s)", idpError, q.Get("error_description"))
+		errorResponse(response, "The identity provider refused the sign-in: "+idpError, http.StatusUnauthorized)
+		return
+	}
+
+	user, login, err := oidcService.Exchange(request.Context(), q.Get("code"), q.Get("state"))
+	var tokenString string
+	if err == nil {
+		tokenString, err = createToken(user, time.Now())
+	}
+	if err != nil {
+		audit(request, model.AuditLoginFailed, user.Email, "", "oidc: "+err.Error())
+	} else {
+		audit(request, model.AuditLogin, user.Email, "", "oidc")
+	}
+
+	if login.ClientRedirect != "" {
+		redirectToClient(response, request, login, tokenString, err)
+		return
+	}
+	if err != nil {
+		log.WithContext(request.Context()).Warnf("Signing in with the identity provider failed: %v", err)
+		errorResponse(response, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	response.WriteHeader(http.StatusOK)
+	_, _ = fmt.Fprint(response, tokenString)
+}
+
+func redirectToClient(response http.ResponseWriter, request *http.Request, login service.PendingLogin, token string, err error) {
+	params := url.Values{}
+	params.Set("state", login.ClientState)
+	if err != nil {
+		log.WithContext(request.Context()).Warnf("Signing in with the identity provider failed: %v", err)
+		params.Set("error", err.Error())
+	} else {
+		params.Set("token", token)
+	}
+	http.Redirect(response, request, login.ClientRedirect+"?"+params.Encode(), http.StatusFound)
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"connectfour/internal/model"
+	"connectfour/internal/service"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ForgotPasswordHandler mails a reset token. It responds the same for unknown e-mail addresses, so it can't be used
+// to find out who has an account. Every request counts as a failed attempt, so it can't be used to flood a mailbox.
+func ForgotPasswordHandler(response http.ResponseWriter, request *http.Request) {
+	if req, ok := unmarshal[service.ForgotPasswordRequest](response, request); ok {
+		keys := resetKeys(request, req.Email)
+		if !allowAttempts(response, keys) {
+			return
+		}
+		countFailures(response, keys)
+		if err := passwordResetService.Forgot(request.Context(), req.Email); err != nil {
+			errorResponse(response, "The reset token could not be sent", http.StatusInternalServerError)
+			return
+		}
+		marshal(map[string]string{"message": "If the e-mail address is known, a reset token was sent to it"}, response)
+	}
+}
+
+// ResetPasswordHandler sets the new password. The request has no e-mail address, so the invalid tokens are counted
+// by the client ip, which keeps the tokens from being guessed.
+func ResetPasswordHandler(response http.ResponseWriter, request *http.Request) {
+	if req, ok := unmarshal[service.ResetPasswordRequest](response, request); ok {
+		keys := resetKeys(request, "")
+		if !allowAttempts(response, keys) {
+			return
+		}
+		if l := len(strings.TrimSpace(req.Password)); l < minPasswordLength || l > maxPasswordLength {
+			errorResponse(response, "The new password must have 3 to 100 characters", http.StatusBadRequest)
+			return
+		}
+		user, err := passwordResetService.Reset(request.Context(), req.Token, hashPassword(req.Password))
+		if errors.As(err, &service.InvalidResetTokenError{}) {
+			// the token is unknown, so is the user whose password it would reset.
+			audit(request, model.AuditResetFailed, "", "", err.Error())
+			countFailures(response, keys)
+			errorResponse(response, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			errorResponse(response, "The password could not be reset", http.StatusInternalServerError)
+			return
+		}
+		audit(request, model.AuditPasswordReset, user.Email, "", "")
+		marshal(map[string]string{"message": "Your password was changed"}, response)
+	}
+}
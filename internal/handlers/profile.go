@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"connectfour/internal/model"
+	"connectfour/internal/service"
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	minPasswordLength = 3
+	maxPasswordLength = 100
+
+	// freshSignIn is how long after signing in with the identity provider a user without a password can delete their
+	// account.
+	freshSignIn = 5 * time.Minute
+)
+
+func ProfileHandler(response http.ResponseWriter, request *http.Request) {
+	user, err := userService.FindUserByEmail(request.Context(), emailFromContext(request))
+	if err != nil || user.Empty() {
+		errorResponse(response, "Could not load user", http.StatusInternalServerError)
+		return
+	}
+	marshal(service.NewProfileResponse(user), response)
+}
+
+// UpdateProfileHandler changes the display name. Since the name is part of the JWT, a new token is returned too.
+func UpdateProfileHandler(response http.ResponseWriter, request *http.Request) {
+	if req, ok := unmarshal[service.UpdateProfileRequest](response, request); ok {
+		user, err := userService.Rename(request.Context(), emailFromContext(request), req.Name)
+		if errors.As(err, &service.NameTakenError{}) {
+			errorResponse(response, err.Error(), http.StatusConflict)
+			return
+		}
+		if !handleError(err, response) {
+			return
+		}
+
+		resp := service.NewProfileResponse(user)
+		if resp.Token, err = createToken(user, authTimeFromContext(request)); err != nil {
+			errorResponse(response, "Internal api error while creating JWT", http.StatusInternalServerError)
+			return
+		}
+		audit(request, model.AuditTokenRefresh, "", "", "renamed to "+user.Name)
+		marshal(resp, response)
+	}
+}
+
+// RevokeMyTokensHandler revokes all the tokens of the authenticated user, like the token of a bot that leaked, and
+// returns a new token.
+func RevokeMyTokensHandler(response http.ResponseWriter, request *http.Request) {
+	user, err := userService.RevokeTokens(request.Context(), emailFromContext(request))
+	if !handleError(err, response) {
+		return
+	}
+	token, err := createToken(user, authTimeFromContext(request))
+	if err != nil {
+		errorResponse(response, "Internal api error while creating JWT", http.StatusInternalServerError)
+		return
+	}
+	audit(request, model.AuditTokenRevoke, "", "", "")
+	marshal(map[string]string{"token": token}, response)
+}
+
+// ChangePasswordHandler changes the password of the authenticated user. The tokens that were issued before are
+// revoked, so a new token is returned.
+func ChangePasswordHandler(response http.ResponseWriter, request *http.Request) {
+	if req, ok := unmarshal[service.ChangePasswordRequest](response, request); ok {
+		email := emailFromContext(request)
+		if !checkPassword(request, response, email, req.OldPassword) {
+			return
+		}
+		if l := len(strings.TrimSpace(req.NewPassword)); l < minPasswordLength || l > maxPasswordLength {
+			errorResponse(response, "The new password must have 3 to 100 characters", http.StatusBadRequest)
+			return
+		}
+		if !handleError(userService.ChangePassword(request.Context(), email, hashPassword(req.NewPassword)), response) {
+			return
+		}
+		audit(request, model.AuditPasswordChange, "", "", "")
+		user, err := userService.RevokeTokens(request.Context(), email)
+		if !handleError(err, response) {
+			return
+		}
+		token, err := createToken(user, authTimeFromContext(request))
+		if err != nil {
+			errorResponse(response, "Internal api error while creating JWT", http.StatusInternalServerError)
+			return
+		}
+		marshal(map[string]string{"message": "Your password was changed", "token": token}, response)
+	}
+}
+
+// DeleteAccountHandler deletes the account of the authenticated user. The games they haven't finished are aborted,
+// and the games they played are anonymized.
+func DeleteAccountHandler(response http.ResponseWriter, request *http.Request) {
+	if req, ok := unmarshal[service.DeleteAccountRequest](response, request); ok {
+		email := emailFromContext(request)
+		if !checkDeletion(request, response, email, req.Password) {
+			return
+		}
+		if !handleError(gamesService.AbortGamesOf(request.Context(), email), response) {
+			return
+		}
+		if handleError(userService.DeleteUser(request.Context(), email), response) {
+			audit(request, model.AuditDeleteAccount, email, "", "")
+			log.WithContext(request.Context()).Infof("Account of %s was deleted", email)
+			marshal(map[string]string{"message": "Your account was deleted"}, response)
+		}
+	}
+}
+
+// checkDeletion asks for the password before the account is deleted. Users that only sign in with an identity
+// provider have no password, so they sign in with it again instead: they must have signed in recently. Tokens that
+// were issued again keep the time of the sign-in, so they don't count.
+func checkDeletion(request *http.Request, response http.ResponseWriter, email string, password string) bool {
+	user, err := userService.FindUserByEmail(request.Context(), email)
+	if err != nil || user.Empty() {
+		errorResponse(response, "Could not load user", http.StatusInternalServerError)
+		return false
+	}
+	if user.Token != "" {
+		return checkPassword(request, response, email, password)
+	}
+	if time.Since(authTimeFromContext(request)) > freshSignIn {
+		errorResponse(response, "Sign in with your identity provider again to delete your account", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// checkPassword verifies the password of the user, and writes an error response when it doesn't match. A wrong
+// password returns 403 rather than 401, since the token itself is valid. The failures are counted like those of the
+// login, so a token that leaked can't be used to guess the password.
+func checkPassword(request *http.Request, response http.ResponseWriter, email string, password string) bool {
+	key := limitKey(request, email)
+	if !allowAttempt(response, key) {
+		return false
+	}
+	user, err := userService.FindUserByEmail(request.Context(), email)
+	if err != nil || user.Empty() {
+		errorResponse(response, "Could not load user", http.StatusInternalServerError)
+		return false
+	}
+	if !verifyPassword(password, user.Token) {
+		setRateLimitHeaders(response, loginLimiter.Failure(key))
+		errorResponse(response, "The password is incorrect", http.StatusForbidden)
+		return false
+	}
+	setRateLimitHeaders(response, loginLimiter.Success(key))
+	return true
+}
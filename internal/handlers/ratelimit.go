@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"connectfour/internal/ratelimit"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// limitKey returns the key that failed attempts are counted by: the e-mail address combined with the client ip.
+func limitKey(r *http.Request, email string) string {
+	return strings.ToLower(strings.TrimSpace(email)) + "|" + clientIp(r)
+}
+
+// resetKeys returns the keys that password reset requests are counted by: the e-mail address and the client ip,
+// each on their own, so a client can't try many addresses and an address can't be tried from many clients. The
+// address is empty when the request doesn't have one.
+func resetKeys(r *http.Request, email string) []string {
+	keys := []string{"reset-ip|" + clientIp(r)}
+	if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+		keys = append(keys, "reset-email|"+email)
+	}
+	return keys
+}
+
+// allowAttempts is allowAttempt for all the keys, which must all be allowed.
+func allowAttempts(w http.ResponseWriter, keys []string) bool {
+	for _, key := range keys {
+		if !allowAttempt(w, key) {
+			return false
+		}
+	}
+	return true
+}
+
+// countFailures counts a failed attempt for each of the keys.
+func countFailures(w http.ResponseWriter, keys []string) {
+	for _, key := range keys {
+		setRateLimitHeaders(w, loginLimiter.Failure(key))
+	}
+}
+
+// allowAttempt sets the rate limit headers and responds with 429 when the key is locked out.
+func allowAttempt(w http.ResponseWriter, key string) bool {
+	d := loginLimiter.Allow(key)
+	setRateLimitHeaders(w, d)
+	if !d.Allowed {
+		errorResponse(w, "Too many failed attempts, try again later", http.StatusTooManyRequests)
+		return false
+	}
+	return true
+}
+
+// setRateLimitHeaders adds the Retry-After (0 when not locked out) and X-RateLimit-* headers to the response.
+func setRateLimitHeaders(w http.ResponseWriter, d ratelimit.Decision) {
+	h := w.Header()
+	h.Set("Retry-After", strconv.Itoa(int(math.Ceil(d.RetryAfter.Seconds()))))
+	h.Set("X-RateLimit-Limit", strconv.Itoa(d.Limit))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(d.Remaining))
+	h.Set("X-RateLimit-Reset", strconv.FormatInt(d.Reset.Unix(), 10))
+}
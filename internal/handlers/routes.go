@@ -1,6 +1,9 @@
 package handlers
 
-import "github.com/go-chi/chi/v5"
+import (
+	"connectfour/internal/model"
+	"github.com/go-chi/chi/v5"
+)
 
 func SetupRoutes(r *chi.Mux) {
 	// Create public routes
@@ -8,16 +11,107 @@ func SetupRoutes(r *chi.Mux) {
 		r.Get("/", GreetHandler)             // GET /
 		r.Post("/login", LoginHandler)       // POST /login
 		r.Post("/register", RegisterHandler) // POST /login
+
+		r.Get("/.well-known/jwks.json", JwksHandler) // GET /.well-known/jwks.json
+
+		r.Get("/healthz", HealthzHandler) // GET /healthz
+		r.Get("/readyz", ReadyzHandler)   // GET /readyz
+	})
+
+	r.Route("/password", func(r chi.Router) {
+		r.Post("/forgot", ForgotPasswordHandler) // POST /password/forgot
+		r.Post("/reset", ResetPasswordHandler)   // POST /password/reset
+	})
+
+	r.Route("/oidc", func(r chi.Router) {
+		r.Get("/login", OidcLoginHandler)       // GET /oidc/login?redirect_uri=http://127.0.0.1:1234/callback&state=abc
+		r.Get("/callback", OidcCallbackHandler) // GET /oidc/callback?code=...&state=...
 	})
 
 	// Create routes that need authentication, so they check for the jwt token to be there
 	r.Route("/games", func(r chi.Router) {
-		r.Use(JwtValidation)
-		r.Get("/", OpenGamesHandler)           // GET  /games
-		r.Get("/my", MyGamesHandler)           // GET  /games
-		r.Post("/", NewGameHandler)            // POST /games
-		r.Get("/{key}", GameStateHandler)      // GET  /games/1234abcd
-		r.Post("/{key}/join", JoinGameHandler) // POST /games/1234abcd/join
-		r.Post("/{key}/play", PlayMoveHandler) // POST /games/1234abcd/play
+		r.Use(JwtValidation, TrackPresence)
+		r.Get("/", OpenGamesHandler)             // GET  /games?limit=20&cursor=...
+		r.Get("/my", MyGamesHandler)             // GET  /games/my?status=started&opponent=sanae@evilnerd.nl
+		r.Post("/", NewGameHandler)              // POST /games
+		r.Get("/{key}", GameStateHandler)        // GET  /games/1234abcd
+		r.Post("/{key}/join", JoinGameHandler)   // POST /games/1234abcd/join
+		r.Post("/{key}/play", PlayMoveHandler)   // POST /games/1234abcd/play
+		r.Get("/{key}/turn", WaitForTurnHandler) // GET  /games/1234abcd/turn?wait=30
+	})
+
+	r.Route("/invitations", func(r chi.Router) {
+		r.Use(JwtValidation, TrackPresence)
+		r.Get("/", InvitationsHandler)                     // GET  /invitations
+		r.Post("/{key}/accept", AcceptInvitationHandler)   // POST /invitations/1234abcd/accept
+		r.Post("/{key}/decline", DeclineInvitationHandler) // POST /invitations/1234abcd/decline
+	})
+
+	r.Route("/notifications", func(r chi.Router) {
+		r.Use(JwtValidation, TrackPresence)
+		r.Get("/", NotificationsHandler)                         // GET  /notifications?unread=true
+		r.Post("/read", ReadAllNotificationsHandler)             // POST /notifications/read
+		r.Post("/{id}/read", ReadNotificationHandler)            // POST /notifications/12/read
+		r.Get("/preferences", NotificationPreferencesHandler)    // GET  /notifications/preferences
+		r.Put("/preferences", SetNotificationPreferencesHandler) // PUT  /notifications/preferences
+	})
+
+	r.Route("/webhooks", func(r chi.Router) {
+		r.Use(JwtValidation, TrackPresence)
+		r.Get("/", WebhooksHandler)                         // GET    /webhooks
+		r.Post("/", RegisterWebhookHandler)                 // POST   /webhooks
+		r.Delete("/{id}", DeleteWebhookHandler)             // DELETE /webhooks/3
+		r.Get("/{id}/deliveries", WebhookDeliveriesHandler) // GET    /webhooks/3/deliveries
+	})
+
+	r.Route("/me", func(r chi.Router) {
+		r.Use(JwtValidation, TrackPresence)
+		r.Get("/", ProfileHandler)                      // GET    /me
+		r.Patch("/", UpdateProfileHandler)              // PATCH  /me
+		r.Post("/password", ChangePasswordHandler)      // POST   /me/password
+		r.Post("/tokens/revoke", RevokeMyTokensHandler) // POST   /me/tokens/revoke
+		r.Delete("/", DeleteAccountHandler)             // DELETE /me
+	})
+
+	r.Route("/verify", func(r chi.Router) {
+		r.Use(JwtValidation, TrackPresence)
+		r.Post("/", VerifyHandler)                   // POST /verify
+		r.Post("/resend", ResendVerificationHandler) // POST /verify/resend
+	})
+
+	r.Route("/friends", func(r chi.Router) {
+		r.Use(JwtValidation, TrackPresence)
+		r.Get("/", FriendsHandler)                     // GET    /friends
+		r.Post("/", RequestFriendHandler)              // POST   /friends
+		r.Post("/{email}/accept", AcceptFriendHandler) // POST   /friends/lucy@evilnerd.nl/accept
+		r.Delete("/{email}", RemoveFriendHandler)      // DELETE /friends/lucy@evilnerd.nl
+	})
+
+	r.Route("/tournaments", func(r chi.Router) {
+		r.Use(JwtValidation, TrackPresence)
+		r.Get("/", TournamentsHandler)                         // GET  /tournaments
+		r.Post("/", NewTournamentHandler)                      // POST /tournaments
+		r.Get("/{id}", TournamentHandler)                      // GET  /tournaments/1
+		r.Post("/{id}/register", RegisterForTournamentHandler) // POST /tournaments/1/register
+		r.Post("/{id}/start", StartTournamentHandler)          // POST /tournaments/1/start?rounds=3
+	})
+
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(JwtValidation, RequireRole(model.RoleModerator))
+		r.Get("/users", AdminUsersHandler)               // GET  /admin/users?q=evilnerd
+		r.Get("/users/{email}", AdminUserHandler)        // GET  /admin/users/lucy@evilnerd.nl
+		r.Post("/users/{email}/ban", BanUserHandler)     // POST /admin/users/lucy@evilnerd.nl/ban
+		r.Post("/users/{email}/unban", UnbanUserHandler) // POST /admin/users/lucy@evilnerd.nl/unban
+		r.Get("/games", AdminGamesHandler)               // GET  /admin/games?status=started&player=lucy@evilnerd.nl
+		r.Post("/games/{key}/abort", AbortGameHandler)   // POST /admin/games/1234abcd/abort
+		r.Get("/stats", StatsHandler)                    // GET  /admin/stats
+		r.Get("/audit", AuditHandler)                    // GET  /admin/audit?actor=lucy@evilnerd.nl&since=2024-06-01T00:00:00Z
+
+		r.Group(func(r chi.Router) {
+			r.Use(RequireRole(model.RoleAdmin))
+			r.Put("/users/{email}/role", SetRoleHandler)         // PUT  /admin/users/lucy@evilnerd.nl/role
+			r.Put("/users/{email}/bot", SetBotHandler)           // PUT  /admin/users/lucy@evilnerd.nl/bot
+			r.Post("/users/{email}/revoke", RevokeTokensHandler) // POST /admin/users/lucy@evilnerd.nl/revoke
+		})
 	})
 }
@@ -7,6 +7,6 @@ import (
 )
 
 func GreetHandler(response http.ResponseWriter, request *http.Request) {
-	log.Debug("Sending a greeting")
+	log.WithContext(request.Context()).Debug("Sending a greeting")
 	response.Write([]byte("Let's play a game."))
 }
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"connectfour/internal/service"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+)
+
+func TournamentsHandler(response http.ResponseWriter, request *http.Request) {
+	log.WithContext(request.Context()).Debug("Listing all tournaments")
+	marshal(tournamentService.List(request.Context()), response)
+}
+
+func TournamentHandler(response http.ResponseWriter, request *http.Request) {
+	if id, ok := parseTournamentId(response, request); ok {
+		t, err := tournamentService.Get(request.Context(), id)
+		if handleError(err, response) {
+			marshal(t, response)
+		}
+	}
+}
+
+func NewTournamentHandler(response http.ResponseWriter, request *http.Request) {
+	if req, ok := unmarshal[service.NewTournamentRequest](response, request); ok {
+		email := emailFromContext(request)
+		t, err := tournamentService.Create(request.Context(), email, req.Name, req.Format)
+		if handleError(err, response) {
+			marshal(t, response)
+		}
+	}
+}
+
+func RegisterForTournamentHandler(response http.ResponseWriter, request *http.Request) {
+	if id, ok := parseTournamentId(response, request); ok {
+		email := emailFromContext(request)
+		t, err := tournamentService.Register(request.Context(), id, email)
+		if handleError(err, response) {
+			marshal(t, response)
+		}
+	}
+}
+
+// StartTournamentHandler starts the tournament. The optional 'rounds' query parameter sets the number of rounds
+// of a Swiss tournament.
+func StartTournamentHandler(response http.ResponseWriter, request *http.Request) {
+	id, ok := parseTournamentId(response, request)
+	if !ok {
+		return
+	}
+	rounds := 0
+	if value := request.URL.Query().Get("rounds"); value != "" {
+		var err error
+		if rounds, err = strconv.Atoi(value); err != nil || rounds < 0 {
+			handleError(errors.New("the rounds parameter should be a positive number"), response)
+			return
+		}
+	}
+	email := emailFromContext(request)
+	t, err := tournamentService.Start(request.Context(), id, email, rounds)
+	if handleError(err, response) {
+		marshal(t, response)
+	}
+}
+
+func parseTournamentId(response http.ResponseWriter, request *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(request, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, handleError(errors.New("the tournament id in the uri is not valid"), response)
+	}
+	return id, true
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"connectfour/internal/model"
+	"connectfour/internal/service"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+func VerifyHandler(response http.ResponseWriter, request *http.Request) {
+	if req, ok := unmarshal[service.VerifyRequest](response, request); ok {
+		err := verificationService.Verify(request.Context(), emailFromContext(request), req.Code)
+		if errors.As(err, &service.InvalidVerificationCodeError{}) {
+			audit(request, model.AuditVerifyFailed, "", "", err.Error())
+			errorResponse(response, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if handleError(err, response) {
+			audit(request, model.AuditVerify, "", "", "")
+			marshal(map[string]string{"message": "Your e-mail address is verified"}, response)
+		}
+	}
+}
+
+// ResendVerificationHandler mails a new verification code. It responds with 429 and a Retry-After header when the
+// user asks too often.
+func ResendVerificationHandler(response http.ResponseWriter, request *http.Request) {
+	err := verificationService.Resend(request.Context(), emailFromContext(request))
+	var tooMany service.TooManyRequestsError
+	if errors.As(err, &tooMany) {
+		response.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(tooMany.RetryAfter.Seconds()))))
+		errorResponse(response, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	if handleError(err, response) {
+		marshal(map[string]string{"message": "A new verification code was sent"}, response)
+	}
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"connectfour/internal/service"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+)
+
+func WebhooksHandler(response http.ResponseWriter, request *http.Request) {
+	log.WithContext(request.Context()).Debug("Listing my webhooks")
+	webhooks, err := webhookService.List(request.Context(), emailFromContext(request))
+	if handleError(err, response) {
+		marshal(webhooks, response)
+	}
+}
+
+// RegisterWebhookHandler returns the new webhook with its secret, which is only returned this once.
+func RegisterWebhookHandler(response http.ResponseWriter, request *http.Request) {
+	if req, ok := unmarshal[service.WebhookRequest](response, request); ok {
+		webhook, err := webhookService.Register(request.Context(), emailFromContext(request), req)
+		if handleError(err, response) {
+			response.Header().Set("Content-Type", "application/json")
+			response.WriteHeader(http.StatusCreated)
+			marshal(webhook, response)
+		}
+	}
+}
+
+func DeleteWebhookHandler(response http.ResponseWriter, request *http.Request) {
+	id, ok := webhookId(response, request)
+	if !ok {
+		return
+	}
+	if handleError(webhookService.Delete(request.Context(), emailFromContext(request), id), response) {
+		WebhooksHandler(response, request)
+	}
+}
+
+// WebhookDeliveriesHandler returns the delivery log of the webhook, the newest deliveries first.
+func WebhookDeliveriesHandler(response http.ResponseWriter, request *http.Request) {
+	id, ok := webhookId(response, request)
+	if !ok {
+		return
+	}
+	deliveries, err := webhookService.Deliveries(request.Context(), emailFromContext(request), id)
+	if handleError(err, response) {
+		marshal(deliveries, response)
+	}
+}
+
+func webhookId(response http.ResponseWriter, request *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(request, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, handleError(errors.New("the webhook id in the uri is not valid"), response)
+	}
+	return id, true
+}
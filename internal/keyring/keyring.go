@@ -0,0 +1,157 @@
+// Package keyring signs and verifies the JWTs of the api with asymmetric keys. Every token carries the id of its key
+// in the kid header, so keys can be rotated: a new key signs the new tokens, while the previous keys keep verifying
+// the tokens they signed until those have expired. The public keys are published as a JSON Web Key Set, so other
+// services can verify the tokens themselves.
+package keyring
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/golang-jwt/jwt/v5"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Key is a private key of the ring, with the algorithm that it signs with.
+type Key struct {
+	Id       string
+	Signer   crypto.Signer // an *rsa.PrivateKey (RS256) or ed25519.PrivateKey (EdDSA).
+	NotAfter time.Time     // when the key stops verifying tokens, zero to keep verifying until it's removed.
+}
+
+// Method returns the signing method of the key, or nil when the type of key isn't supported.
+func (k Key) Method() jwt.SigningMethod {
+	switch k.Signer.(type) {
+	case *rsa.PrivateKey:
+		return jwt.SigningMethodRS256
+	case ed25519.PrivateKey:
+		return jwt.SigningMethodEdDSA
+	}
+	return nil
+}
+
+// KeyRing holds the key that signs new tokens, and all keys that still verify tokens.
+type KeyRing struct {
+	mu     *sync.RWMutex
+	active string
+	keys   map[string]Key
+	now    func() time.Time
+}
+
+func NewKeyRing() *KeyRing {
+	return &KeyRing{
+		mu:   &sync.RWMutex{},
+		keys: make(map[string]Key),
+		now:  time.Now,
+	}
+}
+
+// Add adds a key that verifies tokens. When activate is set, it also signs the new tokens from now on.
+func (k *KeyRing) Add(key Key, activate bool) error {
+	if key.Id == "" {
+		return errors.New("a key needs an id")
+	}
+	if key.Method() == nil {
+		return fmt.Errorf("key %s: only RSA and Ed25519 keys are supported, not %T", key.Id, key.Signer)
+	}
+	if rsaKey, ok := key.Signer.(*rsa.PrivateKey); ok && rsaKey.N.BitLen() < 2048 {
+		return fmt.Errorf("key %s: RSA keys must have at least 2048 bits", key.Id)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys[key.Id] = key
+	if activate {
+		k.active = key.Id
+	}
+	return nil
+}
+
+// Rotate makes the key sign the new tokens. The key that signed them before keeps verifying tokens for the overlap,
+// which should be at least the lifetime of the tokens.
+func (k *KeyRing) Rotate(key Key, overlap time.Duration) error {
+	k.mu.Lock()
+	if previous, ok := k.keys[k.active]; ok && key.Id != previous.Id {
+		previous.NotAfter = k.now().Add(overlap)
+		k.keys[previous.Id] = previous
+	}
+	k.mu.Unlock()
+	return k.Add(key, true)
+}
+
+// Sign signs the claims with the active key.
+func (k *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	k.mu.RLock()
+	key, ok := k.keys[k.active]
+	k.mu.RUnlock()
+	if !ok {
+		return "", errors.New("there is no active key to sign with")
+	}
+
+	token := jwt.NewWithClaims(key.Method(), claims)
+	token.Header["kid"] = key.Id
+	return token.SignedString(key.Signer)
+}
+
+// Parse verifies the token and reads its claims. The token must name one of the keys in its kid header, and must be
+// signed with the algorithm of that key; other algorithms (like HS256 or none) are refused. The expiration time is
+// required.
+func (k *KeyRing) Parse(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, claims, k.publicKey,
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(k.now))
+}
+
+func (k *KeyRing) publicKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	k.mu.RLock()
+	key, ok := k.keys[kid]
+	k.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if !key.NotAfter.IsZero() && k.now().After(key.NotAfter) {
+		return nil, fmt.Errorf("key %s is retired", kid)
+	}
+	if token.Method.Alg() != key.Method().Alg() {
+		return nil, fmt.Errorf("key %s signs with %s, not %s", kid, key.Method().Alg(), token.Method.Alg())
+	}
+	return key.Signer.Public(), nil
+}
+
+// Jwks returns the public keys that verify tokens, as a JSON Web Key Set.
+func (k *KeyRing) Jwks() jose.JSONWebKeySet {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	set := jose.JSONWebKeySet{Keys: make([]jose.JSONWebKey, 0, len(k.keys))}
+	for _, key := range k.keys {
+		if !key.NotAfter.IsZero() && k.now().After(key.NotAfter) {
+			continue
+		}
+		set.Keys = append(set.Keys, jose.JSONWebKey{
+			Key:       key.Signer.Public(),
+			KeyID:     key.Id,
+			Algorithm: key.Method().Alg(),
+			Use:       "sig",
+		})
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].KeyID < set.Keys[j].KeyID })
+	return set
+}
+
+// GenerateKey generates an Ed25519 key, e.g. for a key ring that only lives as long as the process.
+func GenerateKey(id string) (Key, error) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return Key{}, err
+	}
+	return Key{Id: id, Signer: private}, nil
+}
@@ -0,0 +1,172 @@
+package keyring
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func claims(lifetime time.Duration) jwt.MapClaims {
+	return jwt.MapClaims{
+		"email": "dick@evilnerd.nl",
+		"exp":   time.Now().Add(lifetime).Unix(),
+	}
+}
+
+func newRing(t *testing.T, id string) (*KeyRing, Key) {
+	key, err := GenerateKey(id)
+	assert.NoError(t, err)
+	ring := NewKeyRing()
+	assert.NoError(t, ring.Add(key, true))
+	return ring, key
+}
+
+func TestKeyRing_SignAndParse(t *testing.T) {
+	// Arrange
+	ring, _ := newRing(t, "k1")
+
+	// Act
+	tokenString, err := ring.Sign(claims(time.Hour))
+	assert.NoError(t, err)
+	var parsed jwt.MapClaims
+	token, err := ring.Parse(tokenString, &parsed)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "k1", token.Header["kid"])
+	assert.Equal(t, "EdDSA", token.Method.Alg())
+	assert.Equal(t, "dick@evilnerd.nl", parsed["email"])
+}
+
+func TestKeyRing_Parse_RefusesOtherAlgorithms(t *testing.T) {
+	// Arrange
+	ring, key := newRing(t, "k1")
+	public, _ := x509.MarshalPKIXPublicKey(key.Signer.Public())
+
+	hs := jwt.NewWithClaims(jwt.SigningMethodHS256, claims(time.Hour))
+	hs.Header["kid"] = "k1"
+	hsString, _ := hs.SignedString(public)
+
+	none := jwt.NewWithClaims(jwt.SigningMethodNone, claims(time.Hour))
+	none.Header["kid"] = "k1"
+	noneString, _ := none.SignedString(jwt.UnsafeAllowNoneSignatureType)
+
+	// Act
+	_, errHs := ring.Parse(hsString, &jwt.MapClaims{})
+	_, errNone := ring.Parse(noneString, &jwt.MapClaims{})
+
+	// Assert
+	assert.Error(t, errHs, "Expected a token signed with HS256 and the public key to be refused")
+	assert.Error(t, errNone, "Expected an unsigned token to be refused")
+}
+
+func TestKeyRing_Parse_RefusesUnknownKeysAndExpiredTokens(t *testing.T) {
+	// Arrange
+	ring, _ := newRing(t, "k1")
+	other, _ := newRing(t, "k2")
+	foreign, _ := other.Sign(claims(time.Hour))
+	expired, _ := ring.Sign(claims(-time.Minute))
+	noExp, _ := ring.Sign(jwt.MapClaims{"email": "dick@evilnerd.nl"})
+
+	// Act
+	_, errForeign := ring.Parse(foreign, &jwt.MapClaims{})
+	_, errExpired := ring.Parse(expired, &jwt.MapClaims{})
+	_, errNoExp := ring.Parse(noExp, &jwt.MapClaims{})
+
+	// Assert
+	assert.Error(t, errForeign)
+	assert.Error(t, errExpired)
+	assert.Error(t, errNoExp, "Expected the expiration time to be required")
+}
+
+func TestKeyRing_Rotate(t *testing.T) {
+	// Arrange
+	ring, _ := newRing(t, "k1")
+	now := time.Now()
+	ring.now = func() time.Time { return now }
+	old, _ := ring.Sign(claims(3 * time.Hour))
+	next, _ := GenerateKey("k2")
+
+	// Act
+	assert.NoError(t, ring.Rotate(next, 2*time.Hour))
+	rotated, _ := ring.Sign(claims(3 * time.Hour))
+	_, errOld := ring.Parse(old, &jwt.MapClaims{})
+	_, errRotated := ring.Parse(rotated, &jwt.MapClaims{})
+	keysDuringOverlap := len(ring.Jwks().Keys)
+
+	now = now.Add(2*time.Hour + time.Second)
+	_, errOldRetired := ring.Parse(old, &jwt.MapClaims{})
+	keysAfterOverlap := ring.Jwks().Keys
+
+	// Assert
+	assert.NoError(t, errOld, "Expected the previous key to verify tokens during the overlap")
+	assert.NoError(t, errRotated)
+	assert.Equal(t, 2, keysDuringOverlap)
+	assert.Error(t, errOldRetired, "Expected the previous key to be retired after the overlap")
+	assert.Len(t, keysAfterOverlap, 1)
+	assert.Equal(t, "k2", keysAfterOverlap[0].KeyID)
+}
+
+func TestLoadKeyRing(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	ed, _ := GenerateKey("")
+	edDer, _ := x509.MarshalPKCS8PrivateKey(ed.Signer)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "2024-01.pem"),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: edDer}), 0600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "2024-06.pem"),
+		pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}), 0600))
+
+	// Act
+	ring, err := LoadKeyRing(dir, "", time.Hour)
+	pinned, errPinned := LoadKeyRing(dir, "2024-01", time.Hour)
+	_, errMissing := LoadKeyRing(dir, "2023-01", time.Hour)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.NoError(t, errPinned)
+	assert.Error(t, errMissing)
+
+	tokenString, _ := ring.Sign(claims(time.Hour))
+	token, err := pinned.Parse(tokenString, &jwt.MapClaims{})
+	assert.NoError(t, err, "Expected the keys that aren't active to verify tokens too")
+	assert.Equal(t, "2024-06", token.Header["kid"])
+	assert.Equal(t, "RS256", token.Method.Alg())
+}
+
+func TestLoadKeyRing_RetiresOldKeys(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	for _, id := range []string{"2023-01", "2024-01", "2024-06", "2025-01"} {
+		key, _ := GenerateKey(id)
+		der, _ := x509.MarshalPKCS8PrivateKey(key.Signer)
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, id+".pem"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+	}
+	activated := time.Now().Add(-48 * time.Hour)
+	assert.NoError(t, os.Chtimes(filepath.Join(dir, "2024-06.pem"), activated, activated))
+	notAfter := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "2024-01.notafter"), []byte(notAfter.Format(time.RFC3339)+"\n"), 0600))
+
+	// Act
+	ring, err := LoadKeyRing(dir, "2024-06", 24*time.Hour)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, activated.Add(24*time.Hour).Unix(), ring.keys["2023-01"].NotAfter.Unix(), "Expected the overlap to start when the active key was added")
+	assert.True(t, notAfter.Equal(ring.keys["2024-01"].NotAfter), "Expected the .notafter file to set the retirement")
+	assert.True(t, ring.keys["2024-06"].NotAfter.IsZero(), "Expected the active key not to retire")
+	assert.True(t, ring.keys["2025-01"].NotAfter.IsZero(), "Expected the next key not to retire")
+	kids := make([]string, 0)
+	for _, key := range ring.Jwks().Keys {
+		kids = append(kids, key.KeyID)
+	}
+	assert.ElementsMatch(t, []string{"2024-01", "2024-06", "2025-01"}, kids, "Expected the retired key not to be published")
+}
@@ -0,0 +1,109 @@
+package keyring
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// LoadKeyRing reads the PEM encoded private keys (PKCS #8, or PKCS #1 for RSA) in the *.pem files of the directory.
+// The id of a key is its file name without the extension. The active key is the one with the given id, or the last
+// one in alphabetical order when it's empty, so naming the files by date (like 2024-06.pem) activates the newest.
+//
+// The keys before the active one no longer sign tokens, so they retire once the overlap has passed since the active
+// key was added, which is the modification time of its file. A key retires at another time when there's a
+// <id>.notafter file next to it, with an RFC 3339 time. The keys after the active one are kept for the next rotation.
+func LoadKeyRing(dir string, active string, overlap time.Duration) (*KeyRing, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("there are no *.pem keys in %s", dir)
+	}
+	sort.Strings(files)
+	if active == "" {
+		active = keyId(files[len(files)-1])
+	}
+	info, err := os.Stat(filepath.Join(dir, active+".pem"))
+	if err != nil {
+		return nil, fmt.Errorf("the active key %s isn't in %s", active, dir)
+	}
+	retiredAt := info.ModTime().Add(overlap)
+
+	ring := NewKeyRing()
+	for _, file := range files {
+		id := keyId(file)
+		signer, err := readPrivateKey(file)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", id, err)
+		}
+		key := Key{Id: id, Signer: signer}
+		if id < active {
+			key.NotAfter = retiredAt
+		}
+		if id != active {
+			if key.NotAfter, err = readNotAfter(file, key.NotAfter); err != nil {
+				return nil, fmt.Errorf("key %s: %w", id, err)
+			}
+		}
+		if err = ring.Add(key, id == active); err != nil {
+			return nil, err
+		}
+	}
+	return ring, nil
+}
+
+func keyId(file string) string {
+	return strings.TrimSuffix(filepath.Base(file), ".pem")
+}
+
+// readNotAfter reads the time that the key in the file retires at from the .notafter file next to it. Without that
+// file, the key retires at the fallback.
+func readNotAfter(file string, fallback time.Time) (time.Time, error) {
+	b, err := os.ReadFile(strings.TrimSuffix(file, ".pem") + ".notafter")
+	if errors.Is(err, fs.ErrNotExist) {
+		return fallback, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339, strings.TrimSpace(string(b)))
+}
+
+func readPrivateKey(file string) (crypto.Signer, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	var key any
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+	return signer, nil
+}
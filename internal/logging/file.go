@@ -0,0 +1,35 @@
+package logging
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// LogToFile sends the output of the standard log package to the file as JSON lines, at the level that
+// CONNECT_FOUR_LOG_LEVEL sets (info by default). The console clients use it instead of tea.LogToFile, because
+// their screen belongs to the game. The secrets are redacted like in the logs of the api.
+func LogToFile(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	var level slog.Level
+	if err = level.UnmarshalText([]byte(os.Getenv("CONNECT_FOUR_LOG_LEVEL"))); err != nil {
+		level = slog.LevelInfo
+	}
+	slog.SetDefault(slog.New(slog.NewJSONHandler(file, &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			switch {
+			case attr.Key == slog.MessageKey:
+				attr.Value = slog.StringValue(Redact(strings.TrimSpace(attr.Value.String())))
+			case sensitive(attr.Key):
+				attr.Value = slog.StringValue(redacted)
+			}
+			return attr
+		},
+	})))
+	return file, nil
+}
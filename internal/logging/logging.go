@@ -0,0 +1,182 @@
+// Package logging sets up the structured logs of the api. The lines that are logged with the context of a request
+// (log.WithContext(ctx)) carry its request id, route, user, game key and trace id, so they can be tied together.
+package logging
+
+import (
+	"context"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const redacted = "[REDACTED]"
+
+var (
+	// sensitiveFields are the (parts of) field names whose values are never logged.
+	sensitiveFields = []string{"password", "authorization", "token", "secret", "jwt", "cookie"}
+
+	// sensitiveValues finds the secrets in messages, like the bearer token of an Authorization header.
+	sensitiveValues = []*regexp.Regexp{
+		regexp.MustCompile(`(Bearer\s+)[\w\-.~+/]{16,}=*`),
+		regexp.MustCompile(`(?i)("?(?:password|new_password|old_password|token)"?\s*[:=]\s*)("[^"]*"|\S+)`),
+	}
+
+	// quietRoutes are requested every few seconds by the orchestrator and Prometheus, so they're logged at debug.
+	quietRoutes = map[string]bool{"/healthz": true, "/readyz": true, "/metrics": true}
+)
+
+// Setup configures the standard logrus logger. CONNECT_FOUR_LOG_LEVEL sets the level (info by default) and
+// CONNECT_FOUR_LOG_FORMAT chooses between json (the default) and text.
+func Setup() {
+	if strings.ToLower(os.Getenv("CONNECT_FOUR_LOG_FORMAT")) == "text" {
+		log.SetFormatter(&log.TextFormatter{FullTimestamp: true})
+	} else {
+		log.SetFormatter(&log.JSONFormatter{TimestampFormat: time.RFC3339Nano})
+	}
+	log.AddHook(hook{})
+
+	log.SetLevel(log.InfoLevel)
+	if s, ok := os.LookupEnv("CONNECT_FOUR_LOG_LEVEL"); ok {
+		if level, err := log.ParseLevel(s); err == nil {
+			log.SetLevel(level)
+		} else {
+			log.Warnf("Ignoring CONNECT_FOUR_LOG_LEVEL=%s, use debug, info, warn or error", s)
+		}
+	}
+}
+
+// Redact replaces the secrets in the message, like bearer tokens and passwords.
+func Redact(message string) string {
+	for _, re := range sensitiveValues {
+		message = re.ReplaceAllString(message, "${1}"+redacted)
+	}
+	return message
+}
+
+func sensitive(field string) bool {
+	field = strings.ToLower(field)
+	for _, s := range sensitiveFields {
+		if strings.Contains(field, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// hook adds the fields of the request to the lines that are logged with its context, and redacts the secrets.
+type hook struct{}
+
+func (hook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (hook) Fire(entry *log.Entry) error {
+	if entry.Context != nil {
+		for key, value := range contextFields(entry.Context) {
+			if _, ok := entry.Data[key]; !ok {
+				entry.Data[key] = value
+			}
+		}
+	}
+	for key := range entry.Data {
+		if sensitive(key) {
+			entry.Data[key] = redacted
+		}
+	}
+	entry.Message = Redact(entry.Message)
+	return nil
+}
+
+func contextFields(ctx context.Context) log.Fields {
+	fields := log.Fields{}
+	if id := middleware.GetReqID(ctx); id != "" {
+		fields["request_id"] = id
+	}
+	if rctx := chi.RouteContext(ctx); rctx != nil {
+		if route := rctx.RoutePattern(); route != "" {
+			fields["route"] = route
+		}
+		if key := rctx.URLParam("key"); key != "" {
+			fields["game_key"] = key
+		}
+	}
+	if email := userOf(ctx); email != "" {
+		fields["email"] = email
+	}
+	if span := trace.SpanContextFromContext(ctx); span.HasTraceID() {
+		fields["trace_id"] = span.TraceID().String()
+	}
+	return fields
+}
+
+// request keeps the user of a request, who is only known after the JwtValidation, for the access log line.
+type request struct {
+	mu    sync.Mutex
+	email string
+}
+
+type requestKey struct{}
+
+// Identify ties the user to the request that ctx belongs to, so that its access log line names the user too.
+func Identify(ctx context.Context, email string) {
+	if req, ok := ctx.Value(requestKey{}).(*request); ok {
+		req.mu.Lock()
+		req.email = email
+		req.mu.Unlock()
+	}
+}
+
+func userOf(ctx context.Context) string {
+	if email, ok := ctx.Value("email").(string); ok && email != "" {
+		return email
+	}
+	if req, ok := ctx.Value(requestKey{}).(*request); ok {
+		req.mu.Lock()
+		defer req.mu.Unlock()
+		return req.email
+	}
+	return ""
+}
+
+// Middleware logs a line for every request, with its status, size and latency. It replaces chi's middleware.Logger,
+// and must come after middleware.RequestID. The request id is returned in the X-Request-Id header, so that a client
+// can point out the lines of its request.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		if id := middleware.GetReqID(r.Context()); id != "" {
+			w.Header().Set(middleware.RequestIDHeader, id)
+		}
+		ctx := context.WithValue(r.Context(), requestKey{}, &request{})
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		entry := log.WithContext(ctx).WithFields(log.Fields{
+			"method":     r.Method,
+			"path":       r.URL.Path,
+			"status":     status,
+			"bytes":      ww.BytesWritten(),
+			"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
+			"remote":     r.RemoteAddr,
+		})
+		switch {
+		case status >= http.StatusInternalServerError:
+			entry.Error("request")
+		case quietRoutes[r.URL.Path]:
+			entry.Debug("request")
+		default:
+			entry.Info("request")
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package mail
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// FileMailer writes every message to a separate .eml file in a directory, so it can be read without a mail server.
+type FileMailer struct {
+	dir  string
+	from string
+	seq  *atomic.Int64 // keeps the file names unique when messages are sent within the same nanosecond.
+}
+
+var _ Mailer = FileMailer{}
+
+func NewFileMailer(dir string, from string) FileMailer {
+	return FileMailer{
+		dir:  dir,
+		from: from,
+		seq:  &atomic.Int64{},
+	}
+}
+
+func (m FileMailer) Send(msg Message) error {
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d-%d-%s.eml", time.Now().UnixNano(), m.seq.Add(1), safeFileName(msg.To))
+	path := filepath.Join(m.dir, name)
+	if err := os.WriteFile(path, format(m.from, msg), 0o644); err != nil {
+		log.Errorf("Error writing e-mail '%s' to %s: %v", msg.Subject, path, err)
+		return err
+	}
+	log.Debugf("Wrote e-mail '%s' for %s to %s", msg.Subject, msg.To, path)
+	return nil
+}
+
+func safeFileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' {
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+// LogMailer writes the messages to the log.
+type LogMailer struct {
+	from string
+}
+
+var _ Mailer = LogMailer{}
+
+func NewLogMailer(from string) LogMailer {
+	return LogMailer{from: from}
+}
+
+func (m LogMailer) Send(msg Message) error {
+	log.Infof("E-mail from %s to %s\nSubject: %s\n\n%s", m.from, msg.To, msg.Subject, msg.Body)
+	return nil
+}
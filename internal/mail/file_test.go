@@ -0,0 +1,32 @@
+package mail
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileMailer_Send(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	m := NewFileMailer(dir, defaultFrom)
+
+	// Act
+	err1 := m.Send(Message{To: "dick@evilnerd.nl", Subject: "Hello", Body: "Line 1\nLine 2"})
+	err2 := m.Send(Message{To: "dick@evilnerd.nl", Subject: "Hello again", Body: "Line 3"})
+
+	// Assert
+	assert.NoError(t, err1)
+	assert.NoError(t, err2)
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	assert.Len(t, files, 2, "Expected a file for every message")
+
+	b, _ := os.ReadFile(files[0])
+	contents := string(b)
+	assert.True(t, strings.HasPrefix(contents, "From: "+defaultFrom+"\r\n"))
+	assert.Contains(t, contents, "To: dick@evilnerd.nl\r\n")
+	assert.Contains(t, contents, "Subject: Hello\r\n")
+	assert.Contains(t, contents, "\r\n\r\nLine 1\r\nLine 2")
+}
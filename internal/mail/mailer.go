@@ -0,0 +1,68 @@
+package mail
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"strings"
+)
+
+const defaultFrom = "ConnectFour <noreply@connectfour.local>"
+
+// Message is a plain text e-mail message.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends e-mail messages. Use NewMailerFromEnv to get the mailer that's configured for this environment.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// NewMailerFromEnv returns an SmtpMailer when CONNECT_FOUR_SMTP_ADDRESS is set. Otherwise, the messages are written
+// to the directory in CONNECT_FOUR_MAIL_DIR, or to the log when that isn't set either (for local development).
+func NewMailerFromEnv() Mailer {
+	from, ok := os.LookupEnv("CONNECT_FOUR_MAIL_FROM")
+	if !ok {
+		from = defaultFrom
+	}
+
+	if address, ok := os.LookupEnv("CONNECT_FOUR_SMTP_ADDRESS"); ok {
+		log.Infof("Sending e-mail through SMTP server %s", address)
+		return NewSmtpMailer(address, os.Getenv("CONNECT_FOUR_SMTP_USER"), smtpPassword(), from)
+	}
+	if dir, ok := os.LookupEnv("CONNECT_FOUR_MAIL_DIR"); ok {
+		log.Infof("Writing e-mail to directory %s", dir)
+		return NewFileMailer(dir, from)
+	}
+	log.Infoln("No mail configuration found, e-mail is written to the log. Use CONNECT_FOUR_SMTP_ADDRESS to send it.")
+	return NewLogMailer(from)
+}
+
+// smtpPassword reads the SMTP password from the Docker secret in CONNECT_FOUR_SMTP_PASSWORD_FILE, if it's set.
+func smtpPassword() string {
+	file, ok := os.LookupEnv("CONNECT_FOUR_SMTP_PASSWORD_FILE")
+	if !ok {
+		return ""
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		log.Fatalf("Reading secret: %v\n", err)
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// format returns the message including its headers, as it's sent over SMTP or written to a file.
+func format(from string, msg Message) []byte {
+	b := strings.Builder{}
+	b.WriteString(fmt.Sprintf("From: %s\r\n", from))
+	b.WriteString(fmt.Sprintf("To: %s\r\n", msg.To))
+	b.WriteString(fmt.Sprintf("Subject: %s\r\n", msg.Subject))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
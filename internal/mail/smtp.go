@@ -0,0 +1,48 @@
+package mail
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"net/mail"
+	"net/smtp"
+)
+
+// SmtpMailer sends the messages through an SMTP server, authenticating with PLAIN auth when a user is given.
+type SmtpMailer struct {
+	address  string // host:port
+	user     string
+	password string
+	from     string
+}
+
+var _ Mailer = SmtpMailer{}
+
+func NewSmtpMailer(address string, user string, password string, from string) SmtpMailer {
+	return SmtpMailer{
+		address:  address,
+		user:     user,
+		password: password,
+		from:     from,
+	}
+}
+
+func (m SmtpMailer) Send(msg Message) error {
+	sender, err := mail.ParseAddress(m.from)
+	if err != nil {
+		return fmt.Errorf("invalid sender address %s: %w", m.from, err)
+	}
+
+	var auth smtp.Auth
+	if m.user != "" {
+		host, _, _ := net.SplitHostPort(m.address)
+		auth = smtp.PlainAuth("", m.user, m.password, host)
+	}
+
+	if err = smtp.SendMail(m.address, auth, sender.Address, []string{msg.To}, format(m.from, msg)); err != nil {
+		log.Errorf("Error sending e-mail '%s' to %s: %v", msg.Subject, msg.To, err)
+		return err
+	}
+	log.Debugf("Sent e-mail '%s' to %s", msg.Subject, msg.To)
+	return nil
+}
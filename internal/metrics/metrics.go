@@ -0,0 +1,182 @@
+// Package metrics exports the telemetry of the api in the Prometheus exposition format, on /metrics.
+package metrics
+
+import (
+	"connectfour/internal/model"
+	"context"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const namespace = "connectfour"
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route pattern and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time to handle HTTP requests, by method and route pattern.",
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"method", "route"})
+
+	dbQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Time taken by the database queries, by repository and method.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"repository", "method"})
+
+	gamesCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "games_created_total",
+		Help:      "Games that were created.",
+	})
+
+	movesPlayed = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "moves_played_total",
+		Help:      "Moves that were played.",
+	})
+
+	gamesFinished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "games_finished_total",
+		Help:      "Games that finished, by outcome: player1, player2, draw or aborted.",
+	}, []string{"outcome"})
+
+	cacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_lookups_total",
+		Help:      "Lookups in the in-memory caches, by cache and result: hit or miss.",
+	}, []string{"cache", "result"})
+
+	cacheEvictions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_evictions_total",
+		Help:      "Values that were evicted from the in-memory caches, by cache and reason: capacity or expired.",
+	}, []string{"cache", "reason"})
+
+	webhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Attempts to post a game event to a webhook, by result: delivered, retried, failed or dropped.",
+	}, []string{"result"})
+)
+
+// Handler serves the metrics.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Middleware counts and times the requests by their chi route pattern (like /games/{key}/play), so that the
+// number of series doesn't grow with every game key. It must be used on the router, before the routes.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		httpRequests.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+		httpDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// ObserveQuery starts timing a query of the repository method. Call the returned func when the query is done.
+func ObserveQuery(repository string, method string) func() {
+	start := time.Now()
+	return func() {
+		dbQueryDuration.WithLabelValues(repository, method).Observe(time.Since(start).Seconds())
+	}
+}
+
+func GameCreated() {
+	gamesCreated.Inc()
+}
+
+func MovePlayed() {
+	movesPlayed.Inc()
+}
+
+// GameFinished counts the outcome of the game, which has either finished or was aborted.
+func GameFinished(game model.Game) {
+	gamesFinished.WithLabelValues(game.Outcome()).Inc()
+}
+
+// CacheLookup counts a hit or a miss of the cache.
+func CacheLookup(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheLookups.WithLabelValues(cache, result).Inc()
+}
+
+// CacheEviction counts a value that was evicted from the cache, to make room or because it expired.
+func CacheEviction(cache string, reason string) {
+	cacheEvictions.WithLabelValues(cache, reason).Inc()
+}
+
+// WebhookDelivery counts an attempt to post to a webhook. It's retried when it may succeed later, and dropped when
+// the queue was full.
+func WebhookDelivery(result string) {
+	webhookDeliveries.WithLabelValues(result).Inc()
+}
+
+// RegisterActiveUsers exports the number of users that are online, as counted when the metrics are scraped.
+func RegisterActiveUsers(online func() int) {
+	prometheus.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_users",
+		Help:      "Users that were active on this api instance in the last 2 minutes.",
+	}, func() float64 { return float64(online()) }))
+}
+
+// RegisterGamesByStatus exports the number of games per status, which are counted in the database when the metrics
+// are scraped.
+func RegisterGamesByStatus(count func(ctx context.Context) (map[model.GameStatus]int, error)) {
+	prometheus.MustRegister(&gamesByStatus{
+		count: count,
+		desc: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "games"),
+			"Games in the database, by status.", []string{"status"}, nil),
+	})
+}
+
+type gamesByStatus struct {
+	count func(ctx context.Context) (map[model.GameStatus]int, error)
+	desc  *prometheus.Desc
+}
+
+func (c *gamesByStatus) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *gamesByStatus) Collect(ch chan<- prometheus.Metric) {
+	counts, err := c.count(context.Background())
+	if err != nil {
+		log.Warnf("Counting the games for the metrics: %v", err)
+		return
+	}
+	for _, status := range []model.GameStatus{model.Created, model.Started, model.Finished, model.Aborted} {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(counts[status]), string(status))
+	}
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"connectfour/internal/model"
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddleware_CountsByRoutePattern(t *testing.T) {
+	// Arrange
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/games/{key}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	before := testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/games/{key}", "404"))
+
+	// Act
+	for _, key := range []string{"sals-nil-abri", "dolk-mop-rits"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/games/"+key, nil))
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/nothing/here", nil))
+
+	// Assert
+	assert.Equal(t, before+2, testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/games/{key}", "404")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(httpRequests.WithLabelValues("GET", "unmatched", "404")))
+}
+
+func TestGameFinished_CountsTheOutcome(t *testing.T) {
+	// Arrange
+	player1 := model.User{Id: 1, Email: "dick@evilnerd.nl"}
+	player2 := model.User{Id: 2, Email: "sanae@evilnerd.nl"}
+	won := model.NewGame(player1, true)
+	_ = won.Join(player2)
+	for _, column := range []int{1, 2, 1, 2, 1, 2, 1} {
+		_ = won.Play(*won.CurrentPlayer(), column)
+	}
+	aborted := model.NewGame(player1, true)
+	_ = aborted.Abort()
+
+	// Act
+	GameFinished(won)
+	GameFinished(aborted)
+
+	// Assert
+	assert.Equal(t, 1.0, testutil.ToFloat64(gamesFinished.WithLabelValues("player1")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(gamesFinished.WithLabelValues("aborted")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(gamesFinished.WithLabelValues("draw")))
+}
+
+func TestCacheLookup(t *testing.T) {
+	// Act
+	CacheLookup("test", true)
+	CacheLookup("test", true)
+	CacheLookup("test", false)
+
+	// Assert
+	assert.Equal(t, 2.0, testutil.ToFloat64(cacheLookups.WithLabelValues("test", "hit")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(cacheLookups.WithLabelValues("test", "miss")))
+}
@@ -0,0 +1,52 @@
+package model
+
+import "time"
+
+type AuditAction string
+
+const (
+	AuditRegister          AuditAction = "user.register"
+	AuditRegisterFailed    AuditAction = "user.register_failed"
+	AuditLogin             AuditAction = "user.login"
+	AuditLoginFailed       AuditAction = "user.login_failed"
+	AuditTokenRefresh      AuditAction = "user.token_refresh"
+	AuditTokenRevoke       AuditAction = "user.token_revoke"
+	AuditPasswordChange    AuditAction = "user.password_change"
+	AuditPasswordReset     AuditAction = "user.password_reset"
+	AuditResetFailed       AuditAction = "user.password_reset_failed"
+	AuditVerify            AuditAction = "user.verify"
+	AuditVerifyFailed      AuditAction = "user.verify_failed"
+	AuditDeleteAccount     AuditAction = "user.delete"
+	AuditGameCreate        AuditAction = "game.create"
+	AuditGameJoin          AuditAction = "game.join"
+	AuditGameMove          AuditAction = "game.move"
+	AuditInvitationDecline AuditAction = "game.decline"
+	AuditAdminBan          AuditAction = "admin.ban"
+	AuditAdminUnban        AuditAction = "admin.unban"
+	AuditAdminRole         AuditAction = "admin.role"
+	AuditAdminBot          AuditAction = "admin.bot"
+	AuditAdminRevoke       AuditAction = "admin.revoke"
+	AuditAdminAbort        AuditAction = "admin.abort"
+)
+
+// AuditEntry records who did what, when and from where. Subject is what the action was done to, like the key of a
+// game or the e-mail address of a banned user. Passwords and tokens are never part of an entry.
+type AuditEntry struct {
+	Id        int64
+	Time      time.Time
+	Action    AuditAction
+	Actor     string // the e-mail address of the user, which may be unknown for a failed login.
+	Ip        string
+	RequestId string
+	Subject   string
+	Details   string
+}
+
+// AuditFilter selects audit entries. Empty fields don't filter.
+type AuditFilter struct {
+	Actor   string
+	Action  AuditAction
+	Subject string
+	Since   time.Time
+	Limit   int
+}
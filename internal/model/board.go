@@ -79,6 +79,38 @@ func (b *Board) AddDisc(col int, disc Disc) bool {
 	return false
 }
 
+// ColumnFull returns true when no more discs can be dropped in the (0-based) column.
+func (b *Board) ColumnFull(col int) bool {
+	return b.Cell(0, col) != NoDisc
+}
+
+// ValidMoves returns the (0-based) columns that still have room for a disc.
+func (b *Board) ValidMoves() []int {
+	output := make([]int, 0, BoardWidth)
+	for col := 0; col < BoardWidth; col++ {
+		if !b.ColumnFull(col) {
+			output = append(output, col)
+		}
+	}
+	return output
+}
+
+// IsFull returns true when there's no room left for any disc.
+func (b *Board) IsFull() bool {
+	return len(b.ValidMoves()) == 0
+}
+
+// Moves returns the number of discs on the board, which is the number of moves that were played.
+func (b *Board) Moves() int {
+	moves := 0
+	for _, d := range b.cells {
+		if d != NoDisc {
+			moves++
+		}
+	}
+	return moves
+}
+
 func (b *Board) Reset() {
 	b.cells = [BoardWidth * BoardHeight]Disc{}
 }
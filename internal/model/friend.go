@@ -0,0 +1,25 @@
+package model
+
+type FriendStatus string
+
+type Presence string
+
+const (
+	FriendRequested FriendStatus = "requested"
+	FriendAccepted  FriendStatus = "accepted"
+)
+
+const (
+	Online  Presence = "online"
+	InGame  Presence = "in game"
+	Idle    Presence = "idle"
+	Offline Presence = "offline"
+)
+
+// Friend is another user as seen from the user whose friends were listed. Incoming is true when the other user
+// sent the friend request.
+type Friend struct {
+	User     User
+	Status   FriendStatus
+	Incoming bool
+}
@@ -12,10 +12,12 @@ type Game struct {
 	Key        string
 	Player1    User
 	Player2    User
-	PlayerTurn int // either 1 or 2
+	Invitee    User // when set, only this user can join the game as the second player.
+	PlayerTurn int  // either 1 or 2
 	CreatedAt  time.Time
 	StartedAt  time.Time
 	FinishedAt time.Time
+	LastMoveAt time.Time
 
 	Public bool
 	Status GameStatus
@@ -56,6 +58,10 @@ func (g *Game) Join(joining User) error {
 		return nil
 	}
 
+	if !g.Invitee.Empty() && !g.Invitee.Is(joining) {
+		return errors.New("this game is only open to the invited player")
+	}
+
 	// if the second player wasn't set yet, then now is the time
 	// to start the game.
 	if g.Player2.Empty() {
@@ -68,6 +74,41 @@ func (g *Game) Join(joining User) error {
 	return nil
 }
 
+// Invite reserves the second seat of the game for the specified user.
+func (g *Game) Invite(invitee User) error {
+	if g.Status != Created {
+		return errors.New("you can only invite a player to a game that has status 'Created'")
+	}
+	if g.Player1.Is(invitee) {
+		return errors.New("you can't invite yourself")
+	}
+	g.Invitee = invitee
+	return nil
+}
+
+// Decline lets the invitee refuse the invitation, which aborts the game.
+func (g *Game) Decline(invitee User) error {
+	if g.Invitee.Empty() || !g.Invitee.Is(invitee) {
+		return errors.New("you were not invited to this game")
+	}
+	if g.Status != Created {
+		return errors.New("you can only decline an invitation for a game that hasn't started")
+	}
+	g.Status = Aborted
+	g.FinishedAt = time.Now()
+	return nil
+}
+
+// Abort stops a game that hasn't finished yet, like a moderator does with an abandoned or abusive game.
+func (g *Game) Abort() error {
+	if g.Status != Created && g.Status != Started {
+		return errors.New("only a game that hasn't finished can be aborted")
+	}
+	g.Status = Aborted
+	g.FinishedAt = time.Now()
+	return nil
+}
+
 // Play will make a play for the current player on the specified column, and set the other player's turn
 // unless the game has ended.
 // Column is 1-based (so acceptable values are 1-7)
@@ -88,8 +129,9 @@ func (g *Game) Play(user User, column int) error {
 	if !g.Board.AddDisc(column-1, g.playerDisc()) {
 		return errors.New("invalid move")
 	}
+	g.LastMoveAt = time.Now()
 
-	if g.Board.HasConnectFour() {
+	if g.Board.HasConnectFour() || g.Board.IsFull() {
 		g.Status = Finished
 		g.FinishedAt = time.Now()
 	} else {
@@ -99,6 +141,37 @@ func (g *Game) Play(user User, column int) error {
 	return nil
 }
 
+// Winner returns the player that connected four, or an empty User when the game isn't finished (yet) or ended
+// in a draw.
+func (g *Game) Winner() User {
+	if g.Status != Finished || !g.Board.HasConnectFour() {
+		return User{}
+	}
+	// the turn doesn't switch after the winning move.
+	return *g.CurrentPlayer()
+}
+
+// Outcome returns how the game ended: "player1" or "player2" for the winner, "draw" or "aborted". It's empty while
+// the game hasn't ended.
+func (g *Game) Outcome() string {
+	switch {
+	case g.Status == Aborted:
+		return "aborted"
+	case g.Status != Finished:
+		return ""
+	case g.IsDraw():
+		return "draw"
+	case g.Winner().Is(g.Player1):
+		return "player1"
+	}
+	return "player2"
+}
+
+// IsDraw returns true when the game finished with a full board and no connect four.
+func (g *Game) IsDraw() bool {
+	return g.Status == Finished && !g.Board.HasConnectFour()
+}
+
 func (g *Game) switchPlayer() {
 	if g.PlayerTurn == 1 {
 		g.PlayerTurn = 2
@@ -127,6 +200,45 @@ func (g *Game) CurrentPlayerEmail() string {
 	return g.CurrentPlayer().Email
 }
 
+// PlayerDisc returns the disc that the player with the specified email plays with, or NoDisc if the
+// player isn't part of this game. Player 1 always plays red.
+func (g *Game) PlayerDisc(email string) Disc {
+	switch {
+	case strings.EqualFold(g.Player1.Email, email):
+		return RedDisc
+	case strings.EqualFold(g.Player2.Email, email):
+		return YellowDisc
+	}
+	return NoDisc
+}
+
 func (g *Game) IsPlayerTurn(email string) bool {
 	return strings.EqualFold(g.CurrentPlayerEmail(), email)
 }
+
+// GameOrder is the order in which games are listed, by the time they were created.
+type GameOrder string
+
+const (
+	NewestFirst GameOrder = "newest"
+	OldestFirst GameOrder = "oldest"
+)
+
+// GameCursor is the last game of a page. The next page continues after it.
+type GameCursor struct {
+	CreatedAt time.Time
+	Key       string // breaks the tie between games that were created at the same time.
+}
+
+// GameFilter selects games. Empty fields don't filter.
+type GameFilter struct {
+	PlayerId   int64 // games that the user plays in.
+	OpponentId int64 // games against this user, as a player or the invitee.
+	Status     GameStatus
+	Public     *bool
+	From       time.Time // games that were created at or after this time.
+	Until      time.Time // games that were created before this time.
+	Order      GameOrder // NewestFirst when empty.
+	After      *GameCursor
+	Limit      int // all the games when 0.
+}
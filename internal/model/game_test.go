@@ -95,3 +95,71 @@ func TestGame_Play_SetsOtherPlayerMove(t *testing.T) {
 	// Assert
 	assert.Equal(t, 2, game.PlayerTurn)
 }
+
+func TestGame_Winner_IsPlayerThatConnectedFour(t *testing.T) {
+	// Arrange
+	game := NewGame(player1, true)
+	_ = game.Join(player2)
+
+	// Act
+	for _, col := range []int{1, 2, 1, 2, 1, 2, 1} {
+		_ = game.Play(*game.CurrentPlayer(), col)
+	}
+
+	// Assert
+	assert.Equal(t, Finished, game.Status)
+	assert.Equal(t, player1, game.Winner())
+	assert.False(t, game.IsDraw())
+}
+
+func TestGame_Outcome(t *testing.T) {
+	// Arrange
+	won := NewGame(player1, true)
+	_ = won.Join(player2)
+	for _, col := range []int{7, 1, 2, 1, 2, 1, 2, 1} {
+		_ = won.Play(*won.CurrentPlayer(), col)
+	}
+	playing := NewGame(player1, true)
+	_ = playing.Join(player2)
+	_ = playing.Play(player1, 4)
+	aborted := NewGame(player1, true)
+	_ = aborted.Abort()
+
+	// Act & Assert
+	assert.Equal(t, "player2", won.Outcome())
+	assert.Equal(t, 8, won.Board.Moves())
+	assert.Equal(t, "", playing.Outcome(), "Expected no outcome while the game is played")
+	assert.Equal(t, 1, playing.Board.Moves())
+	assert.False(t, playing.LastMoveAt.IsZero(), "Expected the time of the move to be kept")
+	assert.Equal(t, "aborted", aborted.Outcome())
+}
+
+func TestGame_Join_OnlyInviteeCanJoin(t *testing.T) {
+	// Arrange
+	game := NewGame(player1, false)
+	_ = game.Invite(player2)
+
+	// Act
+	err1 := game.Join(player3)
+	err2 := game.Join(player2)
+
+	// Assert
+	assert.Error(t, err1, "Expected an error when somebody else than the invitee joins")
+	assert.NoError(t, err2, "Expected the invitee to be able to join")
+	assert.Equal(t, Started, game.Status)
+}
+
+func TestGame_Decline_AbortsGame(t *testing.T) {
+	// Arrange
+	game := NewGame(player1, false)
+	_ = game.Invite(player2)
+
+	// Act
+	err1 := game.Decline(player3)
+	err2 := game.Decline(player2)
+
+	// Assert
+	assert.Error(t, err1, "Expected an error when somebody else declines")
+	assert.NoError(t, err2)
+	assert.Equal(t, Aborted, game.Status)
+}
@@ -0,0 +1,51 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+type NotificationKind string
+
+type NotificationChannel string
+
+const (
+	OpponentJoinedNotification NotificationKind = "opponent_joined"
+	YourTurnNotification       NotificationKind = "your_turn"
+	GameFinishedNotification   NotificationKind = "game_finished"
+	InvitationNotification     NotificationKind = "invitation"
+)
+
+const (
+	InboxChannel   NotificationChannel = "inbox"
+	EmailChannel   NotificationChannel = "email"
+	WebhookChannel NotificationChannel = "webhook"
+)
+
+// Notification tells a user about something that happened in one of their games, while they may not be looking.
+type Notification struct {
+	Id        int64
+	UserId    int64 // the user that is notified.
+	Kind      NotificationKind
+	GameKey   string
+	Message   string
+	CreatedAt time.Time
+	ReadAt    time.Time // zero while the notification is unread.
+}
+
+// NotificationPreferences are the channels that the notifications of a user are sent to. Muted kinds aren't sent to
+// any channel. The webhook channel posts to the webhooks of the user that want the notification event.
+type NotificationPreferences struct {
+	Channels []NotificationChannel
+	Muted    []NotificationKind
+}
+
+// DefaultNotificationPreferences only keeps the notifications in the inbox, until the user chooses otherwise.
+func DefaultNotificationPreferences() NotificationPreferences {
+	return NotificationPreferences{Channels: []NotificationChannel{InboxChannel}}
+}
+
+// Wants returns true when notifications of the kind are sent to the channel.
+func (p NotificationPreferences) Wants(channel NotificationChannel, kind NotificationKind) bool {
+	return slices.Contains(p.Channels, channel) && !slices.Contains(p.Muted, kind)
+}
@@ -0,0 +1,420 @@
+package model
+
+import (
+	"errors"
+	"math/bits"
+	"sort"
+	"strings"
+	"time"
+)
+
+type TournamentFormat string
+
+type TournamentStatus string
+
+type PairingResult string
+
+const (
+	RoundRobin TournamentFormat = "round-robin"
+	Swiss      TournamentFormat = "swiss"
+	Knockout   TournamentFormat = "knockout"
+)
+
+const (
+	Registering TournamentStatus = "registering"
+	Running     TournamentStatus = "running"
+	Completed   TournamentStatus = "completed"
+)
+
+const (
+	Pending     PairingResult = ""
+	Player1Wins PairingResult = "player1"
+	Player2Wins PairingResult = "player2"
+	Draw        PairingResult = "draw"
+	Bye         PairingResult = "bye" // Player1 advances without playing.
+)
+
+type Tournament struct {
+	Id           int64
+	Name         string
+	Format       TournamentFormat
+	Status       TournamentStatus
+	Organizer    User
+	Rounds       int // the total number of rounds, known once the tournament starts.
+	CurrentRound int
+	CreatedAt    time.Time
+	StartedAt    time.Time
+	FinishedAt   time.Time
+
+	Players  []User
+	Pairings []Pairing
+}
+
+// Pairing is a single game between two players in a round of a tournament.
+type Pairing struct {
+	Id      int64
+	Round   int
+	GameKey string
+	Player1 User
+	Player2 User // empty for a bye.
+	Result  PairingResult
+}
+
+// Standing is the score of a single player in a tournament. A win or a bye is worth 1 point and a draw half a point.
+type Standing struct {
+	Player User
+	Played int
+	Wins   int
+	Draws  int
+	Losses int
+	Points float64
+}
+
+func ValidTournamentFormat(format TournamentFormat) bool {
+	return format == RoundRobin || format == Swiss || format == Knockout
+}
+
+// NewTournament creates a tournament that players can register for.
+func NewTournament(organizer User, name string, format TournamentFormat) Tournament {
+	return Tournament{
+		Name:      name,
+		Format:    format,
+		Status:    Registering,
+		Organizer: organizer,
+		CreatedAt: time.Now(),
+	}
+}
+
+// Register adds the player to the tournament, as long as it hasn't started yet.
+func (t *Tournament) Register(player User) error {
+	if t.Status != Registering {
+		return errors.New("you can only register for a tournament that hasn't started yet")
+	}
+	if t.HasPlayer(player) {
+		return nil
+	}
+	t.Players = append(t.Players, player)
+	return nil
+}
+
+func (t *Tournament) HasPlayer(player User) bool {
+	for _, p := range t.Players {
+		if p.Is(player) {
+			return true
+		}
+	}
+	return false
+}
+
+// Start sets the number of rounds and marks the tournament as running. The swissRounds argument is only used for
+// Swiss tournaments, when it's 0 the number of rounds is based on the number of players.
+func (t *Tournament) Start(swissRounds int) error {
+	if t.Status != Registering {
+		return errors.New("this tournament has already started")
+	}
+	if len(t.Players) < 2 {
+		return errors.New("a tournament needs at least two players")
+	}
+
+	n := len(t.Players)
+	switch t.Format {
+	case RoundRobin:
+		t.Rounds = n - 1
+		if n%2 == 1 {
+			t.Rounds = n
+		}
+	case Swiss:
+		t.Rounds = swissRounds
+		if t.Rounds <= 0 {
+			t.Rounds = bits.Len(uint(n - 1)) // ceil(log2(n))
+		}
+	case Knockout:
+		t.Rounds = bits.Len(uint(n - 1))
+	default:
+		return errors.New("unknown tournament format")
+	}
+
+	t.Status = Running
+	t.StartedAt = time.Now()
+	return nil
+}
+
+// RoundPairings returns the pairings of the specified round.
+func (t *Tournament) RoundPairings(round int) []Pairing {
+	output := make([]Pairing, 0)
+	for _, p := range t.Pairings {
+		if p.Round == round {
+			output = append(output, p)
+		}
+	}
+	return output
+}
+
+// RoundFinished returns true when all games of the current round have a result.
+func (t *Tournament) RoundFinished() bool {
+	pairings := t.RoundPairings(t.CurrentRound)
+	for _, p := range pairings {
+		if p.Result == Pending {
+			return false
+		}
+	}
+	return len(pairings) > 0
+}
+
+// PairingByGameKey returns a pointer to the pairing for the specified game, or nil if the game isn't part of the
+// tournament.
+func (t *Tournament) PairingByGameKey(key string) *Pairing {
+	for i := range t.Pairings {
+		if t.Pairings[i].GameKey != "" && t.Pairings[i].GameKey == key {
+			return &t.Pairings[i]
+		}
+	}
+	return nil
+}
+
+// NextPairings returns the pairings for the next round, based on the format and the results so far. The
+// returned pairings don't have a game yet. It returns nil when the tournament is over.
+func (t *Tournament) NextPairings() []Pairing {
+	round := t.CurrentRound + 1
+	if round > t.Rounds {
+		return nil
+	}
+
+	var pairs [][2]User
+	switch t.Format {
+	case RoundRobin:
+		pairs = roundRobinPairs(t.Players, round)
+	case Swiss:
+		pairs = swissPairs(t.Standings(), t.Pairings)
+	case Knockout:
+		pairs = knockoutPairs(t, round)
+	}
+
+	if t.Format == Knockout && len(pairs) == 0 {
+		return nil
+	}
+
+	output := make([]Pairing, 0, len(pairs))
+	for _, pair := range pairs {
+		p := Pairing{
+			Round:   round,
+			Player1: pair[0],
+			Player2: pair[1],
+		}
+		if p.Player2.Empty() {
+			p.Result = Bye
+		}
+		output = append(output, p)
+	}
+	return output
+}
+
+// Standings returns the players sorted by points, then by wins and then by name.
+func (t *Tournament) Standings() []Standing {
+	standings := make(map[string]*Standing)
+	order := make([]*Standing, 0, len(t.Players))
+	for _, p := range t.Players {
+		s := &Standing{Player: p}
+		standings[strings.ToLower(p.Email)] = s
+		order = append(order, s)
+	}
+
+	for _, p := range t.Pairings {
+		s1 := standings[strings.ToLower(p.Player1.Email)]
+		s2 := standings[strings.ToLower(p.Player2.Email)]
+		switch p.Result {
+		case Bye:
+			s1.Wins++
+			s1.Points++
+		case Player1Wins:
+			s1.Played++
+			s1.Wins++
+			s1.Points++
+			s2.Played++
+			s2.Losses++
+		case Player2Wins:
+			s1.Played++
+			s1.Losses++
+			s2.Played++
+			s2.Wins++
+			s2.Points++
+		case Draw:
+			s1.Played++
+			s1.Draws++
+			s1.Points += 0.5
+			s2.Played++
+			s2.Draws++
+			s2.Points += 0.5
+		}
+	}
+
+	output := make([]Standing, 0, len(order))
+	for _, s := range order {
+		output = append(output, *s)
+	}
+	sort.SliceStable(output, func(i, j int) bool {
+		if output[i].Points != output[j].Points {
+			return output[i].Points > output[j].Points
+		}
+		if output[i].Wins != output[j].Wins {
+			return output[i].Wins > output[j].Wins
+		}
+		return output[i].Player.Name < output[j].Player.Name
+	})
+	return output
+}
+
+// Winner returns the winner of a completed tournament. For knockout tournaments that's the winner of the final,
+// for the other formats it's the player at the top of the standings.
+func (t *Tournament) Winner() User {
+	if t.Status != Completed {
+		return User{}
+	}
+	if t.Format == Knockout {
+		winners := roundWinners(t.RoundPairings(t.Rounds))
+		if len(winners) == 1 {
+			return winners[0]
+		}
+		return User{}
+	}
+	standings := t.Standings()
+	return standings[0].Player
+}
+
+// roundRobinPairs uses the circle method: the first player stays in place, the others rotate one position each
+// round. With an odd number of players, an empty user is added and whoever is paired with it gets a bye.
+func roundRobinPairs(players []User, round int) [][2]User {
+	list := append([]User{}, players...)
+	if len(list)%2 == 1 {
+		list = append(list, User{})
+	}
+	n := len(list)
+
+	rotating := list[1:]
+	shift := (round - 1) % len(rotating)
+	rotated := append(append([]User{}, rotating[len(rotating)-shift:]...), rotating[:len(rotating)-shift]...)
+	list = append([]User{list[0]}, rotated...)
+
+	pairs := make([][2]User, 0, n/2)
+	for i := 0; i < n/2; i++ {
+		pairs = append(pairs, byeLast(list[i], list[n-1-i]))
+	}
+	return pairs
+}
+
+// swissPairs pairs players with the same (or similar) score that haven't played each other yet. When there's an
+// odd number of players, the lowest ranked player that hasn't had a bye yet gets one.
+func swissPairs(standings []Standing, previous []Pairing) [][2]User {
+	met := make(map[string]bool)
+	hadBye := make(map[string]bool)
+	for _, p := range previous {
+		if p.Result == Bye {
+			hadBye[strings.ToLower(p.Player1.Email)] = true
+			continue
+		}
+		met[pairKey(p.Player1, p.Player2)] = true
+	}
+
+	players := make([]User, 0, len(standings))
+	for _, s := range standings {
+		players = append(players, s.Player)
+	}
+
+	pairs := make([][2]User, 0, len(players)/2+1)
+	if len(players)%2 == 1 {
+		bye := len(players) - 1
+		for i := len(players) - 1; i >= 0; i-- {
+			if !hadBye[strings.ToLower(players[i].Email)] {
+				bye = i
+				break
+			}
+		}
+		pairs = append(pairs, [2]User{players[bye], {}})
+		players = append(players[:bye:bye], players[bye+1:]...)
+	}
+
+	paired := make([]bool, len(players))
+	for i := range players {
+		if paired[i] {
+			continue
+		}
+		opponent := -1
+		for j := i + 1; j < len(players); j++ {
+			if paired[j] {
+				continue
+			}
+			if opponent == -1 {
+				opponent = j // fall back to a rematch if everybody else was met already.
+			}
+			if !met[pairKey(players[i], players[j])] {
+				opponent = j
+				break
+			}
+		}
+		if opponent == -1 {
+			continue
+		}
+		paired[i] = true
+		paired[opponent] = true
+		pairs = append(pairs, [2]User{players[i], players[opponent]})
+	}
+	return pairs
+}
+
+// knockoutPairs seeds the first round by registration order, giving the top seeds a bye when the number of players
+// isn't a power of two. The next rounds pair the winners of the previous round in bracket order.
+func knockoutPairs(t *Tournament, round int) [][2]User {
+	var players []User
+	if round == 1 {
+		n := len(t.Players)
+		size := 1 << bits.Len(uint(n-1))
+		byes := size - n
+		pairs := make([][2]User, 0, size/2)
+		for i := 0; i < byes; i++ {
+			pairs = append(pairs, [2]User{t.Players[i], {}})
+		}
+		players = t.Players[byes:]
+		for i := 0; i < len(players)/2; i++ {
+			pairs = append(pairs, [2]User{players[i], players[len(players)-1-i]})
+		}
+		return pairs
+	}
+
+	players = roundWinners(t.RoundPairings(round - 1))
+	pairs := make([][2]User, 0, len(players)/2)
+	for i := 0; i+1 < len(players); i += 2 {
+		pairs = append(pairs, [2]User{players[i], players[i+1]})
+	}
+	return pairs
+}
+
+// roundWinners returns the players that won their pairing, in pairing order. Drawn pairings are replayed, so they
+// don't produce a winner.
+func roundWinners(pairings []Pairing) []User {
+	winners := make([]User, 0, len(pairings))
+	for _, p := range pairings {
+		switch p.Result {
+		case Bye, Player1Wins:
+			winners = append(winners, p.Player1)
+		case Player2Wins:
+			winners = append(winners, p.Player2)
+		}
+	}
+	return winners
+}
+
+// byeLast makes sure the empty (bye) user is always the second player of a pair.
+func byeLast(a User, b User) [2]User {
+	if a.Empty() {
+		return [2]User{b, a}
+	}
+	return [2]User{a, b}
+}
+
+func pairKey(a User, b User) string {
+	ea, eb := strings.ToLower(a.Email), strings.ToLower(b.Email)
+	if ea > eb {
+		ea, eb = eb, ea
+	}
+	return ea + "|" + eb
+}
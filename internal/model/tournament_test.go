@@ -0,0 +1,132 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var player4 = NewUser("Mika", "mika@evilnerd.nl")
+
+func startedTournament(format TournamentFormat, players ...User) Tournament {
+	t := NewTournament(player1, "Test cup", format)
+	for _, p := range players {
+		_ = t.Register(p)
+	}
+	_ = t.Start(0)
+	return t
+}
+
+// playRound adds the next pairings and lets player 1 of every pairing win.
+func playRound(t *Tournament) []Pairing {
+	pairings := t.NextPairings()
+	t.CurrentRound++
+	for i := range pairings {
+		if pairings[i].Result == Pending {
+			pairings[i].Result = Player1Wins
+		}
+	}
+	t.Pairings = append(t.Pairings, pairings...)
+	return pairings
+}
+
+func TestTournament_Register_NotOkAfterStart(t *testing.T) {
+	// Arrange
+	tournament := startedTournament(RoundRobin, player1, player2)
+
+	// Act
+	err := tournament.Register(player3)
+
+	// Assert
+	assert.Error(t, err, "Expected an error when registering for a running tournament")
+}
+
+func TestTournament_RoundRobin_EveryoneMeetsEveryoneOnce(t *testing.T) {
+	// Arrange
+	tournament := startedTournament(RoundRobin, player1, player2, player3, player4)
+	met := make(map[string]int)
+
+	// Act
+	for round := 1; round <= tournament.Rounds; round++ {
+		for _, p := range playRound(&tournament) {
+			met[pairKey(p.Player1, p.Player2)]++
+		}
+	}
+
+	// Assert
+	assert.Equal(t, 3, tournament.Rounds)
+	assert.Len(t, met, 6, "Expected every combination of 4 players to be played")
+	for pair, count := range met {
+		assert.Equal(t, 1, count, "Expected %s to be played once", pair)
+	}
+	assert.Nil(t, tournament.NextPairings(), "Expected no more pairings after the last round")
+}
+
+func TestTournament_RoundRobin_OddPlayersGetBye(t *testing.T) {
+	// Arrange
+	tournament := startedTournament(RoundRobin, player1, player2, player3)
+
+	// Act
+	pairings := tournament.NextPairings()
+
+	// Assert
+	assert.Equal(t, 3, tournament.Rounds)
+	assert.Len(t, pairings, 2)
+	byes := 0
+	for _, p := range pairings {
+		if p.Result == Bye {
+			byes++
+			assert.True(t, p.Player2.Empty(), "Expected the bye to have no second player")
+		}
+	}
+	assert.Equal(t, 1, byes)
+}
+
+func TestTournament_Knockout_ProducesWinner(t *testing.T) {
+	// Arrange
+	tournament := startedTournament(Knockout, player1, player2, player3)
+
+	// Act
+	first := playRound(&tournament)
+	final := playRound(&tournament)
+	tournament.Status = Completed
+
+	// Assert
+	assert.Equal(t, 2, tournament.Rounds)
+	assert.Len(t, first, 2, "Expected one bye and one game in the first round")
+	assert.Equal(t, Bye, first[0].Result, "Expected the top seed to get the bye")
+	assert.Len(t, final, 1)
+	assert.Equal(t, player1, tournament.Winner())
+}
+
+func TestTournament_Swiss_AvoidsRematches(t *testing.T) {
+	// Arrange
+	tournament := startedTournament(Swiss, player1, player2, player3, player4)
+
+	// Act
+	first := playRound(&tournament)
+	second := tournament.NextPairings()
+
+	// Assert
+	assert.Equal(t, 2, tournament.Rounds)
+	for _, p := range second {
+		for _, f := range first {
+			assert.NotEqual(t, pairKey(f.Player1, f.Player2), pairKey(p.Player1, p.Player2), "Expected no rematch in round 2")
+		}
+	}
+}
+
+func TestTournament_Standings_SortsByPoints(t *testing.T) {
+	// Arrange
+	tournament := startedTournament(RoundRobin, player1, player2)
+	tournament.Pairings = []Pairing{
+		{Round: 1, Player1: player1, Player2: player2, Result: Player2Wins},
+	}
+
+	// Act
+	standings := tournament.Standings()
+
+	// Assert
+	assert.Equal(t, player2, standings[0].Player)
+	assert.Equal(t, 1.0, standings[0].Points)
+	assert.Equal(t, 1, standings[1].Losses)
+}
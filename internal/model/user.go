@@ -6,17 +6,59 @@ import (
 	"strings"
 )
 
+// DeletedUserName is the name that replaces the name of a user that deleted their account.
+const DeletedUserName = "Deleted player"
+
+// Role is what a user is allowed to do. Moderators can look up and ban players and abort games, admins can also
+// change the roles of users.
+type Role string
+
+const (
+	RolePlayer    Role = "player"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+var roleRanks = map[Role]int{RolePlayer: 1, RoleModerator: 2, RoleAdmin: 3}
+
+// Valid returns whether the role is one of the known roles.
+func (r Role) Valid() bool {
+	_, ok := roleRanks[r]
+	return ok
+}
+
+// AtLeast returns whether the role grants everything that the other role does. An unknown role grants nothing.
+func (r Role) AtLeast(other Role) bool {
+	return r.Valid() && roleRanks[r] >= roleRanks[other]
+}
+
 type User struct {
-	Id    int64
-	Name  string
-	Email string
-	Token string
+	Id       int64
+	Name     string
+	Email    string
+	Token    string
+	Bot      bool // bot accounts get long-lived tokens and play through the headless api. Only admins make bots.
+	Verified bool // set when the user proved the e-mail address is theirs.
+	Role     Role
+	Banned   bool // banned users can't log in.
+
+	// TokenVersion is in the JWTs of the user. It's raised to revoke the tokens that were issued before.
+	TokenVersion int
+}
+
+// UserStats counts the users, for the statistics of the admin api.
+type UserStats struct {
+	Total    int
+	Bots     int
+	Verified int
+	Banned   int
 }
 
 func NewUser(name string, email string) User {
 	return User{
 		Name:  name,
 		Email: email,
+		Role:  RolePlayer,
 	}
 }
 
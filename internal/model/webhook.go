@@ -0,0 +1,54 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending" // waiting for its first or next attempt.
+	DeliveryDelivered DeliveryStatus = "delivered"
+	DeliveryFailed    DeliveryStatus = "failed" // gave up after the last attempt, or the receiver refused it.
+)
+
+// Webhook is a url that the events of games are posted to, signed with its secret. It gets the events of the games
+// that its owner plays in, or of all games when an admin registered it for all games.
+type Webhook struct {
+	Id        int64
+	OwnerId   int64
+	Url       string
+	Secret    string
+	Events    []string // the types of the game events, all of them when empty.
+	AllGames  bool
+	CreatedAt time.Time
+}
+
+// Wants returns true when the webhook gets events of the type.
+func (w Webhook) Wants(event string) bool {
+	return len(w.Events) == 0 || slices.Contains(w.Events, event)
+}
+
+// WebhookDelivery is an event that is posted to a webhook, with the outcome of the last attempt. It's kept in the
+// delivery log of the webhook.
+type WebhookDelivery struct {
+	Id             int64
+	WebhookId      int64
+	Event          string
+	GameKey        string
+	Payload        string // the JSON that is posted.
+	Status         DeliveryStatus
+	Attempts       int
+	ResponseStatus int    // the http status of the last attempt, 0 when the webhook couldn't be reached.
+	Error          string // why the last attempt failed.
+	CreatedAt      time.Time
+	LastAttemptAt  time.Time
+	NextAttemptAt  time.Time // zero when there won't be another attempt.
+}
+
+// PendingDelivery is a delivery that will be attempted (again), with the webhook that it's posted to.
+type PendingDelivery struct {
+	Webhook  Webhook
+	Delivery WebhookDelivery
+}
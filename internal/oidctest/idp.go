@@ -0,0 +1,185 @@
+// Package oidctest contains a mock OpenID Connect identity provider, to test signing in without a real one. It
+// approves every authorization request for the configured identity.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/go-jose/go-jose/v4"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const keyId = "mock-idp"
+
+// Identity is the user that the MockIdP signs in.
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type authorization struct {
+	clientId  string
+	challenge string
+	nonce     string
+}
+
+// MockIdP is an http.Handler that implements the discovery, jwks, authorization and token endpoints. The Issuer must
+// be set to the url it's served on.
+type MockIdP struct {
+	Issuer   string
+	Identity Identity
+	key      *rsa.PrivateKey
+	mu       sync.Mutex
+	codes    map[string]authorization
+	mux      *http.ServeMux
+}
+
+func NewMockIdP(identity Identity) (*MockIdP, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	idp := &MockIdP{
+		Identity: identity,
+		key:      key,
+		codes:    make(map[string]authorization),
+		mux:      http.NewServeMux(),
+	}
+	idp.mux.HandleFunc("GET /.well-known/openid-configuration", idp.discovery)
+	idp.mux.HandleFunc("GET /jwks", idp.jwks)
+	idp.mux.HandleFunc("GET /authorize", idp.authorize)
+	idp.mux.HandleFunc("POST /token", idp.token)
+	return idp, nil
+}
+
+func (idp *MockIdP) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	idp.mux.ServeHTTP(w, r)
+}
+
+func (idp *MockIdP) discovery(w http.ResponseWriter, _ *http.Request) {
+	writeJson(w, http.StatusOK, map[string]any{
+		"issuer":                                idp.Issuer,
+		"authorization_endpoint":                idp.Issuer + "/authorize",
+		"token_endpoint":                        idp.Issuer + "/token",
+		"jwks_uri":                              idp.Issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (idp *MockIdP) jwks(w http.ResponseWriter, _ *http.Request) {
+	writeJson(w, http.StatusOK, jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{
+		Key:       &idp.key.PublicKey,
+		KeyID:     keyId,
+		Algorithm: string(jose.RS256),
+		Use:       "sig",
+	}}})
+}
+
+// authorize approves the request right away and redirects back to the client with a code.
+func (idp *MockIdP) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" {
+		http.Error(w, "only the code flow with S256 PKCE is supported", http.StatusBadRequest)
+		return
+	}
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || redirect.String() == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	code := randomHex()
+	idp.mu.Lock()
+	idp.codes[code] = authorization{clientId: q.Get("client_id"), challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+	idp.mu.Unlock()
+
+	params := redirect.Query()
+	params.Set("code", code)
+	params.Set("state", q.Get("state"))
+	redirect.RawQuery = params.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+// token exchanges a code for an id token, after checking the PKCE code verifier.
+func (idp *MockIdP) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	idp.mu.Lock()
+	auth, ok := idp.codes[code]
+	delete(idp.codes, code)
+	idp.mu.Unlock()
+
+	h := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(h[:]) != auth.challenge {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	idToken, err := idp.sign(map[string]any{
+		"iss":            idp.Issuer,
+		"sub":            idp.Identity.Subject,
+		"aud":            auth.clientId,
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"nonce":          auth.nonce,
+		"email":          idp.Identity.Email,
+		"email_verified": idp.Identity.EmailVerified,
+		"name":           idp.Identity.Name,
+	})
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJson(w, http.StatusOK, map[string]any{
+		"access_token": randomHex(),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func (idp *MockIdP) sign(claims map[string]any) (string, error) {
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.RS256, Key: jose.JSONWebKey{Key: idp.key, KeyID: keyId}},
+		(&jose.SignerOptions{}).WithType("JWT"))
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	jws, err := signer.Sign(payload)
+	if err != nil {
+		return "", err
+	}
+	return jws.CompactSerialize()
+}
+
+func writeJson(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func randomHex() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,56 @@
+// Package ratelimit protects endpoints like the login against brute-force attempts, by locking a key out for an
+// exponentially growing period after repeated failures.
+package ratelimit
+
+import (
+	"time"
+)
+
+// Limiter counts the failed attempts per key (e.g. e-mail address and client ip). The MemoryLimiter keeps the counts
+// in memory, so they aren't shared between api instances; a shared store (like Redis) can implement this interface
+// to do that.
+type Limiter interface {
+	// Allow returns whether an attempt for the key is allowed right now.
+	Allow(key string) Decision
+	// Failure records a failed attempt, which may lock the key out.
+	Failure(key string) Decision
+	// Success forgets the failed attempts of the key.
+	Success(key string) Decision
+}
+
+// Decision is the state of a key after checking or recording an attempt.
+type Decision struct {
+	Allowed    bool
+	Limit      int           // the number of failures that are allowed before the key is locked out.
+	Remaining  int           // the number of failures left before the key is locked out.
+	RetryAfter time.Duration // how long the key is locked out, zero when it's allowed.
+	Reset      time.Time     // when the failures are forgotten.
+}
+
+// Policy configures when a key is locked out and for how long.
+type Policy struct {
+	MaxFailures int           // failures within the Window before the key is locked out.
+	Window      time.Duration // the period in which failures are counted.
+	BaseLockout time.Duration // the lockout after MaxFailures failures, which doubles with every next failure.
+	MaxLockout  time.Duration // the longest lockout.
+}
+
+// DefaultPolicy allows 5 failures in 15 minutes, then locks out for 30 seconds, 1 minute, 2 minutes etc. up to an hour.
+var DefaultPolicy = Policy{
+	MaxFailures: 5,
+	Window:      15 * time.Minute,
+	BaseLockout: 30 * time.Second,
+	MaxLockout:  time.Hour,
+}
+
+// lockout returns how long a key is locked out after the number of failures.
+func (p Policy) lockout(failures int) time.Duration {
+	if failures < p.MaxFailures {
+		return 0
+	}
+	d := p.BaseLockout
+	for i := p.MaxFailures; i < failures && d < p.MaxLockout; i++ {
+		d *= 2
+	}
+	return min(d, p.MaxLockout)
+}
@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// sweepSize is the number of keys after which expired keys are removed, so the map doesn't grow forever.
+const sweepSize = 10_000
+
+type entry struct {
+	failures    int
+	reset       time.Time // when the failures are forgotten.
+	lockedUntil time.Time
+}
+
+// MemoryLimiter is a Limiter that keeps the failures in memory.
+type MemoryLimiter struct {
+	policy  Policy
+	mu      sync.Mutex
+	entries map[string]*entry
+	now     func() time.Time
+}
+
+var _ Limiter = &MemoryLimiter{}
+
+func NewMemoryLimiter(policy Policy) *MemoryLimiter {
+	return &MemoryLimiter{
+		policy:  policy,
+		entries: make(map[string]*entry),
+		now:     time.Now,
+	}
+}
+
+func (l *MemoryLimiter) Allow(key string) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.decision(l.entry(key))
+}
+
+func (l *MemoryLimiter) Failure(key string) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e := l.entry(key)
+	now := l.now()
+	e.failures++
+	// A lockout extends the window, so the failures aren't forgotten while the key is locked out.
+	e.lockedUntil = now.Add(l.policy.lockout(e.failures))
+	e.reset = maxTime(now.Add(l.policy.Window), e.lockedUntil)
+	return l.decision(e)
+}
+
+func (l *MemoryLimiter) Success(key string) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.entries, key)
+	return l.decision(l.entry(key))
+}
+
+// entry returns the current entry of the key, starting a new one when the old one expired.
+func (l *MemoryLimiter) entry(key string) *entry {
+	now := l.now()
+	if len(l.entries) >= sweepSize {
+		for k, e := range l.entries {
+			if !now.Before(e.reset) {
+				delete(l.entries, k)
+			}
+		}
+	}
+
+	e, ok := l.entries[key]
+	if !ok || !now.Before(e.reset) {
+		e = &entry{reset: now.Add(l.policy.Window)}
+		l.entries[key] = e
+	}
+	return e
+}
+
+func (l *MemoryLimiter) decision(e *entry) Decision {
+	now := l.now()
+	d := Decision{
+		Allowed:   true,
+		Limit:     l.policy.MaxFailures,
+		Remaining: max(l.policy.MaxFailures-e.failures, 0),
+		Reset:     e.reset,
+	}
+	if now.Before(e.lockedUntil) {
+		d.Allowed = false
+		d.RetryAfter = e.lockedUntil.Sub(now)
+	}
+	return d
+}
+
+func maxTime(a time.Time, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func testLimiter() (*MemoryLimiter, *time.Time) {
+	now := time.Now()
+	l := NewMemoryLimiter(DefaultPolicy)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestMemoryLimiter_LocksOutAfterMaxFailures(t *testing.T) {
+	// Arrange
+	l, _ := testLimiter()
+
+	// Act
+	var last Decision
+	for i := 0; i < DefaultPolicy.MaxFailures-1; i++ {
+		last = l.Failure("dick@evilnerd.nl|127.0.0.1")
+	}
+	beforeLockout := l.Allow("dick@evilnerd.nl|127.0.0.1")
+	locked := l.Failure("dick@evilnerd.nl|127.0.0.1")
+	other := l.Allow("dick@evilnerd.nl|10.0.0.1")
+
+	// Assert
+	assert.True(t, last.Allowed)
+	assert.True(t, beforeLockout.Allowed)
+	assert.Equal(t, 1, beforeLockout.Remaining)
+	assert.False(t, locked.Allowed)
+	assert.Equal(t, 0, locked.Remaining)
+	assert.Equal(t, DefaultPolicy.BaseLockout, locked.RetryAfter)
+	assert.True(t, other.Allowed, "Expected another key not to be locked out")
+}
+
+func TestMemoryLimiter_LockoutGrowsExponentially(t *testing.T) {
+	// Arrange
+	l, now := testLimiter()
+	for i := 0; i < DefaultPolicy.MaxFailures; i++ {
+		l.Failure("key")
+	}
+
+	// Act
+	*now = now.Add(DefaultPolicy.BaseLockout)
+	allowedAfterLockout := l.Allow("key")
+	second := l.Failure("key")
+	*now = now.Add(second.RetryAfter)
+	third := l.Failure("key")
+
+	// Assert
+	assert.True(t, allowedAfterLockout.Allowed)
+	assert.Equal(t, 2*DefaultPolicy.BaseLockout, second.RetryAfter)
+	assert.Equal(t, 4*DefaultPolicy.BaseLockout, third.RetryAfter)
+}
+
+func TestMemoryLimiter_SuccessAndWindowReset(t *testing.T) {
+	// Arrange
+	l, now := testLimiter()
+	l.Failure("a")
+	l.Failure("b")
+
+	// Act
+	l.Success("a")
+	afterSuccess := l.Allow("a")
+	*now = now.Add(DefaultPolicy.Window)
+	afterWindow := l.Allow("b")
+
+	// Assert
+	assert.Equal(t, DefaultPolicy.MaxFailures, afterSuccess.Remaining)
+	assert.Equal(t, DefaultPolicy.MaxFailures, afterWindow.Remaining, "Expected the failures to be forgotten")
+}
+
+func TestPolicy_LockoutIsCapped(t *testing.T) {
+	assert.Equal(t, time.Duration(0), DefaultPolicy.lockout(DefaultPolicy.MaxFailures-1))
+	assert.Equal(t, DefaultPolicy.MaxLockout, DefaultPolicy.lockout(100))
+}
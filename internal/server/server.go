@@ -0,0 +1,209 @@
+// Package server runs the api over HTTP or HTTPS, and stops it without cutting off the requests in flight.
+package server
+
+import (
+	"connectfour/internal/metrics"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Config has the address, certificate and timeouts of the api.
+type Config struct {
+	Port         string
+	CertFile     string // the api is served over TLS when both the certificate and the key file are set.
+	KeyFile      string
+	RedirectPort string // with TLS, plain HTTP requests on this port are redirected to https ("" disables it).
+	MetricsPort  string // the metrics are served on this port, which must stay internal ("" disables them).
+
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	// WriteTimeout must be longer than the 60s that a request may take, which includes waiting for a turn.
+	WriteTimeout    time.Duration
+	IdleTimeout     time.Duration
+	ShutdownTimeout time.Duration // how long the requests in flight may take to finish when the api stops.
+}
+
+var DefaultConfig = Config{
+	Port:              "8443",
+	RedirectPort:      "8080",
+	ReadHeaderTimeout: 10 * time.Second,
+	ReadTimeout:       30 * time.Second,
+	WriteTimeout:      75 * time.Second,
+	IdleTimeout:       2 * time.Minute,
+	ShutdownTimeout:   30 * time.Second,
+}
+
+// ConfigFromEnv returns the DefaultConfig, with the values that are set in the CONNECT_FOUR_SERVER_*,
+// CONNECT_FOUR_TLS_* and CONNECT_FOUR_SHUTDOWN_TIMEOUT environment variables.
+func ConfigFromEnv() Config {
+	cfg := DefaultConfig
+	envString("CONNECT_FOUR_SERVER_PORT", &cfg.Port)
+	envString("CONNECT_FOUR_TLS_CERT_FILE", &cfg.CertFile)
+	envString("CONNECT_FOUR_TLS_KEY_FILE", &cfg.KeyFile)
+	envString("CONNECT_FOUR_HTTP_REDIRECT_PORT", &cfg.RedirectPort)
+	envString("CONNECT_FOUR_METRICS_PORT", &cfg.MetricsPort)
+	envDuration("CONNECT_FOUR_SERVER_READ_TIMEOUT", &cfg.ReadTimeout)
+	envDuration("CONNECT_FOUR_SERVER_WRITE_TIMEOUT", &cfg.WriteTimeout)
+	envDuration("CONNECT_FOUR_SERVER_IDLE_TIMEOUT", &cfg.IdleTimeout)
+	envDuration("CONNECT_FOUR_SHUTDOWN_TIMEOUT", &cfg.ShutdownTimeout)
+	return cfg
+}
+
+func (cfg Config) useTLS() bool {
+	return cfg.CertFile != "" && cfg.KeyFile != ""
+}
+
+// stoppingKey is the context key of the channel that is closed when the api stops.
+type stoppingKey struct{}
+
+// Serve runs the api until ctx is cancelled. It then stops accepting connections and waits for the requests in flight
+// to finish, for at most the shutdown timeout. The requests that wait, like the long-polls, are told to stop waiting
+// with UntilStopping.
+func Serve(ctx context.Context, handler http.Handler, cfg Config) error {
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		return errors.New("set both CONNECT_FOUR_TLS_CERT_FILE and CONNECT_FOUR_TLS_KEY_FILE to serve over TLS")
+	}
+
+	api := newServer(":"+cfg.Port, handler, cfg)
+	stopping := make(chan struct{})
+	base := context.WithValue(context.Background(), stoppingKey{}, (<-chan struct{})(stopping))
+	api.BaseContext = func(net.Listener) context.Context { return base }
+	api.RegisterOnShutdown(func() { close(stopping) })
+	servers := []*http.Server{api}
+	if cfg.useTLS() {
+		api.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		log.Infof("Serving the api over TLS on %s", api.Addr)
+		if cfg.RedirectPort != "" {
+			redirect := newServer(":"+cfg.RedirectPort, redirectToHttps(cfg.Port), cfg)
+			servers = append(servers, redirect)
+			log.Infof("Redirecting plain HTTP on %s to https", redirect.Addr)
+		}
+	} else {
+		log.Infof("Serving the api on %s (without TLS)", api.Addr)
+	}
+	if cfg.MetricsPort != "" {
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", metrics.Handler())
+		metricsServer := newServer(":"+cfg.MetricsPort, mux, cfg)
+		servers = append(servers, metricsServer)
+		log.Infof("Serving the metrics on %s/metrics", metricsServer.Addr)
+	}
+
+	failed := make(chan error, len(servers))
+	for _, server := range servers {
+		go func() {
+			var err error
+			if server.TLSConfig != nil {
+				err = server.ListenAndServeTLS(cfg.CertFile, cfg.KeyFile)
+			} else {
+				err = server.ListenAndServe()
+			}
+			if !errors.Is(err, http.ErrServerClosed) {
+				failed <- fmt.Errorf("serving on %s: %w", server.Addr, err)
+			}
+		}()
+	}
+
+	var err error
+	select {
+	case <-ctx.Done():
+		log.Infof("Stopping, waiting at most %s for the requests in flight...", cfg.ShutdownTimeout)
+	case err = <-failed:
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	for _, server := range servers {
+		if shutdownErr := server.Shutdown(shutdownCtx); shutdownErr != nil {
+			log.Warnf("Not all requests on %s finished in time: %v", server.Addr, shutdownErr)
+			_ = server.Close()
+		}
+	}
+	return err
+}
+
+// UntilStopping returns a context of the request that is cancelled too when the api starts to stop, for the requests
+// that wait for something that may take longer than the shutdown timeout. Outside of Serve, it's only cancelled with
+// the request.
+func UntilStopping(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	if stopping, ok := ctx.Value(stoppingKey{}).(<-chan struct{}); ok {
+		go func() {
+			select {
+			case <-stopping:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+	return ctx, cancel
+}
+
+// Stopping returns true when the api serving the request has started to stop.
+func Stopping(ctx context.Context) bool {
+	stopping, ok := ctx.Value(stoppingKey{}).(<-chan struct{})
+	if !ok {
+		return false
+	}
+	select {
+	case <-stopping:
+		return true
+	default:
+		return false
+	}
+}
+
+func newServer(addr string, handler http.Handler, cfg Config) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+}
+
+// redirectToHttps sends the clients to the same url over https, on the port of the api. Only GET and HEAD are
+// redirected: other requests carry passwords, tokens or moves, which were sent in plain text already, so they're
+// refused instead of repeated, and the client has to be fixed to use https.
+func redirectToHttps(port string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Use https, the api doesn't accept "+r.Method+" requests over plain http", http.StatusBadRequest)
+			return
+		}
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+	})
+}
+
+func envString(name string, value *string) {
+	if s, ok := os.LookupEnv(name); ok {
+		*value = s
+	}
+}
+
+func envDuration(name string, value *time.Duration) {
+	if s, ok := os.LookupEnv(name); ok {
+		if d, err := time.ParseDuration(s); err == nil && d > 0 {
+			*value = d
+		} else {
+			log.Warnf("Ignoring %s=%s, it must be a duration like 30s or 5m", name, s)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func freePort(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	return strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+}
+
+func TestRedirectToHttps(t *testing.T) {
+	tests := []struct {
+		port     string
+		host     string
+		url      string
+		expected string
+	}{
+		{"8443", "localhost:8080", "/games/sals-nil-abri/play", "https://localhost:8443/games/sals-nil-abri/play"},
+		{"443", "connectfour.example.com", "/games?status=created", "https://connectfour.example.com/games?status=created"},
+	}
+	for _, test := range tests {
+		t.Run(test.expected, func(t *testing.T) {
+			// Arrange
+			request := httptest.NewRequest("GET", test.url, nil)
+			request.Host = test.host
+			response := httptest.NewRecorder()
+
+			// Act
+			redirectToHttps(test.port).ServeHTTP(response, request)
+
+			// Assert
+			assert.Equal(t, http.StatusMovedPermanently, response.Code)
+			assert.Equal(t, test.expected, response.Header().Get("Location"))
+		})
+	}
+}
+
+func TestRedirectToHttps_RefusesOtherMethods(t *testing.T) {
+	// Arrange
+	request := httptest.NewRequest("POST", "/login", strings.NewReader(`{"password": "hunter2"}`))
+	request.Host = "localhost:8080"
+	response := httptest.NewRecorder()
+
+	// Act
+	redirectToHttps("8443").ServeHTTP(response, request)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	assert.Empty(t, response.Header().Get("Location"), "Expected the request not to be repeated over https")
+}
+
+func TestServe_FinishesTheRequestsInFlight(t *testing.T) {
+	// Arrange
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		_, _ = w.Write([]byte("played"))
+	})
+	cfg := DefaultConfig
+	cfg.Port = freePort(t)
+	ctx, stop := context.WithCancel(context.Background())
+	stopped := make(chan error)
+	go func() { stopped <- Serve(ctx, handler, cfg) }()
+
+	status := make(chan int)
+	go func() {
+		var response *http.Response
+		var err error
+		for range 50 {
+			if response, err = http.Post("http://127.0.0.1:"+cfg.Port+"/play", "application/json", nil); err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if err != nil {
+			status <- 0
+			return
+		}
+		_ = response.Body.Close()
+		status <- response.StatusCode
+	}()
+	<-started
+
+	// Act
+	stop()
+
+	// Assert
+	assert.Equal(t, http.StatusOK, <-status, "Expected the request in flight to finish")
+	assert.NoError(t, <-stopped)
+}
+
+func TestServe_StopsTheRequestsThatWait(t *testing.T) {
+	// Arrange
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := UntilStopping(r.Context())
+		defer cancel()
+		close(started)
+		select {
+		case <-ctx.Done():
+			if Stopping(r.Context()) {
+				_, _ = w.Write([]byte("stopping"))
+			}
+		case <-time.After(time.Minute):
+			_, _ = w.Write([]byte("your turn"))
+		}
+	})
+	cfg := DefaultConfig
+	cfg.Port = freePort(t)
+	ctx, stop := context.WithCancel(context.Background())
+	stopped := make(chan error)
+	go func() { stopped <- Serve(ctx, handler, cfg) }()
+
+	body := make(chan string)
+	go func() {
+		var response *http.Response
+		var err error
+		for range 50 {
+			if response, err = http.Get("http://127.0.0.1:" + cfg.Port + "/games/sals-nil-abri/turn"); err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if err != nil {
+			body <- ""
+			return
+		}
+		defer response.Body.Close()
+		b, _ := io.ReadAll(response.Body)
+		body <- string(b)
+	}()
+	<-started
+
+	// Act
+	begin := time.Now()
+	stop()
+
+	// Assert
+	assert.Equal(t, "stopping", <-body, "Expected the waiting request to stop waiting")
+	assert.NoError(t, <-stopped)
+	assert.Less(t, time.Since(begin), cfg.ShutdownTimeout, "Expected the api to stop without waiting for the timeout")
+}
+
+func TestServe_MetricsOnlyOnTheirOwnPort(t *testing.T) {
+	// Arrange
+	cfg := DefaultConfig
+	cfg.Port = freePort(t)
+	cfg.MetricsPort = freePort(t)
+	ctx, stop := context.WithCancel(context.Background())
+	stopped := make(chan error)
+	go func() { stopped <- Serve(ctx, http.NotFoundHandler(), cfg) }()
+	defer func() {
+		stop()
+		<-stopped
+	}()
+
+	get := func(port string) int {
+		for range 50 {
+			if response, err := http.Get("http://127.0.0.1:" + port + "/metrics"); err == nil {
+				_ = response.Body.Close()
+				return response.StatusCode
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return 0
+	}
+
+	// Act
+	metrics := get(cfg.MetricsPort)
+	api := get(cfg.Port)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, metrics)
+	assert.Equal(t, http.StatusNotFound, api, "Expected the api not to serve the metrics")
+}
+
+func TestServe_NeedsTheCertificateAndTheKey(t *testing.T) {
+	// Arrange
+	cfg := DefaultConfig
+	cfg.CertFile = "server.crt"
+
+	// Act
+	err := Serve(context.Background(), http.NotFoundHandler(), cfg)
+
+	// Assert
+	assert.ErrorContains(t, err, "CONNECT_FOUR_TLS_KEY_FILE")
+}
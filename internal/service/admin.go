@@ -0,0 +1,207 @@
+package service
+
+import (
+	"connectfour/internal/db"
+	"connectfour/internal/model"
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"strings"
+)
+
+// maxSearchResults is the most users that a search returns.
+const maxSearchResults = 50
+
+// NotAllowedError is returned when a moderator or admin tries something that their role doesn't allow.
+type NotAllowedError struct {
+	reason string
+}
+
+func (e NotAllowedError) Error() string {
+	return e.reason
+}
+
+// AdminService lets moderators and admins look up users and games, ban users and abort games.
+type AdminService struct {
+	userService  *UserService
+	gamesService *GamesService
+	gameRepo     db.GameRepository
+}
+
+func NewAdminService(userService *UserService, gamesService *GamesService) *AdminService {
+	return &AdminService{
+		userService:  userService,
+		gamesService: gamesService,
+		gameRepo:     gamesService.gameRepository,
+	}
+}
+
+// Users returns the users whose e-mail address or name contains the query.
+func (s AdminService) Users(ctx context.Context, query string) ([]AdminUserResponse, error) {
+	users, err := s.userService.repo.Search(ctx, strings.TrimSpace(query), maxSearchResults)
+	if err != nil {
+		return nil, err
+	}
+	output := make([]AdminUserResponse, 0, len(users))
+	for _, u := range users {
+		output = append(output, NewAdminUserResponse(u))
+	}
+	return output, nil
+}
+
+func (s AdminService) User(ctx context.Context, email string) (AdminUserResponse, error) {
+	user, err := s.userService.existingUser(ctx, email)
+	if err != nil {
+		return AdminUserResponse{}, err
+	}
+	return NewAdminUserResponse(user), nil
+}
+
+// Ban refuses the user to log in, or lifts the ban. Moderators can only ban players; nobody can ban themselves.
+func (s AdminService) Ban(ctx context.Context, moderatorEmail string, email string, banned bool) (AdminUserResponse, error) {
+	moderator, user, err := s.actorAndUser(ctx, moderatorEmail, email)
+	if err != nil {
+		return AdminUserResponse{}, err
+	}
+	if moderator.Is(user) {
+		return AdminUserResponse{}, NotAllowedError{"you can't ban yourself"}
+	}
+	if !moderator.Role.AtLeast(model.RoleAdmin) && user.Role.AtLeast(model.RoleModerator) {
+		return AdminUserResponse{}, NotAllowedError{"only an admin can ban a moderator or admin"}
+	}
+
+	if err = s.userService.repo.SetBanned(ctx, user.Id, banned); err != nil {
+		return AdminUserResponse{}, err
+	}
+	s.userService.Invalidate(user.Email)
+	user.Banned = banned
+	log.WithContext(ctx).Infof("User %s was banned: %t (by %s)", user.Email, banned, moderator.Email)
+	return NewAdminUserResponse(user), nil
+}
+
+// SetRole changes the role of the user. Admins can't change their own role, so there's always one left.
+func (s AdminService) SetRole(ctx context.Context, adminEmail string, email string, role model.Role) (AdminUserResponse, error) {
+	if !role.Valid() {
+		return AdminUserResponse{}, fmt.Errorf("unknown role '%s'", role)
+	}
+	admin, user, err := s.actorAndUser(ctx, adminEmail, email)
+	if err != nil {
+		return AdminUserResponse{}, err
+	}
+	if !admin.Role.AtLeast(model.RoleAdmin) {
+		return AdminUserResponse{}, NotAllowedError{"only an admin can change roles"}
+	}
+	if admin.Is(user) {
+		return AdminUserResponse{}, NotAllowedError{"you can't change your own role"}
+	}
+
+	if err = s.userService.repo.SetRole(ctx, user.Id, role); err != nil {
+		return AdminUserResponse{}, err
+	}
+	s.userService.Invalidate(user.Email)
+	user.Role = role
+	log.WithContext(ctx).Infof("User %s is now %s (by %s)", user.Email, role, admin.Email)
+	return NewAdminUserResponse(user), nil
+}
+
+// SetBot makes the user a bot account, or a player again. Bots get long-lived tokens, so only admins make them; the
+// tokens of a bot that is made a player again are revoked.
+func (s AdminService) SetBot(ctx context.Context, adminEmail string, email string, bot bool) (AdminUserResponse, error) {
+	admin, user, err := s.actorAndUser(ctx, adminEmail, email)
+	if err != nil {
+		return AdminUserResponse{}, err
+	}
+	if !admin.Role.AtLeast(model.RoleAdmin) {
+		return AdminUserResponse{}, NotAllowedError{"only an admin can make bots"}
+	}
+
+	if err = s.userService.repo.SetBot(ctx, user.Id, bot); err != nil {
+		return AdminUserResponse{}, err
+	}
+	s.userService.Invalidate(user.Email)
+	if user.Bot && !bot {
+		if user, err = s.userService.RevokeTokens(ctx, user.Email); err != nil {
+			return AdminUserResponse{}, err
+		}
+	}
+	user.Bot = bot
+	log.WithContext(ctx).Infof("User %s is a bot: %t (by %s)", user.Email, bot, admin.Email)
+	return NewAdminUserResponse(user), nil
+}
+
+// RevokeTokens refuses the tokens that were issued to the user so far, like a leaked bot token.
+func (s AdminService) RevokeTokens(ctx context.Context, adminEmail string, email string) (AdminUserResponse, error) {
+	admin, user, err := s.actorAndUser(ctx, adminEmail, email)
+	if err != nil {
+		return AdminUserResponse{}, err
+	}
+	if !admin.Role.AtLeast(model.RoleAdmin) {
+		return AdminUserResponse{}, NotAllowedError{"only an admin can revoke tokens"}
+	}
+
+	if user, err = s.userService.RevokeTokens(ctx, user.Email); err != nil {
+		return AdminUserResponse{}, err
+	}
+	log.WithContext(ctx).Infof("The tokens of user %s were revoked by %s", user.Email, admin.Email)
+	return NewAdminUserResponse(user), nil
+}
+
+// Games returns a page of the games of the player (everyone when empty), filtered and paged like the listings of
+// the players.
+func (s AdminService) Games(ctx context.Context, playerEmail string, req GameListRequest) (GamePage, error) {
+	filter, err := s.gamesService.gameFilter(ctx, req)
+	if err != nil {
+		return GamePage{}, err
+	}
+	if playerEmail != "" {
+		player, err := s.userService.existingUser(ctx, playerEmail)
+		if err != nil {
+			return GamePage{}, err
+		}
+		filter.PlayerId = player.Id
+	}
+	return s.gamesService.listGames(ctx, filter)
+}
+
+// AbortGame stops a game that hasn't finished yet.
+func (s AdminService) AbortGame(ctx context.Context, moderatorEmail string, key string) (GameStateResponse, error) {
+	moderator, err := s.userService.existingUser(ctx, moderatorEmail)
+	if err != nil {
+		return GameStateResponse{}, err
+	}
+	game, err := s.gamesService.AbortGame(ctx, key, moderator)
+	if err != nil {
+		return GameStateResponse{}, err
+	}
+	log.WithContext(ctx).Infof("Game %s was aborted by %s", key, moderator.Email)
+	return NewGameStateResponse(game), nil
+}
+
+// Stats counts the users and games, and the players that are online right now.
+func (s AdminService) Stats(ctx context.Context) (StatsResponse, error) {
+	users, err := s.userService.repo.Stats(ctx)
+	if err != nil {
+		return StatsResponse{}, err
+	}
+	games, err := s.gameRepo.CountByStatus(ctx)
+	if err != nil {
+		return StatsResponse{}, err
+	}
+	return StatsResponse{
+		Users:         users.Total,
+		Bots:          users.Bots,
+		VerifiedUsers: users.Verified,
+		BannedUsers:   users.Banned,
+		OnlineUsers:   s.userService.Presence().Online(),
+		Games:         games,
+	}, nil
+}
+
+func (s AdminService) actorAndUser(ctx context.Context, actorEmail string, email string) (model.User, model.User, error) {
+	actor, err := s.userService.existingUser(ctx, actorEmail)
+	if err != nil {
+		return model.User{}, model.User{}, err
+	}
+	user, err := s.userService.existingUser(ctx, email)
+	return actor, user, err
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"connectfour/internal/model"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+)
+
+func withRole(u model.User, role model.Role) model.User {
+	u.Role = role
+	return u
+}
+
+func TestAdminService_Ban(t *testing.T) {
+	// Arrange
+	moderator := withRole(user1, model.RoleModerator)
+	player := withRole(user2, model.RolePlayer)
+	gs, ur, _ := mockedGamesService()
+	ur.On("FindByEmail", mock.Anything, moderator.Email).Return(moderator, nil)
+	ur.On("FindByEmail", mock.Anything, player.Email).Return(player, nil)
+	ur.On("SetBanned", mock.Anything, player.Id, true).Return(nil)
+	s := NewAdminService(gs.userService, gs)
+
+	// Act
+	banned, err := s.Ban(context.Background(), moderator.Email, player.Email, true)
+	_, errSelf := s.Ban(context.Background(), moderator.Email, moderator.Email, true)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, banned.Banned)
+	ur.AssertCalled(t, "SetBanned", mock.Anything, player.Id, true)
+	assert.ErrorAs(t, errSelf, &NotAllowedError{}, "Expected nobody to be able to ban themselves")
+}
+
+func TestAdminService_Ban_ModeratorCantBanModerator(t *testing.T) {
+	// Arrange
+	moderator := withRole(user1, model.RoleModerator)
+	other := withRole(user2, model.RoleModerator)
+	gs, ur, _ := mockedGamesService()
+	ur.On("FindByEmail", mock.Anything, moderator.Email).Return(moderator, nil)
+	ur.On("FindByEmail", mock.Anything, other.Email).Return(other, nil)
+	s := NewAdminService(gs.userService, gs)
+
+	// Act
+	_, err := s.Ban(context.Background(), moderator.Email, other.Email, true)
+
+	// Assert
+	assert.ErrorAs(t, err, &NotAllowedError{})
+	ur.AssertNotCalled(t, "SetBanned", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAdminService_SetRole(t *testing.T) {
+	// Arrange
+	admin := withRole(user1, model.RoleAdmin)
+	player := withRole(user2, model.RolePlayer)
+	gs, ur, _ := mockedGamesService()
+	ur.On("FindByEmail", mock.Anything, admin.Email).Return(admin, nil)
+	ur.On("FindByEmail", mock.Anything, player.Email).Return(player, nil)
+	ur.On("SetRole", mock.Anything, player.Id, model.RoleModerator).Return(nil)
+	s := NewAdminService(gs.userService, gs)
+
+	// Act
+	promoted, err := s.SetRole(context.Background(), admin.Email, player.Email, model.RoleModerator)
+	_, errUnknown := s.SetRole(context.Background(), admin.Email, player.Email, "superuser")
+	_, errSelf := s.SetRole(context.Background(), admin.Email, admin.Email, model.RolePlayer)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, model.RoleModerator, promoted.Role)
+	assert.Error(t, errUnknown)
+	assert.ErrorAs(t, errSelf, &NotAllowedError{}, "Expected an admin not to be able to demote themselves")
+}
+
+func TestAdminService_SetBot(t *testing.T) {
+	// Arrange
+	admin := withRole(user1, model.RoleAdmin)
+	moderator := model.User{Id: 3, Name: "Lucy", Email: "lucy@evilnerd.nl", Role: model.RoleModerator}
+	bot := withRole(user2, model.RolePlayer)
+	bot.Bot = true
+	gs, ur, _ := mockedGamesService()
+	ur.On("FindByEmail", mock.Anything, admin.Email).Return(admin, nil)
+	ur.On("FindByEmail", mock.Anything, moderator.Email).Return(moderator, nil)
+	ur.On("FindByEmail", mock.Anything, bot.Email).Return(bot, nil)
+	ur.On("SetBot", mock.Anything, bot.Id, false).Return(nil)
+	ur.On("RevokeTokens", mock.Anything, bot.Id).Return(nil)
+	s := NewAdminService(gs.userService, gs)
+
+	// Act
+	player, err := s.SetBot(context.Background(), admin.Email, bot.Email, false)
+	_, errModerator := s.SetBot(context.Background(), moderator.Email, bot.Email, true)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.False(t, player.Bot)
+	ur.AssertCalled(t, "RevokeTokens", mock.Anything, bot.Id)
+	assert.ErrorAs(t, errModerator, &NotAllowedError{}, "Expected only admins to make bots")
+	ur.AssertNotCalled(t, "SetBot", mock.Anything, bot.Id, true)
+}
+
+func TestAdminService_AbortGame(t *testing.T) {
+	// Arrange
+	moderator := withRole(user1, model.RoleModerator)
+	game := model.NewGame(user2, true)
+	gs, ur, gr := mockedGamesService()
+	ur.On("FindByEmail", mock.Anything, moderator.Email).Return(moderator, nil)
+	gr.On("Fetch", mock.Anything, game.Key).Return(game, nil)
+	gr.On("Save", mock.Anything, mock.AnythingOfType("model.Game")).Return(true)
+	s := NewAdminService(gs.userService, gs)
+	var finished []GameEvent
+	gs.Subscribe(func(e GameEvent) { finished = append(finished, e) })
+
+	// Act
+	aborted, err := s.AbortGame(context.Background(), moderator.Email, game.Key)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, model.Aborted, aborted.Status)
+	assert.Len(t, finished, 1)
+	assert.Equal(t, GameFinishedEvent, finished[0].Type)
+}
+
+func TestAdminService_Games_Pages(t *testing.T) {
+	// Arrange
+	list := mockedGames()
+	gs, ur, gr := mockedGamesService()
+	ur.On("FindByEmail", mock.Anything, user2.Email).Return(user2, nil)
+	gr.On("List", mock.Anything, mock.AnythingOfType("model.GameFilter")).Return(list, nil)
+	s := NewAdminService(gs.userService, gs)
+
+	// Act
+	all, err := s.Games(context.Background(), "", GameListRequest{Status: model.Started})
+	page, _ := s.Games(context.Background(), user2.Email, GameListRequest{Limit: 2})
+
+	// Assert
+	assert.NoError(t, err)
+	gr.AssertCalled(t, "List", mock.Anything, model.GameFilter{Status: model.Started, Limit: defaultGamePageSize + 1})
+	gr.AssertCalled(t, "List", mock.Anything, model.GameFilter{PlayerId: user2.Id, Limit: 3})
+	assert.Len(t, all.Games, 3)
+	assert.Len(t, page.Games, 2, "Expected the admin listing to be paged like the other listings")
+	assert.NotEmpty(t, page.NextCursor)
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"connectfour/internal/db"
+	"connectfour/internal/model"
+	"context"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+const (
+	maxAuditEntries = 1000 // the most entries that can be read at once.
+	maxAuditDetails = 1000 // the longest details that are stored.
+)
+
+// AuditService keeps the audit log of security-relevant and game events, so disputes can be looked into later.
+type AuditService struct {
+	repo db.AuditRepository
+	now  func() time.Time
+}
+
+func NewAuditService(repo db.AuditRepository) *AuditService {
+	return &AuditService{
+		repo: repo,
+		now:  time.Now,
+	}
+}
+
+// Record appends the entry to the audit log. When it can't be stored, that is logged, but the action that was
+// audited isn't failed for it.
+func (s AuditService) Record(ctx context.Context, e model.AuditEntry) {
+	e.Time = s.now()
+	e.Actor = strings.ToLower(e.Actor)
+	if len(e.Details) > maxAuditDetails {
+		e.Details = e.Details[:maxAuditDetails]
+	}
+	if err := s.repo.Append(ctx, e); err != nil {
+		log.WithContext(ctx).Errorf("Could not record %s by %s in the audit log: %v", e.Action, e.Actor, err)
+	}
+}
+
+// Entries returns the entries that match the filter, the newest first.
+func (s AuditService) Entries(ctx context.Context, f model.AuditFilter) ([]AuditEntryResponse, error) {
+	if f.Limit <= 0 || f.Limit > maxAuditEntries {
+		f.Limit = maxAuditEntries
+	}
+	f.Actor = strings.ToLower(f.Actor)
+	entries, err := s.repo.List(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	output := make([]AuditEntryResponse, 0, len(entries))
+	for _, e := range entries {
+		output = append(output, NewAuditEntryResponse(e))
+	}
+	return output, nil
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"connectfour/internal/db"
+	"connectfour/internal/model"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditService_Record(t *testing.T) {
+	// Arrange
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	repo := db.NewMockAuditRepository()
+	repo.On("Append", mock.Anything, mock.AnythingOfType("model.AuditEntry")).Return(nil)
+	s := NewAuditService(repo)
+	s.now = func() time.Time { return now }
+
+	// Act
+	s.Record(context.Background(), model.AuditEntry{
+		Action:  model.AuditGameMove,
+		Actor:   "Dick@EvilNerd.nl",
+		Subject: "sals-nil-abri",
+		Details: strings.Repeat("x", 2000),
+	})
+
+	// Assert
+	entry := repo.Calls[0].Arguments.Get(1).(model.AuditEntry)
+	assert.Equal(t, now, entry.Time)
+	assert.Equal(t, "dick@evilnerd.nl", entry.Actor)
+	assert.Len(t, entry.Details, maxAuditDetails)
+}
+
+func TestAuditService_Record_IgnoresStorageErrors(t *testing.T) {
+	// Arrange
+	repo := db.NewMockAuditRepository()
+	repo.On("Append", mock.Anything, mock.AnythingOfType("model.AuditEntry")).Return(errors.New("database is down"))
+	s := NewAuditService(repo)
+
+	// Act & Assert
+	assert.NotPanics(t, func() { s.Record(context.Background(), model.AuditEntry{Action: model.AuditLogin}) })
+}
+
+func TestAuditService_Entries_CapsTheLimit(t *testing.T) {
+	// Arrange
+	repo := db.NewMockAuditRepository()
+	repo.On("List", mock.Anything, mock.AnythingOfType("model.AuditFilter")).Return([]model.AuditEntry{{Id: 1}}, nil)
+	s := NewAuditService(repo)
+
+	// Act
+	entries, err := s.Entries(context.Background(), model.AuditFilter{Limit: 1000000})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	repo.AssertCalled(t, "List", mock.Anything, model.AuditFilter{Limit: maxAuditEntries})
+}
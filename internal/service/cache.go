@@ -1,45 +1,171 @@
 package service
 
 import (
+	"connectfour/internal/metrics"
+	"container/list"
 	"sync"
 	"time"
 )
 
+// Cache keeps at most capacity values, for ttl each. When it's full, storing a value evicts the least recently used
+// one. Expired values aren't returned, and are removed by a sweep in the background; Close stops that sweep.
 type Cache[K comparable, V any] struct {
-	m   sync.Map
-	t   sync.Map
-	ttl time.Duration
+	name     string // counts the lookups and evictions in the metrics.
+	capacity int
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	entries map[K]*list.Element // the elements of order, by key.
+	order   *list.List          // the entries, the most recently used first.
+	stats   CacheStats
+	stop    chan struct{}
+	closed  sync.Once
+}
+
+type cacheEntry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+// CacheStats counts how well a Cache does.
+type CacheStats struct {
+	Hits      uint64 // lookups that found a value.
+	Misses    uint64 // lookups that didn't find a (live) value.
+	Evictions uint64 // values that were removed to make room, or because they expired.
+	Size      int    // values in the cache now.
 }
 
-func NewCache[K comparable, V any](ttl time.Duration) *Cache[K, V] {
-	return &Cache[K, V]{
-		ttl: ttl,
+func NewCache[K comparable, V any](name string, capacity int, ttl time.Duration) *Cache[K, V] {
+	c := &Cache[K, V]{
+		name:     name,
+		capacity: max(capacity, 1),
+		ttl:      ttl,
+		now:      time.Now,
+		entries:  make(map[K]*list.Element),
+		order:    list.New(),
+		stop:     make(chan struct{}),
 	}
+	if ttl > 0 {
+		go c.sweep(ttl)
+	}
+	return c
 }
 
 func (c *Cache[K, V]) Load(key K) (value V, ok bool) {
-	v, ok := c.m.Load(key)
-	if ok {
-		created, ok := c.t.Load(key)
-		// check if the item's create time + the TTL is still later than now.
-		if ok && created.(time.Time).Add(c.ttl).After(time.Now()) {
-			// item found, and still within ttl
-			return v.(V), ok
-		} else {
-			// ttl expired, so remove the item from the cache.
-			c.Delete(key)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, found := c.entries[key]; found {
+		entry := element.Value.(*cacheEntry[K, V])
+		if c.now().Before(entry.expires) {
+			c.order.MoveToFront(element)
+			c.stats.Hits++
+			metrics.CacheLookup(c.name, true)
+			return entry.value, true
 		}
+		c.evict(element, "expired")
 	}
+	c.stats.Misses++
+	metrics.CacheLookup(c.name, false)
 	return value, false
 }
 
 func (c *Cache[K, V]) Store(key K, value V) {
-	// TODO: implement item limit.
-	c.m.Store(key, value)
-	c.t.Store(key, time.Now())
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := c.now().Add(c.ttl)
+	if element, found := c.entries[key]; found {
+		entry := element.Value.(*cacheEntry[K, V])
+		entry.value, entry.expires = value, expires
+		c.order.MoveToFront(element)
+		return
+	}
+	for c.order.Len() >= c.capacity {
+		c.evict(c.order.Back(), "capacity")
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry[K, V]{key: key, value: value, expires: expires})
 }
 
+// Delete removes the value of the key, when it's stale. That isn't counted as an eviction.
 func (c *Cache[K, V]) Delete(key K) {
-	c.m.Delete(key)
-	c.t.Delete(key)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, found := c.entries[key]; found {
+		c.remove(element)
+	}
+}
+
+// DeleteFunc removes the values for which stale returns true, and returns how many it removed.
+func (c *Cache[K, V]) DeleteFunc(stale func(key K, value V) bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := 0
+	for element := c.order.Front(); element != nil; {
+		next := element.Next()
+		if entry := element.Value.(*cacheEntry[K, V]); stale(entry.key, entry.value) {
+			c.remove(element)
+			removed++
+		}
+		element = next
+	}
+	return removed
+}
+
+// Stats returns the numbers of hits, misses and evictions since the cache was created.
+func (c *Cache[K, V]) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Size = c.order.Len()
+	return stats
+}
+
+// Close stops the sweep of the expired values. The cache can still be used, but only expires values on Load.
+func (c *Cache[K, V]) Close() {
+	c.closed.Do(func() { close(c.stop) })
+}
+
+func (c *Cache[K, V]) sweep(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.removeExpired()
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+func (c *Cache[K, V]) removeExpired() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	for element := c.order.Front(); element != nil; {
+		next := element.Next()
+		if entry := element.Value.(*cacheEntry[K, V]); !now.Before(entry.expires) {
+			c.evict(element, "expired")
+		}
+		element = next
+	}
+}
+
+// evict removes the element and counts it as an eviction. The caller holds the lock.
+func (c *Cache[K, V]) evict(element *list.Element, reason string) {
+	c.remove(element)
+	c.stats.Evictions++
+	metrics.CacheEviction(c.name, reason)
+}
+
+func (c *Cache[K, V]) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*cacheEntry[K, V]).key)
 }
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestCache returns a cache with a clock that only moves when the returned func is called.
+func newTestCache(capacity int, ttl time.Duration) (*Cache[string, int], func(time.Duration)) {
+	c := NewCache[string, int]("test", capacity, ttl)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	c.mu.Lock()
+	c.now = func() time.Time { return now }
+	c.mu.Unlock()
+	return c, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestCache_EvictsTheLeastRecentlyUsed(t *testing.T) {
+	// Arrange
+	c, _ := newTestCache(2, time.Minute)
+	defer c.Close()
+	c.Store("dick", 1)
+	c.Store("sanae", 2)
+	c.Load("dick")
+
+	// Act
+	c.Store("hiro", 3)
+
+	// Assert
+	_, sanae := c.Load("sanae")
+	_, dick := c.Load("dick")
+	_, hiro := c.Load("hiro")
+	assert.False(t, sanae, "Expected the least recently used value to be evicted")
+	assert.True(t, dick)
+	assert.True(t, hiro)
+	assert.Equal(t, CacheStats{Hits: 3, Misses: 1, Evictions: 1, Size: 2}, c.Stats())
+}
+
+func TestCache_Expires(t *testing.T) {
+	// Arrange
+	c, wait := newTestCache(10, time.Minute)
+	defer c.Close()
+	c.Store("dick", 1)
+	wait(30 * time.Second)
+	c.Store("sanae", 2)
+
+	// Act
+	wait(45 * time.Second)
+	_, dick := c.Load("dick")
+	c.removeExpired()
+
+	// Assert
+	assert.False(t, dick, "Expected the value to have expired")
+	assert.Equal(t, CacheStats{Misses: 1, Evictions: 1, Size: 1}, c.Stats())
+	wait(time.Minute)
+	c.removeExpired()
+	assert.Equal(t, 0, c.Stats().Size, "Expected the sweep to remove the expired value")
+	assert.Equal(t, uint64(2), c.Stats().Evictions)
+}
+
+func TestCache_StoreRefreshes(t *testing.T) {
+	// Arrange
+	c, wait := newTestCache(10, time.Minute)
+	defer c.Close()
+	c.Store("dick", 1)
+	wait(45 * time.Second)
+
+	// Act
+	c.Store("dick", 2)
+	wait(45 * time.Second)
+
+	// Assert
+	value, ok := c.Load("dick")
+	assert.True(t, ok, "Expected storing the value again to reset its ttl")
+	assert.Equal(t, 2, value)
+}
+
+func TestCache_Delete(t *testing.T) {
+	// Arrange
+	c, _ := newTestCache(10, time.Minute)
+	defer c.Close()
+	c.Store("dick@evilnerd.nl", 1)
+	c.Store("sanae@evilnerd.nl", 2)
+	c.Store("hiro@example.com", 3)
+
+	// Act
+	c.Delete("hiro@example.com")
+	removed := c.DeleteFunc(func(key string, _ int) bool { return strings.HasSuffix(key, "@evilnerd.nl") })
+
+	// Assert
+	assert.Equal(t, 2, removed)
+	assert.Equal(t, CacheStats{}, c.Stats(), "Expected deleting not to count as evicting")
+}
+
+func TestCache_SweepsInTheBackground(t *testing.T) {
+	// Arrange
+	c := NewCache[string, int]("test", 10, 10*time.Millisecond)
+	defer c.Close()
+
+	// Act
+	c.Store("dick", 1)
+
+	// Assert
+	assert.Eventually(t, func() bool { return c.Stats().Size == 0 }, time.Second, 5*time.Millisecond)
+}
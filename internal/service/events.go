@@ -0,0 +1,49 @@
+package service
+
+import (
+	"connectfour/internal/model"
+	"sync"
+)
+
+type GameEventType string
+
+const (
+	GameCreatedEvent  GameEventType = "game.created"
+	GameJoinedEvent   GameEventType = "game.joined"
+	MovePlayedEvent   GameEventType = "game.move"
+	GameFinishedEvent GameEventType = "game.finished"
+
+	// NotificationEvent isn't a game event, but the event of the notifications that the webhook channel posts.
+	NotificationEvent GameEventType = "notification"
+)
+
+// GameEvent describes something that happened to a game. Actor is the player that caused it.
+type GameEvent struct {
+	Type  GameEventType
+	Game  model.Game
+	Actor model.User
+}
+
+type GameEventHandler func(event GameEvent)
+
+// gameEvents keeps the handlers that want to know about game events. Handlers are called synchronously, in the
+// order they subscribed.
+type gameEvents struct {
+	mu       sync.RWMutex
+	handlers []GameEventHandler
+}
+
+func (e *gameEvents) subscribe(handler GameEventHandler) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.handlers = append(e.handlers, handler)
+}
+
+func (e *gameEvents) publish(event GameEvent) {
+	e.mu.RLock()
+	handlers := append([]GameEventHandler{}, e.handlers...)
+	e.mu.RUnlock()
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
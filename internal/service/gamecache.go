@@ -0,0 +1,89 @@
+package service
+
+import (
+	"connectfour/internal/db"
+	"connectfour/internal/model"
+	"context"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// gameCacheSize is the most games that are cached, which covers the games that are played at once.
+	gameCacheSize = 1_000
+
+	// saveStripes is the number of save counters; the games share them by the hash of their key.
+	saveStripes = 64
+)
+
+// CachingGameRepository serves the games that are fetched again and again, like the game that's being polled while
+// it's played, from memory. A game is dropped from the cache when it's saved, so this instance of the api never
+// serves a stale game. Other instances may, for at most the ttl, so keep it short when running more than one.
+type CachingGameRepository struct {
+	db.GameRepository
+	games *Cache[string, model.Game]
+	// saves counts the saves, so that a Fetch that raced with a Save doesn't cache the game as it was before. The
+	// lock makes checking the count and caching the game one step.
+	saves *[saveStripes]atomic.Uint64
+	lock  *sync.Mutex
+}
+
+var _ db.GameRepository = CachingGameRepository{}
+
+func NewCachingGameRepository(repo db.GameRepository, ttl time.Duration) CachingGameRepository {
+	return CachingGameRepository{
+		GameRepository: repo,
+		games:          NewCache[string, model.Game]("games", gameCacheSize, ttl),
+		saves:          new([saveStripes]atomic.Uint64),
+		lock:           &sync.Mutex{},
+	}
+}
+
+// Fetch returns the cached game, or fetches and caches it. Games that aren't found aren't cached, so a game that's
+// created right after is found.
+func (r CachingGameRepository) Fetch(ctx context.Context, key string) (model.Game, error) {
+	if game, ok := r.games.Load(key); ok {
+		return game, nil
+	}
+	saves := r.savesOf(key)
+	before := saves.Load()
+	game, err := r.GameRepository.Fetch(ctx, key)
+	if err == nil {
+		r.lock.Lock()
+		if saves.Load() == before {
+			r.games.Store(key, game)
+		}
+		r.lock.Unlock()
+	}
+	return game, err
+}
+
+// Save drops the game from the cache, also when saving fails, so the next Fetch reads what was stored.
+func (r CachingGameRepository) Save(ctx context.Context, game model.Game) bool {
+	saved := r.GameRepository.Save(ctx, game)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.savesOf(game.Key).Add(1)
+	r.games.Delete(game.Key)
+	return saved
+}
+
+func (r CachingGameRepository) savesOf(key string) *atomic.Uint64 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return &r.saves[h.Sum32()%saveStripes]
+}
+
+// InvalidateUser drops the games of the user, which have the old name of the user. Register it with
+// UserService.OnInvalidate.
+func (r CachingGameRepository) InvalidateUser(email string) {
+	r.games.DeleteFunc(func(_ string, game model.Game) bool {
+		return strings.EqualFold(game.Player1.Email, email) ||
+			strings.EqualFold(game.Player2.Email, email) ||
+			strings.EqualFold(game.Invitee.Email, email)
+	})
+}
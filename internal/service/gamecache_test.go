@@ -0,0 +1,104 @@
+package service
+
+import (
+	"connectfour/internal/db"
+	"connectfour/internal/model"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func newCachedGame() model.Game {
+	game := model.NewGame(user1, true)
+	_ = game.Join(user2)
+	return game
+}
+
+func TestCachingGameRepository_Fetch(t *testing.T) {
+	// Arrange
+	game := newCachedGame()
+	repo := db.NewMockGameRepository()
+	repo.On("Fetch", mock.Anything, game.Key).Return(game, nil)
+	repo.On("Fetch", mock.Anything, "dolk-mop-rits").Return(model.Game{}, errors.New("no rows"))
+	r := NewCachingGameRepository(repo, time.Minute)
+
+	// Act
+	first, _ := r.Fetch(context.Background(), game.Key)
+	second, _ := r.Fetch(context.Background(), game.Key)
+	_, err1 := r.Fetch(context.Background(), "dolk-mop-rits")
+	_, err2 := r.Fetch(context.Background(), "dolk-mop-rits")
+
+	// Assert
+	assert.Equal(t, game, first)
+	assert.Equal(t, game, second)
+	assert.Error(t, err1)
+	assert.Error(t, err2)
+	repo.AssertNumberOfCalls(t, "Fetch", 3) // a game that isn't found isn't cached.
+}
+
+func TestCachingGameRepository_Save(t *testing.T) {
+	// Arrange
+	game := newCachedGame()
+	played := game
+	_ = played.Play(user1, 4)
+	repo := db.NewMockGameRepository()
+	repo.On("Fetch", mock.Anything, game.Key).Return(game, nil).Once()
+	repo.On("Fetch", mock.Anything, game.Key).Return(played, nil)
+	repo.On("Save", mock.Anything, played).Return(true)
+	r := NewCachingGameRepository(repo, time.Minute)
+	_, _ = r.Fetch(context.Background(), game.Key)
+
+	// Act
+	saved := r.Save(context.Background(), played)
+	fetched, _ := r.Fetch(context.Background(), game.Key)
+
+	// Assert
+	assert.True(t, saved)
+	assert.Equal(t, played, fetched, "Expected the saved game to be fetched again")
+	repo.AssertNumberOfCalls(t, "Fetch", 2)
+}
+
+func TestCachingGameRepository_FetchDuringSave(t *testing.T) {
+	// Arrange
+	game := newCachedGame()
+	played := game
+	_ = played.Play(user1, 4)
+	repo := db.NewMockGameRepository()
+	repo.On("Save", mock.Anything, played).Return(true)
+	r := NewCachingGameRepository(repo, time.Minute)
+	// the move is saved while the game is being read, so the game that is read is already stale.
+	repo.On("Fetch", mock.Anything, game.Key).Return(game, nil).Once().Run(func(mock.Arguments) {
+		r.Save(context.Background(), played)
+	})
+	repo.On("Fetch", mock.Anything, game.Key).Return(played, nil)
+
+	// Act
+	stale, _ := r.Fetch(context.Background(), game.Key)
+	fetched, _ := r.Fetch(context.Background(), game.Key)
+
+	// Assert
+	assert.Equal(t, game, stale)
+	assert.Equal(t, played, fetched, "Expected the stale game not to be cached")
+}
+
+func TestCachingGameRepository_InvalidateUser(t *testing.T) {
+	// Arrange
+	game := newCachedGame()
+	renamed := game
+	renamed.Player2.Name = "Sanae-chan"
+	repo := db.NewMockGameRepository()
+	repo.On("Fetch", mock.Anything, game.Key).Return(game, nil).Once()
+	repo.On("Fetch", mock.Anything, game.Key).Return(renamed, nil)
+	r := NewCachingGameRepository(repo, time.Minute)
+	_, _ = r.Fetch(context.Background(), game.Key)
+
+	// Act
+	r.InvalidateUser("Sanae@EvilNerd.nl")
+	fetched, _ := r.Fetch(context.Background(), game.Key)
+
+	// Assert
+	assert.Equal(t, "Sanae-chan", fetched.Player2.Name)
+}
@@ -2,125 +2,243 @@ package service
 
 import (
 	"connectfour/internal/db"
+	"connectfour/internal/metrics"
 	"connectfour/internal/model"
+	"connectfour/internal/tracing"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"strings"
+	"time"
+)
+
+// turnRecheckInterval is how often WaitForTurn looks at the game in the database, in case the game was changed
+// without this instance knowing about it.
+const turnRecheckInterval = 5 * time.Second
+
+const (
+	defaultGamePageSize = 20  // the games on a page when the request doesn't say.
+	maxGamePageSize     = 100 // the most games on a page.
 )
 
 type GamesService struct {
 	userService    *UserService
 	gameRepository db.GameRepository
+	turns          *turnNotifier
+	events         *gameEvents
 }
 
 func NewGamesService(userService *UserService, gamesRepository db.GameRepository) *GamesService {
-	return &GamesService{
+	s := &GamesService{
 		userService:    userService,
 		gameRepository: gamesRepository,
+		turns:          newTurnNotifier(),
+		events:         &gameEvents{},
 	}
+	s.Subscribe(userService.presence.onGameEvent)
+	s.Subscribe(countGameEvent)
+	return s
+}
+
+// countGameEvent updates the game counters in the metrics.
+func countGameEvent(event GameEvent) {
+	switch event.Type {
+	case GameCreatedEvent:
+		metrics.GameCreated()
+	case MovePlayedEvent:
+		metrics.MovePlayed()
+	case GameFinishedEvent:
+		metrics.GameFinished(event.Game)
+	}
+}
+
+// Subscribe registers a handler that is called after a game was created, joined, played or finished.
+func (s GamesService) Subscribe(handler GameEventHandler) {
+	s.events.subscribe(handler)
 }
 
 func init() {
 }
 
-func (s GamesService) GetGame(key string) model.Game {
-	game, err := s.gameRepository.Fetch(key)
+// CountByStatus counts all games per status.
+func (s GamesService) CountByStatus(ctx context.Context) (map[model.GameStatus]int, error) {
+	return s.gameRepository.CountByStatus(ctx)
+}
+
+func (s GamesService) GetGame(ctx context.Context, key string) model.Game {
+	game, err := s.gameRepository.Fetch(ctx, key)
 	if err != nil {
-		log.Errorf("Error fetching game from db: %v", err)
+		log.WithContext(ctx).Errorf("Error fetching game from db: %v", err)
 		return model.Game{}
 	}
 	return game
 }
 
-func (s GamesService) GetGameState(key string) GameStateResponse {
-	game := s.GetGame(key)
+func (s GamesService) GetGameState(ctx context.Context, key string) GameStateResponse {
+	game := s.GetGame(ctx, key)
 	if game.Key != key {
 		return GameStateResponse{}
 	}
 	return NewGameStateResponse(game)
 }
 
-func (s GamesService) AllOpenGames(email string) []NewGameResponse {
-	user, err := s.userService.FindUserByEmail(email)
+// OpenGames returns a page of the public games that are waiting for a second player. The status and public filters of
+// the request don't apply, and the opponent is the player that created the game.
+func (s GamesService) OpenGames(ctx context.Context, req GameListRequest) (GamePage, error) {
+	filter, err := s.gameFilter(ctx, req)
 	if err != nil {
-		log.Errorf("Error finding user by email '%s': %v", email, err)
-		return []NewGameResponse{}
+		return GamePage{}, err
 	}
+	public := true
+	filter.Status, filter.Public = model.Created, &public
+	return s.listGames(ctx, filter)
+}
 
-	games, err := s.gameRepository.List(user.Id, string(model.Created))
+// MyGames returns a page of the games that the user plays in.
+func (s GamesService) MyGames(ctx context.Context, email string, req GameListRequest) (GamePage, error) {
+	log.WithContext(ctx).Debugf("Listing the games of user %s", email)
+	user, err := s.userService.existingUser(ctx, email)
 	if err != nil {
-		log.Errorf("Error getting games: %v\n", err)
-		return []NewGameResponse{}
+		return GamePage{}, err
 	}
+	filter, err := s.gameFilter(ctx, req)
+	if err != nil {
+		return GamePage{}, err
+	}
+	filter.PlayerId = user.Id
+	return s.listGames(ctx, filter)
+}
 
-	output := make([]NewGameResponse, 0)
-	for _, game := range games {
-		if game.Public {
-			output = append(output, NewGameResponseFromGame(game))
+// gameFilter turns the request into a filter, with the page size capped and the opponent and cursor looked up.
+func (s GamesService) gameFilter(ctx context.Context, req GameListRequest) (model.GameFilter, error) {
+	filter := model.GameFilter{
+		Status: req.Status,
+		Public: req.Public,
+		From:   req.From,
+		Until:  req.Until,
+		Order:  req.Order,
+		Limit:  req.Limit,
+	}
+	if filter.Order != "" && filter.Order != model.NewestFirst && filter.Order != model.OldestFirst {
+		return model.GameFilter{}, fmt.Errorf("the order must be %s or %s", model.NewestFirst, model.OldestFirst)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultGamePageSize
+	}
+	filter.Limit = min(filter.Limit, maxGamePageSize)
+
+	if req.Opponent != "" {
+		opponent, err := s.userService.existingUser(ctx, req.Opponent)
+		if err != nil {
+			return model.GameFilter{}, err
 		}
+		filter.OpponentId = opponent.Id
 	}
-	return output
+	if req.Cursor != "" {
+		cursor, err := decodeGameCursor(req.Cursor)
+		if err != nil {
+			return model.GameFilter{}, err
+		}
+		filter.After = &cursor
+	}
+	return filter, nil
 }
 
-func (s GamesService) AllMyGames(email string) []NewGameResponse {
-	log.Debugf("Listing all games for user %s", email)
-	user, err := s.userService.FindUserByEmail(email)
+// listGames returns a page of the games. It asks for one game more than fits on the page, to know if there's a next
+// page without counting the games.
+func (s GamesService) listGames(ctx context.Context, filter model.GameFilter) (GamePage, error) {
+	pageSize := filter.Limit
+	filter.Limit++
+	games, err := s.gameRepository.List(ctx, filter)
 	if err != nil {
-		log.Errorf("Error finding user by email '%s': %v", email, err)
-		return []NewGameResponse{}
+		log.WithContext(ctx).Errorf("Error getting games: %v\n", err)
+		return GamePage{}, errors.New("the games could not be listed")
 	}
 
-	if email == "" || user.Empty() {
-		log.Errorf("No active user.")
-		return []NewGameResponse{}
+	page := GamePage{Games: make([]GameListingResponse, 0, min(len(games), pageSize))}
+	if len(games) > pageSize {
+		games = games[:pageSize]
+		last := games[pageSize-1]
+		page.NextCursor = encodeGameCursor(model.GameCursor{CreatedAt: last.CreatedAt, Key: last.Key})
+	}
+	for _, game := range games {
+		page.Games = append(page.Games, NewGameListingResponse(game))
 	}
+	return page, nil
+}
+
+// encodeGameCursor makes the cursor opaque, so clients pass it back as they got it instead of building their own.
+func encodeGameCursor(c model.GameCursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(c.CreatedAt.Format(time.RFC3339Nano) + " " + c.Key))
+}
 
-	output := make([]NewGameResponse, 0)
-	games, err := s.gameRepository.List(user.Id, "")
+func decodeGameCursor(cursor string) (model.GameCursor, error) {
+	invalid := errors.New("the cursor is invalid, start again from the first page")
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
 	if err != nil {
-		log.Errorf("Error getting games: %v\n", err)
+		return model.GameCursor{}, invalid
 	}
-	for _, game := range games {
-		output = append(output, NewGameResponseFromGame(game))
+	createdAt, key, found := strings.Cut(string(decoded), " ")
+	if !found || key == "" {
+		return model.GameCursor{}, invalid
 	}
-	return output
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return model.GameCursor{}, invalid
+	}
+	return model.GameCursor{CreatedAt: t, Key: key}, nil
 }
 
-func (s GamesService) GameExists(key string) bool {
-	game, err := s.gameRepository.Fetch(key)
+func (s GamesService) GameExists(ctx context.Context, key string) bool {
+	game, err := s.gameRepository.Fetch(ctx, key)
 	if err != nil {
-		log.Errorf("Error determining if game '%s' exists or not: %v", key, err)
+		log.WithContext(ctx).Errorf("Error determining if game '%s' exists or not: %v", key, err)
 		return false
 	}
 	return game.Key == key
 }
 
-func (s GamesService) JoinGame(key string, player2Email string) error {
-	user, err := s.userService.FindUserByEmail(player2Email)
+func (s GamesService) JoinGame(ctx context.Context, key string, player2Email string) error {
+	user, err := s.userService.FindUserByEmail(ctx, player2Email)
 	if err != nil {
-		log.Errorf("Error fetching user: %v", err)
+		log.WithContext(ctx).Errorf("Error fetching user: %v", err)
 		return err
 	}
 
-	game, err := s.gameRepository.Fetch(key)
+	game, err := s.gameRepository.Fetch(ctx, key)
 	if err != nil {
 		return err
 	}
 
+	wasCreated := game.Status == model.Created
 	err = game.Join(user)
 	if err != nil {
 		return err
 	}
 
-	s.gameRepository.Save(game)
+	if !s.gameRepository.Save(ctx, game) {
+		return errors.New("the game could not be saved")
+	}
+	s.turns.notify(key)
+	if wasCreated && game.Status == model.Started {
+		s.events.publish(GameEvent{Type: GameJoinedEvent, Game: game, Actor: user})
+	}
 	return nil
 }
 
-func (s GamesService) PlayMove(key string, playerEmail string, column int) error {
-	user, err := s.userService.FindUserByEmail(playerEmail)
+func (s GamesService) PlayMove(ctx context.Context, key string, playerEmail string, column int) (err error) {
+	ctx, span := tracing.Start(ctx, "GamesService.PlayMove", attribute.String("game.key", key), attribute.Int("game.column", column))
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.userService.FindUserByEmail(ctx, playerEmail)
 	if err != nil {
-		log.Errorf("Error fetching user: %v", err)
+		log.WithContext(ctx).Errorf("Error fetching user: %v", err)
 		return err
 	}
-	game, err := s.gameRepository.Fetch(key)
+	game, err := s.gameRepository.Fetch(ctx, key)
 	if err != nil {
 		return err
 	}
@@ -128,22 +246,212 @@ func (s GamesService) PlayMove(key string, playerEmail string, column int) error
 	if err != nil {
 		return err
 	}
-	s.gameRepository.Save(game)
+	if !s.gameRepository.Save(ctx, game) {
+		return errors.New("the move could not be saved")
+	}
+	s.turns.notify(key)
+	s.events.publish(GameEvent{Type: MovePlayedEvent, Game: game, Actor: user})
+	if game.Status == model.Finished {
+		s.events.publish(GameEvent{Type: GameFinishedEvent, Game: game, Actor: user})
+	}
 	return nil
 }
 
-func (s GamesService) NewGame(player1Email string, public bool) NewGameResponse {
-	user, err := s.userService.FindUserByEmail(player1Email)
+// WaitForTurn blocks until it's the turn of the player with the specified email, the game is over, the timeout
+// passes or the context is cancelled - whichever comes first. It always returns the latest known state of the game.
+func (s GamesService) WaitForTurn(ctx context.Context, key string, playerEmail string, timeout time.Duration) (TurnResponse, error) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		// register before fetching, so that a move made in between isn't missed.
+		changed := s.turns.wait(key)
+		game, err := s.gameRepository.Fetch(ctx, key)
+		if err != nil {
+			s.turns.cancel(key, changed)
+			return TurnResponse{}, err
+		}
+
+		resp := TurnResponse{
+			YourTurn: game.Status == model.Started && game.IsPlayerTurn(playerEmail),
+			Disc:     game.PlayerDisc(playerEmail),
+			State:    NewGameStateResponse(game),
+		}
+		if resp.Disc == model.NoDisc {
+			s.turns.cancel(key, changed)
+			return TurnResponse{}, errors.New("you are not a player in this game")
+		}
+		if resp.YourTurn || game.Status == model.Finished || game.Status == model.Aborted {
+			s.turns.cancel(key, changed)
+			return resp, nil
+		}
+
+		recheck := time.NewTimer(turnRecheckInterval)
+		select {
+		case <-changed:
+		case <-recheck.C:
+			s.turns.cancel(key, changed)
+		case <-deadline.C:
+			recheck.Stop()
+			s.turns.cancel(key, changed)
+			return resp, nil
+		case <-ctx.Done():
+			recheck.Stop()
+			s.turns.cancel(key, changed)
+			return resp, ctx.Err()
+		}
+		recheck.Stop()
+	}
+}
+
+// NewGame creates a new game for the player. When an invitee's email is specified, the game is private and only
+// the invitee can join it.
+func (s GamesService) NewGame(ctx context.Context, player1Email string, public bool, inviteeEmail string) (NewGameResponse, error) {
+	user, err := s.userService.FindUserByEmail(ctx, player1Email)
 	if err != nil {
-		log.Errorf("Error fetching user: %v", err)
-		return NewGameResponse{}
+		log.WithContext(ctx).Errorf("Error fetching user: %v", err)
+		return NewGameResponse{}, err
 	}
 
 	game := model.NewGame(user, public)
-	if s.gameRepository.Save(game) {
-		return NewGameResponseFromGame(game)
-	} else {
-		log.Errorf("Error creating new game for player: %s", player1Email)
-		return NewGameResponse{}
+	if inviteeEmail != "" {
+		invitee, err := s.userService.FindUserByEmail(ctx, inviteeEmail)
+		if err != nil {
+			log.WithContext(ctx).Errorf("Error fetching invitee: %v", err)
+			return NewGameResponse{}, err
+		}
+		if invitee.Empty() {
+			return NewGameResponse{}, fmt.Errorf("there is no player with e-mail address %s", inviteeEmail)
+		}
+		if err = game.Invite(invitee); err != nil {
+			return NewGameResponse{}, err
+		}
+		game.Public = false
+	}
+	if game.Public && !user.Verified {
+		return NewGameResponse{}, errors.New("verify your e-mail address before creating public games")
+	}
+
+	if !s.gameRepository.Save(ctx, game) {
+		log.WithContext(ctx).Errorf("Error creating new game for player: %s", player1Email)
+		return NewGameResponse{}, errors.New("the game could not be saved")
+	}
+	s.events.publish(GameEvent{Type: GameCreatedEvent, Game: game, Actor: user})
+	return NewGameResponseFromGame(game), nil
+}
+
+// Invitations returns the games that the player has been invited to, but hasn't accepted or declined yet.
+func (s GamesService) Invitations(ctx context.Context, email string) []InvitationResponse {
+	user, err := s.userService.FindUserByEmail(ctx, email)
+	if err != nil || user.Empty() {
+		log.WithContext(ctx).Errorf("Error finding user by email '%s': %v", email, err)
+		return []InvitationResponse{}
+	}
+
+	games, err := s.gameRepository.ListInvitations(ctx, user.Id)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error getting invitations: %v\n", err)
+		return []InvitationResponse{}
+	}
+
+	output := make([]InvitationResponse, 0, len(games))
+	for _, game := range games {
+		output = append(output, NewInvitationResponse(game))
+	}
+	return output
+}
+
+// AcceptInvitation joins the game that the player was invited to.
+func (s GamesService) AcceptInvitation(ctx context.Context, key string, email string) error {
+	game, err := s.gameRepository.Fetch(ctx, key)
+	if err != nil {
+		return err
+	}
+	if !game.Invitee.Is(model.User{Email: email}) {
+		return errors.New("you were not invited to this game")
+	}
+	return s.JoinGame(ctx, key, email)
+}
+
+// DeclineInvitation refuses the invitation, which aborts the game.
+func (s GamesService) DeclineInvitation(ctx context.Context, key string, email string) error {
+	user, err := s.userService.FindUserByEmail(ctx, email)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error fetching user: %v", err)
+		return err
+	}
+	game, err := s.gameRepository.Fetch(ctx, key)
+	if err != nil {
+		return err
+	}
+	if err = game.Decline(user); err != nil {
+		return err
+	}
+	if !s.gameRepository.Save(ctx, game) {
+		return errors.New("the game could not be saved")
+	}
+	s.turns.notify(key)
+	return nil
+}
+
+// AbortGame stops a game that hasn't finished yet, on behalf of a moderator. The players waiting for their turn are
+// told that the game is over.
+func (s GamesService) AbortGame(ctx context.Context, key string, moderator model.User) (model.Game, error) {
+	game, err := s.gameRepository.Fetch(ctx, key)
+	if err != nil {
+		return model.Game{}, err
+	}
+	if err = game.Abort(); err != nil {
+		return model.Game{}, err
+	}
+	if !s.gameRepository.Save(ctx, game) {
+		return model.Game{}, errors.New("the game could not be saved")
+	}
+	s.turns.notify(key)
+	s.events.publish(GameEvent{Type: GameFinishedEvent, Game: game, Actor: moderator})
+	return game, nil
+}
+
+// AbortGamesOf aborts the games of the user that haven't finished: the games they play or wait for an opponent in,
+// and the games they were invited to. It's done before the account of the user is deleted, so the opponents, the
+// tournaments and the webhooks hear that the games are over.
+func (s GamesService) AbortGamesOf(ctx context.Context, email string) error {
+	user, err := s.userService.existingUser(ctx, email)
+	if err != nil {
+		return err
+	}
+	var games []model.Game
+	for _, status := range []model.GameStatus{model.Created, model.Started} {
+		list, err := s.gameRepository.List(ctx, model.GameFilter{PlayerId: user.Id, Status: status})
+		if err != nil {
+			return err
+		}
+		games = append(games, list...)
+	}
+	invitations, err := s.gameRepository.ListInvitations(ctx, user.Id)
+	if err != nil {
+		return err
+	}
+	games = append(games, invitations...)
+
+	for _, game := range games {
+		if _, err = s.AbortGame(ctx, game.Key, user); err != nil {
+			return fmt.Errorf("game %s could not be aborted: %w", game.Key, err)
+		}
+	}
+	return nil
+}
+
+// StartGame creates a private game between two players that starts right away, as used for tournament pairings.
+func (s GamesService) StartGame(ctx context.Context, player1 model.User, player2 model.User) (model.Game, error) {
+	game := model.NewGame(player1, false)
+	if err := game.Join(player2); err != nil {
+		return model.Game{}, err
+	}
+	if !s.gameRepository.Save(ctx, game) {
+		return model.Game{}, fmt.Errorf("could not save the game between %s and %s", player1.Email, player2.Email)
 	}
+	s.events.publish(GameEvent{Type: GameCreatedEvent, Game: game, Actor: player1})
+	s.events.publish(GameEvent{Type: GameJoinedEvent, Game: game, Actor: player2})
+	return game, nil
 }
@@ -3,9 +3,11 @@ package service
 import (
 	"connectfour/internal/db"
 	"connectfour/internal/model"
+	"context"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"testing"
+	"time"
 )
 
 func mockedGamesService() (*GamesService, *db.MockUserRepository, *db.MockGameRepository) {
@@ -24,63 +26,332 @@ func mockedGames() []model.Game {
 	return output
 }
 
-func TestGamesService_AllOpenGames(t *testing.T) {
+func TestGamesService_OpenGames(t *testing.T) {
 	// Arrange
 	list := mockedGames()
-	s, ur, sr := mockedGamesService()
-	ur.On("FindByEmail", mock.AnythingOfType("string")).Return(user1, nil)
-	sr.On("List", mock.AnythingOfType("int64"), mock.AnythingOfType("string")).Return(list, nil)
+	s, _, sr := mockedGamesService()
+	sr.On("List", mock.Anything, mock.AnythingOfType("model.GameFilter")).Return(list[:2], nil)
 
 	// Act
-	games := s.AllOpenGames(user1.Email)
+	page, err := s.OpenGames(context.Background(), GameListRequest{Status: model.Finished})
 
 	// Assert
-	assert.Len(t, games, len(list)-1, "Expected number of games to match all Public games in the test set")
-	assert.Equal(t, model.Created, games[0].Status)
-	assert.Equal(t, model.Created, games[1].Status)
+	assert.NoError(t, err)
+	assert.Len(t, page.Games, 2)
+	assert.Empty(t, page.NextCursor, "Expected no next page when all games fit on the page")
+	filter := sr.Calls[0].Arguments.Get(1).(model.GameFilter)
+	assert.Zero(t, filter.PlayerId, "Expected the open games of all players")
+	assert.Equal(t, model.Created, filter.Status)
+	assert.True(t, *filter.Public)
+	assert.Equal(t, defaultGamePageSize+1, filter.Limit)
 }
 
 func TestGamesService_JoinGame_SavesToDb(t *testing.T) {
 	list := mockedGames()
 	game := &list[0]
 	s, ur, sr := mockedGamesService()
-	sr.On("Fetch", mock.AnythingOfType("string")).Return(*game, nil)
-	sr.On("Save", mock.AnythingOfType("model.Game")).Return(true)
-	ur.On("FindByEmail", mock.AnythingOfType("string")).Return(user2, nil)
+	sr.On("Fetch", mock.Anything, mock.AnythingOfType("string")).Return(*game, nil)
+	sr.On("Save", mock.Anything, mock.AnythingOfType("model.Game")).Return(true)
+	ur.On("FindByEmail", mock.Anything, mock.AnythingOfType("string")).Return(user2, nil)
 
 	// Act
-	err := s.JoinGame(game.Key, user2.Email)
+	err := s.JoinGame(context.Background(), game.Key, user2.Email)
 
 	// Assert
 	assert.NoError(t, err, "Expected no error when joining game")
 }
 
-func TestGamesService_AllMyGames(t *testing.T) {
+func TestGamesService_MyGames(t *testing.T) {
 	// Arrange
 	list := mockedGames()
+	user1 := user1
+	user1.Id = 1
 	s, ur, sr := mockedGamesService()
-	ur.On("FindByEmail", mock.AnythingOfType("string")).Return(user1, nil)
-	sr.On("List", user1.Id, mock.AnythingOfType("string")).Return(append([]model.Game{}, list[0], list[2]), nil)
+	ur.On("FindByEmail", mock.Anything, user1.Email).Return(user1, nil)
+	sr.On("List", mock.Anything, mock.AnythingOfType("model.GameFilter")).Return(append([]model.Game{}, list[0], list[2]), nil)
+
+	// Act
+	page, err := s.MyGames(context.Background(), user1.Email, GameListRequest{Order: model.OldestFirst})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, page.Games, 2)
+	sr.AssertCalled(t, "List", mock.Anything, model.GameFilter{PlayerId: user1.Id, Order: model.OldestFirst, Limit: defaultGamePageSize + 1})
+}
+
+func TestGamesService_MyGames_Pages(t *testing.T) {
+	// Arrange
+	list := mockedGames()
+	user1 := user1
+	user1.Id = 1
+	s, ur, sr := mockedGamesService()
+	ur.On("FindByEmail", mock.Anything, user1.Email).Return(user1, nil)
+	sr.On("List", mock.Anything, mock.AnythingOfType("model.GameFilter")).Return(list, nil)
+
+	// Act
+	first, err := s.MyGames(context.Background(), user1.Email, GameListRequest{Limit: 2})
+	_, _ = s.MyGames(context.Background(), user1.Email, GameListRequest{Limit: 2, Cursor: first.NextCursor})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, first.Games, 2, "Expected the extra game to be left for the next page")
+	assert.NotEmpty(t, first.NextCursor)
+	next := sr.Calls[1].Arguments.Get(1).(model.GameFilter)
+	assert.Equal(t, list[1].Key, next.After.Key, "Expected the next page to start after the last game of the page")
+	assert.True(t, list[1].CreatedAt.Equal(next.After.CreatedAt))
+}
+
+func TestNewGameListingResponse(t *testing.T) {
+	// Arrange
+	game := model.NewGame(user1, true)
+	_ = game.Join(user2)
+	_ = game.Play(user1, 4)
 
 	// Act
-	games := s.AllMyGames(user1.Email)
+	r := NewGameListingResponse(game)
 
 	// Assert
-	assert.Len(t, games, 2)
-	sr.AssertCalled(t, "List", user1.Id, "")
+	assert.Equal(t, user1.Name, r.Player1Name)
+	assert.Equal(t, user2.Email, r.Player2Email)
+	assert.Equal(t, 2, r.PlayerTurn)
+	assert.Equal(t, user2.Email, r.PlayerTurnEmail)
+	assert.Equal(t, 1, r.Moves)
+	assert.Equal(t, game.LastMoveAt, r.LastMoveAt)
+	assert.Empty(t, r.Outcome)
+}
+
+func TestGamesService_MyGames_InvalidRequest(t *testing.T) {
+	// Arrange
+	s, ur, _ := mockedGamesService()
+	ur.On("FindByEmail", mock.Anything, user1.Email).Return(user1, nil)
+	ur.On("FindByEmail", mock.Anything, "nobody@evilnerd.nl").Return(model.User{}, nil)
+
+	// Act
+	_, errCursor := s.MyGames(context.Background(), user1.Email, GameListRequest{Cursor: "not-a-cursor"})
+	_, errOrder := s.MyGames(context.Background(), user1.Email, GameListRequest{Order: "random"})
+	_, errOpponent := s.MyGames(context.Background(), user1.Email, GameListRequest{Opponent: "nobody@evilnerd.nl"})
+
+	// Assert
+	assert.Error(t, errCursor)
+	assert.Error(t, errOrder)
+	assert.Error(t, errOpponent)
 }
 
 func TestGamesService_CreateGame_SavesToDb(t *testing.T) {
 	// Arrange
 	s, ur, sr := mockedGamesService()
-	sr.On("Save", mock.AnythingOfType("model.Game")).Return(true)
-	ur.Mock.On("FindByEmail", mock.AnythingOfType("string")).Return(user1, nil)
+	sr.On("Save", mock.Anything, mock.AnythingOfType("model.Game")).Return(true)
+	verified := user1
+	verified.Verified = true
+	ur.Mock.On("FindByEmail", mock.Anything, mock.AnythingOfType("string")).Return(verified, nil)
 
 	// Act
-	resp := s.NewGame(user1.Email, true)
+	resp, err := s.NewGame(context.Background(), user1.Email, true, "")
 
 	// Assert
-	sr.AssertCalled(t, "Save", mock.AnythingOfType("model.Game"))
+	assert.NoError(t, err)
+	sr.AssertCalled(t, "Save", mock.Anything, mock.AnythingOfType("model.Game"))
 	assert.Equal(t, model.Created, resp.Status)
 	assert.Equal(t, user1.Email, resp.CreatedBy)
 }
+
+func TestGamesService_WaitForTurn_ReturnsWhenItsYourTurn(t *testing.T) {
+	// Arrange
+	game := model.NewGame(user1, true)
+	_ = game.Join(user2)
+	s, _, sr := mockedGamesService()
+	sr.On("Fetch", mock.Anything, game.Key).Return(game, nil)
+
+	// Act
+	turn, err := s.WaitForTurn(context.Background(), game.Key, user1.Email, time.Minute)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, turn.YourTurn, "Expected player 1 to have the first turn")
+	assert.Equal(t, model.Disc(model.RedDisc), turn.Disc)
+}
+
+func TestGamesService_WaitForTurn_WakesUpAfterOpponentMove(t *testing.T) {
+	// Arrange
+	game := model.NewGame(user1, true)
+	_ = game.Join(user2)
+	played := game
+	_ = played.Play(user1, 1)
+
+	s, ur, sr := mockedGamesService()
+	ur.On("FindByEmail", mock.Anything, user1.Email).Return(user1, nil)
+	sr.On("Fetch", mock.Anything, game.Key).Return(game, nil).Twice() // once for the waiting player, once for the move.
+	sr.On("Fetch", mock.Anything, game.Key).Return(played, nil)
+	sr.On("Save", mock.Anything, mock.AnythingOfType("model.Game")).Return(true)
+
+	// Act
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = s.PlayMove(context.Background(), game.Key, user1.Email, 1)
+	}()
+	turn, err := s.WaitForTurn(context.Background(), game.Key, user2.Email, time.Minute)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, turn.YourTurn, "Expected player 2 to get the turn after player 1 moved")
+	assert.Equal(t, model.Disc(model.YellowDisc), turn.Disc)
+}
+
+func TestGamesService_WaitForTurn_TimesOut(t *testing.T) {
+	// Arrange
+	game := model.NewGame(user1, true)
+	_ = game.Join(user2)
+	s, _, sr := mockedGamesService()
+	sr.On("Fetch", mock.Anything, game.Key).Return(game, nil)
+
+	// Act
+	turn, err := s.WaitForTurn(context.Background(), game.Key, user2.Email, 10*time.Millisecond)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.False(t, turn.YourTurn)
+	assert.Equal(t, game.Key, turn.State.Key)
+}
+
+func TestGamesService_CreateGame_PublicNeedsVerifiedUser(t *testing.T) {
+	// Arrange
+	s, ur, sr := mockedGamesService()
+	ur.On("FindByEmail", mock.Anything, user1.Email).Return(user1, nil)
+
+	// Act
+	_, err := s.NewGame(context.Background(), user1.Email, true, "")
+
+	// Assert
+	assert.Error(t, err, "Expected an unverified user not to be able to create a public game")
+	sr.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestGamesService_CreateGame_WithInviteeIsPrivate(t *testing.T) {
+	// Arrange
+	s, ur, sr := mockedGamesService()
+	sr.On("Save", mock.Anything, mock.AnythingOfType("model.Game")).Return(true)
+	ur.On("FindByEmail", mock.Anything, user1.Email).Return(user1, nil)
+	ur.On("FindByEmail", mock.Anything, user2.Email).Return(user2, nil)
+
+	// Act
+	resp, err := s.NewGame(context.Background(), user1.Email, true, user2.Email)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, user2.Email, resp.Invitee)
+	sr.AssertCalled(t, "Save", mock.Anything, mock.MatchedBy(func(g model.Game) bool {
+		return !g.Public && g.Invitee.Is(user2)
+	}))
+}
+
+func TestGamesService_AcceptInvitation_OnlyForInvitee(t *testing.T) {
+	// Arrange
+	game := model.NewGame(user1, false)
+	_ = game.Invite(user2)
+	s, ur, sr := mockedGamesService()
+	sr.On("Fetch", mock.Anything, game.Key).Return(game, nil)
+	sr.On("Save", mock.Anything, mock.AnythingOfType("model.Game")).Return(true)
+	ur.On("FindByEmail", mock.Anything, user2.Email).Return(user2, nil)
+
+	// Act
+	err1 := s.AcceptInvitation(context.Background(), game.Key, "other@evilnerd.nl")
+	err2 := s.AcceptInvitation(context.Background(), game.Key, user2.Email)
+
+	// Assert
+	assert.Error(t, err1, "Expected an error when accepting somebody else's invitation")
+	assert.NoError(t, err2)
+	sr.AssertNumberOfCalls(t, "Save", 1)
+}
+
+func TestGamesService_PlayMove_Cancelled(t *testing.T) {
+	// Arrange
+	game := model.NewGame(user1, true)
+	_ = game.Join(user2)
+	s, ur, sr := mockedGamesService()
+	cancelled := mock.MatchedBy(func(ctx context.Context) bool { return ctx.Err() != nil })
+	ur.On("FindByEmail", mock.Anything, user1.Email).Return(user1, nil)
+	sr.On("Fetch", cancelled, game.Key).Return(model.Game{}, context.Canceled)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var events []GameEvent
+	s.Subscribe(func(e GameEvent) { events = append(events, e) })
+
+	// Act
+	err := s.PlayMove(ctx, game.Key, user1.Email, 1)
+
+	// Assert
+	assert.ErrorIs(t, err, context.Canceled)
+	sr.AssertCalled(t, "Fetch", cancelled, game.Key)
+	sr.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+	assert.Empty(t, events, "Expected no move to be published when the request was cancelled")
+}
+
+func TestGamesService_PlayMove_NotSaved(t *testing.T) {
+	// Arrange
+	game := model.NewGame(user1, true)
+	_ = game.Join(user2)
+	s, ur, sr := mockedGamesService()
+	ur.On("FindByEmail", mock.Anything, user1.Email).Return(user1, nil)
+	ur.On("FindByEmail", mock.Anything, user2.Email).Return(user2, nil)
+	sr.On("Fetch", mock.Anything, game.Key).Return(game, nil)
+	sr.On("Save", mock.Anything, mock.AnythingOfType("model.Game")).Return(false)
+	var events []GameEvent
+	s.Subscribe(func(e GameEvent) { events = append(events, e) })
+
+	// Act
+	err := s.PlayMove(context.Background(), game.Key, game.CurrentPlayer().Email, 1)
+
+	// Assert
+	assert.Error(t, err)
+	assert.Empty(t, events, "Expected no move to be published when it wasn't saved")
+}
+
+func TestGamesService_JoinGame_NotSaved(t *testing.T) {
+	// Arrange
+	game := model.NewGame(user1, true)
+	s, ur, sr := mockedGamesService()
+	ur.On("FindByEmail", mock.Anything, user2.Email).Return(user2, nil)
+	sr.On("Fetch", mock.Anything, game.Key).Return(game, nil)
+	sr.On("Save", mock.Anything, mock.AnythingOfType("model.Game")).Return(false)
+	var events []GameEvent
+	s.Subscribe(func(e GameEvent) { events = append(events, e) })
+
+	// Act
+	err := s.JoinGame(context.Background(), game.Key, user2.Email)
+
+	// Assert
+	assert.Error(t, err)
+	assert.Empty(t, events, "Expected no join to be published when it wasn't saved")
+}
+
+func TestGamesService_AbortGamesOf(t *testing.T) {
+	// Arrange
+	created := model.NewGame(user1, true)
+	started := model.NewGame(user1, true)
+	_ = started.Join(user2)
+	invitation := model.NewGame(user2, false)
+	_ = invitation.Invite(user1)
+	s, ur, sr := mockedGamesService()
+	ur.On("FindByEmail", mock.Anything, user1.Email).Return(user1, nil)
+	sr.On("List", mock.Anything, model.GameFilter{PlayerId: user1.Id, Status: model.Created}).Return([]model.Game{created}, nil)
+	sr.On("List", mock.Anything, model.GameFilter{PlayerId: user1.Id, Status: model.Started}).Return([]model.Game{started}, nil)
+	sr.On("ListInvitations", mock.Anything, user1.Id).Return([]model.Game{invitation}, nil)
+	for _, game := range []model.Game{created, started, invitation} {
+		sr.On("Fetch", mock.Anything, game.Key).Return(game, nil)
+	}
+	sr.On("Save", mock.Anything, mock.MatchedBy(func(g model.Game) bool { return g.Status == model.Aborted })).Return(true)
+	var events []GameEvent
+	s.Subscribe(func(e GameEvent) { events = append(events, e) })
+
+	// Act
+	err := s.AbortGamesOf(context.Background(), user1.Email)
+
+	// Assert
+	assert.NoError(t, err)
+	sr.AssertNumberOfCalls(t, "Save", 3)
+	assert.Len(t, events, 3, "Expected every aborted game to be published")
+	for _, e := range events {
+		assert.Equal(t, GameFinishedEvent, e.Type)
+		assert.Equal(t, model.Aborted, e.Game.Status)
+	}
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"connectfour/internal/db"
+	"connectfour/internal/model"
+	"context"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"slices"
+	"time"
+)
+
+// maxInboxNotifications is the most notifications that are returned from the inbox at once.
+const maxInboxNotifications = 50
+
+// NotificationService tells players about what happens in their games while they aren't looking: that an opponent
+// joined, that it's their turn, that the game finished or that they were invited. Each notification is delivered
+// over the channels that the player chose in their preferences.
+type NotificationService struct {
+	userService *UserService
+	repo        db.NotificationRepository
+	notifiers   []Notifier
+	now         func() time.Time
+	// dispatch runs a delivery. It's in the background, so a move isn't slowed down by sending mail.
+	dispatch func(deliver func())
+}
+
+func NewNotificationService(userService *UserService, repo db.NotificationRepository, notifiers ...Notifier) *NotificationService {
+	return &NotificationService{
+		userService: userService,
+		repo:        repo,
+		notifiers:   notifiers,
+		now:         time.Now,
+		dispatch:    func(deliver func()) { go deliver() },
+	}
+}
+
+// OnGameEvent notifies the players of the game. Subscribe it with GamesService.Subscribe.
+func (s NotificationService) OnGameEvent(event GameEvent) {
+	for _, recipient := range recipientsOf(event) {
+		n := model.Notification{
+			UserId:    recipient.Id,
+			Kind:      notificationKind(event),
+			GameKey:   event.Game.Key,
+			Message:   notificationMessage(event, recipient),
+			CreatedAt: s.now(),
+		}
+		s.dispatch(func() {
+			if err := s.Send(context.Background(), recipient, n); err != nil {
+				log.Warnf("Could not notify %s of %s in game %s: %v", recipient.Email, n.Kind, n.GameKey, err)
+			}
+		})
+	}
+}
+
+// Send delivers the notification over every channel that the recipient wants it on. A channel that fails doesn't
+// keep the others from delivering; their errors are returned together.
+func (s NotificationService) Send(ctx context.Context, recipient model.User, n model.Notification) error {
+	prefs, err := s.repo.Preferences(ctx, recipient.Id)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, notifier := range s.notifiers {
+		if !prefs.Wants(notifier.Channel(), n.Kind) {
+			continue
+		}
+		if err = notifier.Notify(ctx, recipient, prefs, n); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", notifier.Channel(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// recipientsOf returns the players that are told about the event. That's never the player that caused it.
+func recipientsOf(event GameEvent) []model.User {
+	game := event.Game
+	var candidates []model.User
+	switch event.Type {
+	case GameCreatedEvent:
+		candidates = []model.User{game.Invitee}
+	case GameJoinedEvent:
+		candidates = []model.User{game.Player1}
+	case MovePlayedEvent:
+		if game.Status == model.Started {
+			candidates = []model.User{*game.CurrentPlayer()}
+		}
+	case GameFinishedEvent:
+		candidates = []model.User{game.Player1, game.Player2}
+	}
+
+	recipients := make([]model.User, 0, len(candidates))
+	for _, user := range candidates {
+		if !user.Empty() && !user.Is(event.Actor) {
+			recipients = append(recipients, user)
+		}
+	}
+	return recipients
+}
+
+func notificationKind(event GameEvent) model.NotificationKind {
+	switch event.Type {
+	case GameCreatedEvent:
+		return model.InvitationNotification
+	case GameJoinedEvent:
+		return model.OpponentJoinedNotification
+	case MovePlayedEvent:
+		return model.YourTurnNotification
+	}
+	return model.GameFinishedNotification
+}
+
+func notificationMessage(event GameEvent, recipient model.User) string {
+	game := event.Game
+	opponent := game.Player1
+	if opponent.Is(recipient) {
+		opponent = game.Player2
+	}
+	switch event.Type {
+	case GameCreatedEvent:
+		return fmt.Sprintf("%s invited you to game %s", game.Player1.Name, game.Key)
+	case GameJoinedEvent:
+		return fmt.Sprintf("%s joined your game %s, it's your turn", event.Actor.Name, game.Key)
+	case MovePlayedEvent:
+		return fmt.Sprintf("%s played, it's your turn in game %s", event.Actor.Name, game.Key)
+	}
+	switch winner := game.Winner(); {
+	case game.Status == model.Aborted:
+		return fmt.Sprintf("Game %s was aborted", game.Key)
+	case game.IsDraw():
+		return fmt.Sprintf("Game %s against %s ended in a draw", game.Key, opponent.Name)
+	case winner.Is(recipient):
+		return fmt.Sprintf("You won game %s against %s", game.Key, opponent.Name)
+	}
+	return fmt.Sprintf("You lost game %s against %s", game.Key, opponent.Name)
+}
+
+// Inbox returns the newest notifications in the inbox of the user.
+func (s NotificationService) Inbox(ctx context.Context, email string, unreadOnly bool) ([]NotificationResponse, error) {
+	user, err := s.userService.existingUser(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	notifications, err := s.repo.List(ctx, user.Id, unreadOnly, maxInboxNotifications)
+	if err != nil {
+		return nil, errors.New("the notifications could not be read")
+	}
+	output := make([]NotificationResponse, 0, len(notifications))
+	for _, n := range notifications {
+		output = append(output, NewNotificationResponse(n))
+	}
+	return output, nil
+}
+
+// MarkRead marks a notification in the inbox of the user as read, or all of them when the id is 0.
+func (s NotificationService) MarkRead(ctx context.Context, email string, id int64) error {
+	user, err := s.userService.existingUser(ctx, email)
+	if err != nil {
+		return err
+	}
+	return s.repo.MarkRead(ctx, user.Id, id, s.now())
+}
+
+func (s NotificationService) Preferences(ctx context.Context, email string) (NotificationPreferencesResponse, error) {
+	user, err := s.userService.existingUser(ctx, email)
+	if err != nil {
+		return NotificationPreferencesResponse{}, err
+	}
+	prefs, err := s.repo.Preferences(ctx, user.Id)
+	if err != nil {
+		return NotificationPreferencesResponse{}, errors.New("the notification preferences could not be read")
+	}
+	return NewNotificationPreferencesResponse(prefs), nil
+}
+
+// SetPreferences replaces the notification preferences of the user.
+func (s NotificationService) SetPreferences(ctx context.Context, email string, req NotificationPreferencesRequest) (NotificationPreferencesResponse, error) {
+	user, err := s.userService.existingUser(ctx, email)
+	if err != nil {
+		return NotificationPreferencesResponse{}, err
+	}
+	prefs := model.NotificationPreferences{
+		Channels: slices.Compact(slices.Sorted(slices.Values(req.Channels))),
+		Muted:    slices.Compact(slices.Sorted(slices.Values(req.Muted))),
+	}
+	if err = validatePreferences(prefs); err != nil {
+		return NotificationPreferencesResponse{}, err
+	}
+	if err = s.repo.SavePreferences(ctx, user.Id, prefs); err != nil {
+		return NotificationPreferencesResponse{}, errors.New("the notification preferences could not be saved")
+	}
+	return NewNotificationPreferencesResponse(prefs), nil
+}
+
+func validatePreferences(p model.NotificationPreferences) error {
+	for _, channel := range p.Channels {
+		if channel != model.InboxChannel && channel != model.EmailChannel && channel != model.WebhookChannel {
+			return fmt.Errorf("there's no notification channel %q", channel)
+		}
+	}
+	for _, kind := range p.Muted {
+		switch kind {
+		case model.OpponentJoinedNotification, model.YourTurnNotification, model.GameFinishedNotification, model.InvitationNotification:
+		default:
+			return fmt.Errorf("there's no kind of notification %q", kind)
+		}
+	}
+	return nil
+}
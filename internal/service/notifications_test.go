@@ -0,0 +1,189 @@
+package service
+
+import (
+	"connectfour/internal/db"
+	"connectfour/internal/model"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// mockedNotificationService delivers right away instead of in the background, so the tests can check the delivery.
+func mockedNotificationService(notifiers ...Notifier) (*NotificationService, *db.MockUserRepository, *db.MockNotificationRepository) {
+	ur := db.NewMockUserRepository()
+	repo := db.NewMockNotificationRepository()
+	s := NewNotificationService(NewUserService(ur, 0), repo, append([]Notifier{NewInboxNotifier(repo)}, notifiers...)...)
+	s.dispatch = func(deliver func()) { deliver() }
+	return s, ur, repo
+}
+
+// startedGame returns a game between dick (1) and sanae (2), where dick played the first move.
+func startedGame() (model.Game, model.User, model.User) {
+	dick, sanae := user1, user2
+	dick.Id, sanae.Id = 1, 2
+	game := model.NewGame(dick, true)
+	_ = game.Join(sanae)
+	_ = game.Play(dick, 4)
+	return game, dick, sanae
+}
+
+func TestNotificationService_OnGameEvent_YourTurn(t *testing.T) {
+	// Arrange
+	game, dick, sanae := startedGame()
+	mailer := &recordingMailer{}
+	s, _, repo := mockedNotificationService(NewEmailNotifier(mailer))
+	repo.On("Preferences", mock.Anything, sanae.Id).Return(model.NotificationPreferences{
+		Channels: []model.NotificationChannel{model.InboxChannel, model.EmailChannel},
+	}, nil)
+	repo.On("Add", mock.Anything, mock.AnythingOfType("model.Notification")).Return(model.Notification{Id: 1}, nil)
+
+	// Act
+	s.OnGameEvent(GameEvent{Type: MovePlayedEvent, Game: game, Actor: dick})
+
+	// Assert
+	repo.AssertNumberOfCalls(t, "Add", 1)
+	n := repo.Calls[1].Arguments.Get(1).(model.Notification)
+	assert.Equal(t, sanae.Id, n.UserId, "Expected only the player whose turn it is to be notified")
+	assert.Equal(t, model.YourTurnNotification, n.Kind)
+	assert.Equal(t, game.Key, n.GameKey)
+	assert.Len(t, mailer.sent, 1)
+	assert.Equal(t, sanae.Email, mailer.sent[0].To)
+	assert.Contains(t, mailer.sent[0].Subject, "it's your turn")
+}
+
+func TestNotificationService_OnGameEvent_Muted(t *testing.T) {
+	// Arrange
+	game, dick, sanae := startedGame()
+	s, _, repo := mockedNotificationService()
+	repo.On("Preferences", mock.Anything, sanae.Id).Return(model.NotificationPreferences{
+		Channels: []model.NotificationChannel{model.InboxChannel},
+		Muted:    []model.NotificationKind{model.YourTurnNotification},
+	}, nil)
+
+	// Act
+	s.OnGameEvent(GameEvent{Type: MovePlayedEvent, Game: game, Actor: dick})
+
+	// Assert
+	repo.AssertNotCalled(t, "Add", mock.Anything, mock.Anything)
+}
+
+func TestNotificationService_OnGameEvent_Finished(t *testing.T) {
+	// Arrange
+	game, dick, sanae := startedGame()
+	for _, col := range []int{1, 4, 1, 4, 1, 4} {
+		_ = game.Play(*game.CurrentPlayer(), col)
+	}
+	s, _, repo := mockedNotificationService()
+	repo.On("Preferences", mock.Anything, mock.Anything).Return(model.DefaultNotificationPreferences(), nil)
+	repo.On("Add", mock.Anything, mock.AnythingOfType("model.Notification")).Return(model.Notification{}, nil)
+
+	// Act
+	s.OnGameEvent(GameEvent{Type: GameFinishedEvent, Game: game, Actor: dick})
+
+	// Assert
+	assert.Equal(t, model.Finished, game.Status)
+	repo.AssertNumberOfCalls(t, "Add", 1)
+	n := repo.Calls[1].Arguments.Get(1).(model.Notification)
+	assert.Equal(t, sanae.Id, n.UserId, "Expected the player that made the winning move not to be notified")
+	assert.Equal(t, "You lost game "+game.Key+" against Dick", n.Message)
+}
+
+func TestWebhookNotifier_Notify(t *testing.T) {
+	// Arrange
+	receiver := &webhookReceiver{statuses: []int{http.StatusOK}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	repo := db.NewMockWebhookRepository()
+	deliveries := recordDeliveries(repo)
+	queue := NewWebhookQueue(repo, http.DefaultClient, time.Millisecond)
+	defer queue.Close()
+	notifier := NewWebhookNotifier(repo, queue)
+	webhook := model.Webhook{Id: 3, OwnerId: 2, Url: server.URL, Secret: "s3cr3t", Events: []string{string(NotificationEvent)}}
+	repo.On("ListByOwner", mock.Anything, int64(2)).Return([]model.Webhook{
+		webhook,
+		{Id: 4, OwnerId: 2, Url: server.URL, Events: []string{string(MovePlayedEvent)}},
+	}, nil)
+	sanae := user2
+	sanae.Id = 2
+	n := model.Notification{Kind: model.InvitationNotification, GameKey: "dolk-mop-rits", Message: "Dick invited you", CreatedAt: time.Now()}
+	payload, _ := json.Marshal(WebhookNotificationPayload{Event: NotificationEvent, OccurredAt: n.CreatedAt, Notification: NewNotificationResponse(n)})
+	queued := model.WebhookDelivery{
+		WebhookId:     webhook.Id,
+		Event:         "notification",
+		GameKey:       n.GameKey,
+		Payload:       string(payload),
+		Status:        model.DeliveryPending,
+		CreatedAt:     n.CreatedAt,
+		NextAttemptAt: n.CreatedAt,
+	}
+	added := queued
+	added.Id = 12
+	repo.On("AddDelivery", mock.Anything, queued).Return(added, nil)
+
+	// Act
+	err := notifier.Notify(context.Background(), sanae, model.NotificationPreferences{}, n)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Eventually(t, deliveries.reaches(model.DeliveryDelivered), time.Second, time.Millisecond)
+	repo.AssertNumberOfCalls(t, "AddDelivery", 1)
+	assert.Equal(t, 1, receiver.received(), "Expected only the webhook that wants notifications to get it")
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	assert.JSONEq(t, string(payload), string(receiver.bodies[0]))
+	assert.Equal(t, "notification", receiver.requests[0].Header.Get("X-ConnectFour-Event"))
+	timestamp := receiver.requests[0].Header.Get("X-ConnectFour-Timestamp")
+	assert.Equal(t, "sha256="+signPayload("s3cr3t", timestamp, receiver.bodies[0]), receiver.requests[0].Header.Get("X-ConnectFour-Signature-256"))
+}
+
+func TestNotificationService_Send_ChannelFails(t *testing.T) {
+	// Arrange
+	_, _, sanae := startedGame()
+	webhooks := db.NewMockWebhookRepository()
+	webhooks.On("ListByOwner", mock.Anything, sanae.Id).Return([]model.Webhook{}, nil)
+	queue := NewWebhookQueue(webhooks, http.DefaultClient, time.Millisecond)
+	defer queue.Close()
+	s, _, repo := mockedNotificationService(NewWebhookNotifier(webhooks, queue))
+	repo.On("Preferences", mock.Anything, sanae.Id).Return(model.NotificationPreferences{
+		Channels: []model.NotificationChannel{model.WebhookChannel, model.InboxChannel},
+	}, nil)
+	repo.On("Add", mock.Anything, mock.AnythingOfType("model.Notification")).Return(model.Notification{}, nil)
+
+	// Act
+	err := s.Send(context.Background(), sanae, model.Notification{UserId: sanae.Id, Kind: model.YourTurnNotification})
+
+	// Assert
+	assert.ErrorContains(t, err, "webhook")
+	repo.AssertNumberOfCalls(t, "Add", 1)
+}
+
+func TestNotificationService_SetPreferences(t *testing.T) {
+	// Arrange
+	s, ur, repo := mockedNotificationService()
+	ur.On("FindByEmail", mock.Anything, user1.Email).Return(user1, nil)
+	repo.On("SavePreferences", mock.Anything, user1.Id, mock.AnythingOfType("model.NotificationPreferences")).Return(nil)
+
+	// Act
+	prefs, err := s.SetPreferences(context.Background(), user1.Email, NotificationPreferencesRequest{
+		Channels: []model.NotificationChannel{model.EmailChannel, model.InboxChannel, model.EmailChannel},
+	})
+	_, errChannel := s.SetPreferences(context.Background(), user1.Email, NotificationPreferencesRequest{
+		Channels: []model.NotificationChannel{"pigeon"},
+	})
+	_, errKind := s.SetPreferences(context.Background(), user1.Email, NotificationPreferencesRequest{
+		Channels: []model.NotificationChannel{model.WebhookChannel},
+		Muted:    []model.NotificationKind{"spam"},
+	})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []model.NotificationChannel{model.EmailChannel, model.InboxChannel}, prefs.Channels)
+	assert.Error(t, errChannel)
+	assert.Error(t, errKind)
+	repo.AssertNumberOfCalls(t, "SavePreferences", 1)
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"connectfour/internal/db"
+	"connectfour/internal/mail"
+	"connectfour/internal/model"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Notifier delivers notifications over one channel. The NotificationService only calls it for the users that want
+// notifications on that channel.
+type Notifier interface {
+	Channel() model.NotificationChannel
+	Notify(ctx context.Context, recipient model.User, prefs model.NotificationPreferences, n model.Notification) error
+}
+
+// InboxNotifier keeps the notification, so the user finds it at GET /notifications.
+type InboxNotifier struct {
+	repo db.NotificationRepository
+}
+
+func NewInboxNotifier(repo db.NotificationRepository) InboxNotifier {
+	return InboxNotifier{repo: repo}
+}
+
+func (n InboxNotifier) Channel() model.NotificationChannel {
+	return model.InboxChannel
+}
+
+func (n InboxNotifier) Notify(ctx context.Context, _ model.User, _ model.NotificationPreferences, notification model.Notification) error {
+	_, err := n.repo.Add(ctx, notification)
+	return err
+}
+
+// EmailNotifier mails the notification to the user.
+type EmailNotifier struct {
+	mailer mail.Mailer
+}
+
+func NewEmailNotifier(mailer mail.Mailer) EmailNotifier {
+	return EmailNotifier{mailer: mailer}
+}
+
+func (n EmailNotifier) Channel() model.NotificationChannel {
+	return model.EmailChannel
+}
+
+func (n EmailNotifier) Notify(_ context.Context, recipient model.User, _ model.NotificationPreferences, notification model.Notification) error {
+	return n.mailer.Send(mail.Message{
+		To:      recipient.Email,
+		Subject: "ConnectFour: " + notification.Message,
+		Body: fmt.Sprintf(`Hi %s,
+
+%s.
+
+Open the game with the key %s to see it. You can choose which notifications you get at /notifications/preferences.
+`, recipient.Name, notification.Message, notification.GameKey),
+	})
+}
+
+// WebhookNotifier posts the notification to the webhooks of the user that want the notification event. It goes
+// through the WebhookQueue, like the game events, so it's signed, retried and kept in the delivery log.
+type WebhookNotifier struct {
+	repo  db.WebhookRepository
+	queue *WebhookQueue
+}
+
+func NewWebhookNotifier(repo db.WebhookRepository, queue *WebhookQueue) WebhookNotifier {
+	return WebhookNotifier{repo: repo, queue: queue}
+}
+
+func (n WebhookNotifier) Channel() model.NotificationChannel {
+	return model.WebhookChannel
+}
+
+func (n WebhookNotifier) Notify(ctx context.Context, recipient model.User, _ model.NotificationPreferences, notification model.Notification) error {
+	webhooks, err := n.repo.ListByOwner(ctx, recipient.Id)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(WebhookNotificationPayload{
+		Event:        NotificationEvent,
+		OccurredAt:   notification.CreatedAt,
+		Notification: NewNotificationResponse(notification),
+	})
+	if err != nil {
+		return err
+	}
+
+	delivered := false
+	var errs []error
+	for _, w := range webhooks {
+		if !w.Wants(string(NotificationEvent)) {
+			continue
+		}
+		delivered = true
+		if err = n.queue.Deliver(ctx, w, NotificationEvent, notification.GameKey, payload, notification.CreatedAt); err != nil {
+			errs = append(errs, fmt.Errorf("webhook %d: %w", w.Id, err))
+		}
+	}
+	if !delivered {
+		return fmt.Errorf("there's no webhook for the %s event", NotificationEvent)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,241 @@
+package service
+
+import (
+	"connectfour/internal/model"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/coreos/go-oidc/v3/oidc"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// pendingLoginLifetime is how long a user has to complete the sign-in at the identity provider.
+const pendingLoginLifetime = 10 * time.Minute
+
+// OidcConfig configures the identity provider that users can sign in with.
+type OidcConfig struct {
+	Issuer       string // the issuer url, which serves /.well-known/openid-configuration.
+	ClientId     string
+	ClientSecret string // optional, since PKCE protects the flow for public clients too.
+	RedirectUrl  string // the callback of this api, e.g. http://localhost:8443/oidc/callback.
+}
+
+// PendingLogin is a sign-in that was started, but not completed at the identity provider yet.
+type PendingLogin struct {
+	verifier       string // the PKCE code verifier.
+	nonce          string
+	ClientRedirect string // the loopback url of the client that receives the JWT, if any.
+	ClientState    string // the state of the client, which it uses to match the redirect with its request.
+	expires        time.Time
+}
+
+// OidcService signs users in with an OpenID Connect identity provider, using the authorization code flow with
+// PKCE. Identities are linked to a user by their verified e-mail address the first time, and by issuer and subject
+// after that.
+type OidcService struct {
+	userService *UserService
+	issuer      string
+	config      oauth2.Config
+	verifier    *oidc.IDTokenVerifier
+	mu          *sync.Mutex
+	pending     map[string]PendingLogin // state -> login
+	now         func() time.Time
+}
+
+// NewOidcService fetches the configuration of the identity provider.
+func NewOidcService(ctx context.Context, userService *UserService, cfg OidcConfig) (*OidcService, error) {
+	provider, err := oidc.NewProvider(ctx, cfg.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("the identity provider configuration could not be loaded: %w", err)
+	}
+	return &OidcService{
+		userService: userService,
+		issuer:      cfg.Issuer,
+		config: oauth2.Config{
+			ClientID:     cfg.ClientId,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectUrl,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       []string{oidc.ScopeOpenID, "email", "profile"},
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: cfg.ClientId}),
+		mu:       &sync.Mutex{},
+		pending:  make(map[string]PendingLogin),
+		now:      time.Now,
+	}, nil
+}
+
+// AuthCodeUrl starts a sign-in and returns the url of the identity provider to send the user to. The client
+// redirect must be a loopback url, since the JWT is passed to it.
+func (s *OidcService) AuthCodeUrl(clientRedirect string, clientState string) (string, error) {
+	if clientRedirect != "" && !isLoopback(clientRedirect) {
+		return "", errors.New("the redirect uri must be a http loopback address, like http://127.0.0.1:1234/callback")
+	}
+
+	state, err := randomString()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return "", err
+	}
+	login := PendingLogin{
+		verifier:       oauth2.GenerateVerifier(),
+		nonce:          nonce,
+		ClientRedirect: clientRedirect,
+		ClientState:    clientState,
+		expires:        s.now().Add(pendingLoginLifetime),
+	}
+
+	s.mu.Lock()
+	for k, p := range s.pending {
+		if s.now().After(p.expires) {
+			delete(s.pending, k)
+		}
+	}
+	s.pending[state] = login
+	s.mu.Unlock()
+
+	return s.config.AuthCodeURL(state, oauth2.S256ChallengeOption(login.verifier), oidc.Nonce(nonce)), nil
+}
+
+// Exchange completes the sign-in with the code from the identity provider, and returns the linked user together
+// with the login that was started by AuthCodeUrl.
+func (s *OidcService) Exchange(ctx context.Context, code string, state string) (model.User, PendingLogin, error) {
+	s.mu.Lock()
+	login, ok := s.pending[state]
+	delete(s.pending, state)
+	s.mu.Unlock()
+	if !ok || s.now().After(login.expires) {
+		return model.User{}, PendingLogin{}, errors.New("the sign-in is unknown or has expired, please try again")
+	}
+
+	token, err := s.config.Exchange(ctx, code, oauth2.VerifierOption(login.verifier))
+	if err != nil {
+		return model.User{}, login, fmt.Errorf("the code could not be exchanged: %w", err)
+	}
+	rawIdToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return model.User{}, login, errors.New("the identity provider didn't return an id token")
+	}
+	idToken, err := s.verifier.Verify(ctx, rawIdToken)
+	if err != nil {
+		return model.User{}, login, fmt.Errorf("the id token is invalid: %w", err)
+	}
+	if idToken.Nonce != login.nonce {
+		return model.User{}, login, errors.New("the id token doesn't belong to this sign-in")
+	}
+
+	var claims struct {
+		Email             string `json:"email"`
+		EmailVerified     bool   `json:"email_verified"`
+		Name              string `json:"name"`
+		PreferredUsername string `json:"preferred_username"`
+	}
+	if err = idToken.Claims(&claims); err != nil {
+		return model.User{}, login, err
+	}
+
+	name := claims.Name
+	if name == "" {
+		name = claims.PreferredUsername
+	}
+	user, err := s.link(ctx, idToken.Subject, claims.Email, claims.EmailVerified, name)
+	return user, login, err
+}
+
+// link returns the user of the identity, linking it by e-mail address (or creating a user) on the first sign-in.
+func (s *OidcService) link(ctx context.Context, subject string, email string, emailVerified bool, name string) (model.User, error) {
+	repo := s.userService.repo
+	user, err := repo.FindByIdentity(ctx, s.issuer, subject)
+	if err != nil {
+		return model.User{}, err
+	}
+	if !user.Empty() {
+		return user, nil
+	}
+
+	if !emailVerified || email == "" {
+		return model.User{}, errors.New("the identity provider didn't confirm that the e-mail address is verified")
+	}
+	user, err = s.userService.FindUserByEmail(ctx, email)
+	if err != nil {
+		return model.User{}, err
+	}
+	if user.Empty() {
+		if name == "" {
+			name, _, _ = strings.Cut(email, "@")
+		}
+		if user, err = s.createUser(ctx, email, name); err != nil {
+			return model.User{}, err
+		}
+	}
+	if !user.Verified {
+		// Anyone can register an unverified account with the e-mail address of someone else. The identity provider
+		// proves that the address belongs to this user, so the password and the tokens of whoever registered the
+		// account are dropped, before it is verified and linked.
+		if user.Token != "" {
+			user.Token = ""
+			if err = repo.Update(ctx, user); err != nil {
+				return model.User{}, err
+			}
+			if err = repo.RevokeTokens(ctx, user.Id); err != nil {
+				return model.User{}, err
+			}
+			user.TokenVersion++
+		}
+		if err = repo.SetVerified(ctx, user.Id); err != nil {
+			return model.User{}, err
+		}
+		user.Verified = true
+		s.userService.Invalidate(user.Email)
+	}
+	if err = repo.AddIdentity(ctx, user.Id, s.issuer, subject); err != nil {
+		return model.User{}, err
+	}
+	log.WithContext(ctx).Debugf("Linked identity %s of %s to user %d (%s)", subject, s.issuer, user.Id, user.Email)
+	return user, nil
+}
+
+// createUser creates the user of an identity that signs in for the first time. Names are unique, so when the name at
+// the identity provider is taken, a few random characters are appended to it.
+func (s *OidcService) createUser(ctx context.Context, email string, name string) (model.User, error) {
+	candidate := name
+	for attempt := 0; ; attempt++ {
+		// Without a password hash, the user can only sign in through the identity provider.
+		user, err := s.userService.CreateUser(ctx, email, candidate, "")
+		if !errors.As(err, &NameTakenError{}) || attempt == 3 {
+			return user, err
+		}
+		suffix, err := randomString()
+		if err != nil {
+			return model.User{}, err
+		}
+		candidate = name + " " + suffix[:4]
+	}
+}
+
+func isLoopback(redirect string) bool {
+	u, err := url.Parse(redirect)
+	if err != nil || u.Scheme != "http" {
+		return false
+	}
+	host := u.Hostname()
+	return host == "127.0.0.1" || host == "::1" || host == "localhost"
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
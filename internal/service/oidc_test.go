@@ -0,0 +1,180 @@
+package service
+
+import (
+	"connectfour/internal/db"
+	"connectfour/internal/model"
+	"connectfour/internal/oidctest"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// mockedOidcService returns a service that signs in with a mock identity provider.
+func mockedOidcService(t *testing.T, identity oidctest.Identity) (*OidcService, *db.MockUserRepository) {
+	idp, err := oidctest.NewMockIdP(identity)
+	assert.NoError(t, err)
+	srv := httptest.NewServer(idp)
+	t.Cleanup(srv.Close)
+	idp.Issuer = srv.URL
+
+	repo := db.NewMockUserRepository()
+	s, err := NewOidcService(context.Background(), NewUserService(repo, 0), OidcConfig{
+		Issuer:      srv.URL,
+		ClientId:    "connectfour",
+		RedirectUrl: "http://localhost:8443/oidc/callback",
+	})
+	assert.NoError(t, err)
+	return s, repo
+}
+
+// authorize follows the authorization url to the mock identity provider, and returns the code and state that it
+// redirects back with.
+func authorize(t *testing.T, authUrl string) (string, string) {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authUrl)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	location, err := url.Parse(resp.Header.Get("Location"))
+	assert.NoError(t, err)
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func TestOidcService_LinksExistingUserByVerifiedEmail(t *testing.T) {
+	// Arrange
+	s, repo := mockedOidcService(t, oidctest.Identity{Subject: "abc123", Email: user1.Email, EmailVerified: true})
+	repo.On("FindByIdentity", mock.Anything, s.issuer, "abc123").Return(model.User{}, nil)
+	repo.On("FindByEmail", mock.Anything, user1.Email).Return(user1, nil)
+	repo.On("Update", mock.Anything, mock.Anything).Return(nil)
+	repo.On("RevokeTokens", mock.Anything, user1.Id).Return(nil)
+	repo.On("SetVerified", mock.Anything, user1.Id).Return(nil)
+	repo.On("AddIdentity", mock.Anything, user1.Id, s.issuer, "abc123").Return(nil)
+
+	authUrl, err := s.AuthCodeUrl("http://127.0.0.1:5000/callback", "client-state")
+	assert.NoError(t, err)
+	code, state := authorize(t, authUrl)
+
+	// Act
+	user, login, err := s.Exchange(context.Background(), code, state)
+	_, _, errReplay := s.Exchange(context.Background(), code, state)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, user.Is(user1))
+	assert.True(t, user.Verified, "Expected the e-mail address to be verified by the identity provider")
+	assert.Equal(t, "http://127.0.0.1:5000/callback", login.ClientRedirect)
+	assert.Equal(t, "client-state", login.ClientState)
+	repo.AssertCalled(t, "AddIdentity", mock.Anything, user1.Id, s.issuer, "abc123")
+	assert.Error(t, errReplay, "Expected the state to be usable only once")
+}
+
+func TestOidcService_LinkingUnverifiedUserDropsTheirPasswordAndTokens(t *testing.T) {
+	// Arrange
+	registered := user1
+	registered.Id = 7
+	registered.Token = "hash of the password of whoever registered"
+	s, repo := mockedOidcService(t, oidctest.Identity{Subject: "abc123", Email: registered.Email, EmailVerified: true})
+	repo.On("FindByIdentity", mock.Anything, s.issuer, "abc123").Return(model.User{}, nil)
+	repo.On("FindByEmail", mock.Anything, registered.Email).Return(registered, nil)
+	repo.On("Update", mock.Anything, mock.Anything).Return(nil)
+	repo.On("RevokeTokens", mock.Anything, registered.Id).Return(nil)
+	repo.On("SetVerified", mock.Anything, registered.Id).Return(nil)
+	repo.On("AddIdentity", mock.Anything, registered.Id, s.issuer, "abc123").Return(nil)
+
+	authUrl, _ := s.AuthCodeUrl("", "")
+	code, state := authorize(t, authUrl)
+
+	// Act
+	user, _, err := s.Exchange(context.Background(), code, state)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Empty(t, user.Token, "Expected the password of the unverified account to be removed")
+	repo.AssertCalled(t, "Update", mock.Anything, mock.MatchedBy(func(u model.User) bool {
+		return u.Id == registered.Id && u.Token == ""
+	}))
+	repo.AssertCalled(t, "RevokeTokens", mock.Anything, registered.Id)
+	assert.Equal(t, registered.TokenVersion+1, user.TokenVersion)
+}
+
+func TestOidcService_LinkingVerifiedUserKeepsTheirPassword(t *testing.T) {
+	// Arrange
+	verified := user1
+	verified.Id = 7
+	verified.Verified = true
+	s, repo := mockedOidcService(t, oidctest.Identity{Subject: "abc123", Email: verified.Email, EmailVerified: true})
+	repo.On("FindByIdentity", mock.Anything, s.issuer, "abc123").Return(model.User{}, nil)
+	repo.On("FindByEmail", mock.Anything, verified.Email).Return(verified, nil)
+	repo.On("AddIdentity", mock.Anything, verified.Id, s.issuer, "abc123").Return(nil)
+
+	authUrl, _ := s.AuthCodeUrl("", "")
+	code, state := authorize(t, authUrl)
+
+	// Act
+	user, _, err := s.Exchange(context.Background(), code, state)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, verified.Token, user.Token)
+	repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	repo.AssertNotCalled(t, "RevokeTokens", mock.Anything, mock.Anything)
+}
+
+func TestOidcService_CreatesUserWithAnotherNameWhenTaken(t *testing.T) {
+	// Arrange
+	s, repo := mockedOidcService(t, oidctest.Identity{Subject: "abc123", Email: "alice@example.com", EmailVerified: true, Name: "Alice"})
+	created := model.User{Id: 9, Email: "alice@example.com"}
+	repo.On("FindByIdentity", mock.Anything, s.issuer, "abc123").Return(model.User{}, nil)
+	repo.On("FindByEmail", mock.Anything, "alice@example.com").Return(model.User{}, nil)
+	repo.On("Create", mock.Anything, mock.MatchedBy(func(u model.User) bool { return u.Name == "Alice" })).
+		Return(model.User{}, db.ErrNameTaken)
+	repo.On("Create", mock.Anything, mock.AnythingOfType("model.User")).Return(created, nil)
+	repo.On("SetVerified", mock.Anything, created.Id).Return(nil)
+	repo.On("AddIdentity", mock.Anything, created.Id, s.issuer, "abc123").Return(nil)
+
+	authUrl, _ := s.AuthCodeUrl("", "")
+	code, state := authorize(t, authUrl)
+
+	// Act
+	user, _, err := s.Exchange(context.Background(), code, state)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, created.Id, user.Id)
+	repo.AssertCalled(t, "Create", mock.Anything, mock.MatchedBy(func(u model.User) bool {
+		return strings.HasPrefix(u.Name, "Alice ") && len(u.Name) == len("Alice ")+4
+	}))
+	repo.AssertNotCalled(t, "RevokeTokens", mock.Anything, mock.Anything)
+}
+
+func TestOidcService_RefusesUnverifiedEmail(t *testing.T) {
+	// Arrange
+	s, repo := mockedOidcService(t, oidctest.Identity{Subject: "abc123", Email: user1.Email, EmailVerified: false})
+	repo.On("FindByIdentity", mock.Anything, s.issuer, "abc123").Return(model.User{}, nil)
+
+	authUrl, _ := s.AuthCodeUrl("", "")
+	code, state := authorize(t, authUrl)
+
+	// Act
+	_, _, err := s.Exchange(context.Background(), code, state)
+
+	// Assert
+	assert.Error(t, err, "Expected an unverified e-mail address not to be linked to an existing user")
+	repo.AssertNotCalled(t, "FindByEmail", mock.Anything, mock.Anything)
+}
+
+func TestOidcService_AuthCodeUrl_OnlyLoopbackRedirects(t *testing.T) {
+	// Arrange
+	s, _ := mockedOidcService(t, oidctest.Identity{Subject: "abc123"})
+
+	// Act
+	_, err := s.AuthCodeUrl("https://evil.example.com/callback", "")
+
+	// Assert
+	assert.Error(t, err)
+}
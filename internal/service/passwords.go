@@ -0,0 +1,97 @@
+package service
+
+import (
+	"connectfour/internal/mail"
+	"connectfour/internal/model"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+const resetTokenLifetime = 30 * time.Minute
+
+// InvalidResetTokenError is returned when a reset token doesn't exist, was already used or has expired.
+type InvalidResetTokenError struct{}
+
+func (e InvalidResetTokenError) Error() string {
+	return "the reset token is invalid or has expired"
+}
+
+// PasswordResetService lets users that forgot their password choose a new one, with a single-use token that is sent
+// to their e-mail address. Only the hash of the token is stored.
+type PasswordResetService struct {
+	userService *UserService
+	mailer      mail.Mailer
+	now         func() time.Time
+}
+
+func NewPasswordResetService(userService *UserService, mailer mail.Mailer) *PasswordResetService {
+	return &PasswordResetService{
+		userService: userService,
+		mailer:      mailer,
+		now:         time.Now,
+	}
+}
+
+// Forgot sends a reset token to the user. Whether the e-mail address is known is not revealed to the caller, so an
+// unknown address doesn't return an error.
+func (s PasswordResetService) Forgot(ctx context.Context, email string) error {
+	user, err := s.userService.FindUserByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if user.Empty() || user.Bot {
+		log.WithContext(ctx).Debugf("Password reset requested for unknown user %s", email)
+		return nil
+	}
+
+	token, err := randomString()
+	if err != nil {
+		return err
+	}
+	if err = s.userService.repo.CreateResetToken(ctx, user.Id, hashResetToken(token), s.now().Add(resetTokenLifetime)); err != nil {
+		return err
+	}
+
+	return s.mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Reset your ConnectFour password",
+		Body: fmt.Sprintf(`Hi %s,
+
+Somebody (hopefully you) asked to reset the password of your ConnectFour account.
+Use this token to choose a new password within %d minutes:
+
+    %s
+
+If you didn't ask for this, you can ignore this e-mail. Your password has not been changed.
+`, user.Name, int(resetTokenLifetime.Minutes()), token),
+	})
+}
+
+// Reset consumes the token and stores the new password hash of its user, which is returned. The tokens that were
+// issued before are revoked, since the password may have been reset because the account was compromised.
+func (s PasswordResetService) Reset(ctx context.Context, token string, passwordHash string) (model.User, error) {
+	user, err := s.userService.repo.ConsumeResetToken(ctx, hashResetToken(strings.TrimSpace(token)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.User{}, InvalidResetTokenError{}
+	}
+	if err != nil {
+		return model.User{}, err
+	}
+	log.WithContext(ctx).Debugf("Resetting the password of %s", user.Email)
+	if err = s.userService.ChangePassword(ctx, user.Email, passwordHash); err != nil {
+		return model.User{}, err
+	}
+	return s.userService.RevokeTokens(ctx, user.Email)
+}
+
+func hashResetToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"connectfour/internal/db"
+	"connectfour/internal/mail"
+	"connectfour/internal/model"
+	"context"
+	"database/sql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordingMailer keeps the sent messages, instead of sending them.
+type recordingMailer struct {
+	sent []mail.Message
+}
+
+func (m *recordingMailer) Send(msg mail.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func TestPasswordResetService_Forgot(t *testing.T) {
+	// Arrange
+	repo := db.NewMockUserRepository()
+	repo.On("FindByEmail", mock.Anything, user1.Email).Return(user1, nil)
+	repo.On("CreateResetToken", mock.Anything, user1.Id, mock.AnythingOfType("string"), mock.AnythingOfType("time.Time")).Return(nil)
+	mailer := &recordingMailer{}
+	s := NewPasswordResetService(NewUserService(repo, time.Minute), mailer)
+
+	// Act
+	err := s.Forgot(context.Background(), user1.Email)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, mailer.sent, 1)
+	assert.Equal(t, user1.Email, mailer.sent[0].To)
+
+	// The mail contains the token, while only its hash is stored.
+	tokenHash := repo.Calls[1].Arguments.String(2)
+	found := false
+	for _, line := range strings.Split(mailer.sent[0].Body, "\n") {
+		if token := strings.TrimSpace(line); token != "" && hashResetToken(token) == tokenHash {
+			found = true
+		}
+	}
+	assert.True(t, found, "Expected the mail to contain the token that belongs to the stored hash")
+}
+
+func TestPasswordResetService_Forgot_UnknownUser(t *testing.T) {
+	// Arrange
+	repo := db.NewMockUserRepository()
+	repo.On("FindByEmail", mock.Anything, "nobody@evilnerd.nl").Return(model.User{}, nil)
+	mailer := &recordingMailer{}
+	s := NewPasswordResetService(NewUserService(repo, time.Minute), mailer)
+
+	// Act
+	err := s.Forgot(context.Background(), "nobody@evilnerd.nl")
+
+	// Assert
+	assert.NoError(t, err, "Expected no error, so it can't be used to find out which addresses are known")
+	assert.Empty(t, mailer.sent)
+}
+
+func TestPasswordResetService_Reset(t *testing.T) {
+	// Arrange
+	repo := db.NewMockUserRepository()
+	repo.On("ConsumeResetToken", mock.Anything, hashResetToken("valid")).Return(user1, nil)
+	repo.On("ConsumeResetToken", mock.Anything, mock.Anything).Return(model.User{}, sql.ErrNoRows)
+	repo.On("FindByEmail", mock.Anything, user1.Email).Return(user1, nil)
+	repo.On("Update", mock.Anything, mock.AnythingOfType("model.User")).Return(nil)
+	repo.On("RevokeTokens", mock.Anything, user1.Id).Return(nil)
+	s := NewPasswordResetService(NewUserService(repo, time.Minute), &recordingMailer{})
+
+	// Act
+	user, err1 := s.Reset(context.Background(), " valid ", "newhash")
+	_, err2 := s.Reset(context.Background(), "invalid", "newhash")
+
+	// Assert
+	assert.NoError(t, err1)
+	assert.True(t, user.Is(user1))
+	repo.AssertCalled(t, "Update", mock.Anything, mock.MatchedBy(func(u model.User) bool { return u.Token == "newhash" }))
+	repo.AssertCalled(t, "RevokeTokens", mock.Anything, user1.Id)
+	assert.Equal(t, user1.TokenVersion+1, user.TokenVersion, "Expected the tokens from before the reset to be revoked")
+	assert.ErrorAs(t, err2, &InvalidResetTokenError{})
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"connectfour/internal/model"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	onlineWindow = 2 * time.Minute  // users with activity within this window are online (or in game).
+	idleWindow   = 15 * time.Minute // users with activity within this window are idle, after that they're offline.
+)
+
+// PresenceTracker derives the presence of users from their authenticated activity and the games they play.
+// It's kept in memory, so it only knows about the activity on this api instance.
+type PresenceTracker struct {
+	mu       sync.RWMutex
+	lastSeen map[string]time.Time
+	playing  map[string]string // email -> key of the game the user is playing.
+	now      func() time.Time
+}
+
+func NewPresenceTracker() *PresenceTracker {
+	return &PresenceTracker{
+		lastSeen: make(map[string]time.Time),
+		playing:  make(map[string]string),
+		now:      time.Now,
+	}
+}
+
+// Touch registers activity of the user.
+func (p *PresenceTracker) Touch(email string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastSeen[strings.ToLower(email)] = p.now()
+}
+
+// Presence returns whether the user is online, in game, idle or offline.
+func (p *PresenceTracker) Presence(email string) model.Presence {
+	email = strings.ToLower(email)
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	seen, ok := p.lastSeen[email]
+	if !ok {
+		return model.Offline
+	}
+	since := p.now().Sub(seen)
+	switch {
+	case since <= onlineWindow && p.playing[email] != "":
+		return model.InGame
+	case since <= onlineWindow:
+		return model.Online
+	case since <= idleWindow:
+		return model.Idle
+	}
+	return model.Offline
+}
+
+// Online counts the users with activity within the online window, including the ones that are in game.
+func (p *PresenceTracker) Online() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	count := 0
+	for _, seen := range p.lastSeen {
+		if p.now().Sub(seen) <= onlineWindow {
+			count++
+		}
+	}
+	return count
+}
+
+// onGameEvent marks players as in game while they are playing a started game.
+func (p *PresenceTracker) onGameEvent(event GameEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	players := []string{strings.ToLower(event.Game.Player1.Email), strings.ToLower(event.Game.Player2.Email)}
+	switch event.Type {
+	case GameJoinedEvent, MovePlayedEvent:
+		if event.Game.Status != model.Started {
+			return
+		}
+		for _, email := range players {
+			if email != "" {
+				p.playing[email] = event.Game.Key
+			}
+		}
+	case GameFinishedEvent:
+		for _, email := range players {
+			if p.playing[email] == event.Game.Key {
+				delete(p.playing, email)
+			}
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"connectfour/internal/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestPresenceTracker_Presence(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	p := NewPresenceTracker()
+	p.now = func() time.Time { return now }
+	p.Touch(user1.Email)
+
+	// Act & Assert
+	assert.Equal(t, model.Online, p.Presence(user1.Email))
+	assert.Equal(t, model.Offline, p.Presence(user2.Email), "Expected a user without activity to be offline")
+
+	now = now.Add(5 * time.Minute)
+	assert.Equal(t, model.Idle, p.Presence(user1.Email))
+
+	now = now.Add(time.Hour)
+	assert.Equal(t, model.Offline, p.Presence(user1.Email))
+}
+
+func TestPresenceTracker_InGame(t *testing.T) {
+	// Arrange
+	p := NewPresenceTracker()
+	game := model.NewGame(user1, false)
+	_ = game.Join(user2)
+	p.Touch(user1.Email)
+
+	// Act
+	p.onGameEvent(GameEvent{Type: GameJoinedEvent, Game: game})
+	playing := p.Presence(user1.Email)
+	game.Status = model.Finished
+	p.onGameEvent(GameEvent{Type: GameFinishedEvent, Game: game})
+	finished := p.Presence(user1.Email)
+
+	// Assert
+	assert.Equal(t, model.InGame, playing)
+	assert.Equal(t, model.Online, finished)
+}
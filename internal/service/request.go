@@ -1,13 +1,36 @@
 package service
 
+import (
+	"connectfour/internal/model"
+	"time"
+)
+
 type NewGameRequest struct {
-	Public bool `json:"public"`
+	Public  bool   `json:"public"`
+	Invitee string `json:"invitee,omitempty"` // e-mail address of the only player that may join.
+}
+
+// GameListRequest narrows a game listing down and pages through it. It's read from the query parameters, and empty
+// fields don't filter.
+type GameListRequest struct {
+	Status   model.GameStatus
+	Opponent string // the e-mail address of the other player.
+	Public   *bool
+	From     time.Time
+	Until    time.Time
+	Order    model.GameOrder
+	Cursor   string // the NextCursor of the previous page.
+	Limit    int
 }
 
 type PlayMoveRequest struct {
 	Column int `json:"column"`
 }
 
+type FriendRequest struct {
+	Email string `json:"email"`
+}
+
 type LoginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
@@ -17,4 +40,58 @@ type RegisterRequest struct {
 	Name     string `json:"name"`
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	Bot      bool   `json:"bot"` // refused: only admins make bots.
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type VerifyRequest struct {
+	Code string `json:"code"`
+}
+
+type UpdateProfileRequest struct {
+	Name string `json:"name"`
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password"` // asked again, so a stolen token can't delete the account.
+}
+
+type SetRoleRequest struct {
+	Role model.Role `json:"role"`
+}
+
+type SetBotRequest struct {
+	Bot bool `json:"bot"`
+}
+
+type NewTournamentRequest struct {
+	Name   string                 `json:"name"`
+	Format model.TournamentFormat `json:"format"`
+}
+
+// NotificationPreferencesRequest replaces the notification preferences of the user.
+type NotificationPreferencesRequest struct {
+	Channels []model.NotificationChannel `json:"channels"`
+	Muted    []model.NotificationKind    `json:"muted"`
+}
+
+// WebhookRequest registers a webhook for the events, or for all events when there are none. Only admins can register
+// a webhook for all games instead of their own.
+type WebhookRequest struct {
+	Url      string          `json:"url"`
+	Events   []GameEventType `json:"events"`
+	AllGames bool            `json:"all_games"`
 }
@@ -10,6 +10,7 @@ type NewGameResponse struct {
 	CreatedAt time.Time        `json:"created_at"`
 	CreatedBy string           `json:"created_by"`
 	Status    model.GameStatus `json:"status"`
+	Invitee   string           `json:"invitee,omitempty"`
 }
 
 func NewGameResponseFromGame(game model.Game) NewGameResponse {
@@ -18,6 +19,72 @@ func NewGameResponseFromGame(game model.Game) NewGameResponse {
 		CreatedAt: game.CreatedAt,
 		CreatedBy: game.Player1.Email,
 		Status:    game.Status,
+		Invitee:   game.Invitee.Email,
+	}
+}
+
+// GameListingResponse is a game in a listing, with what a player needs to pick a game from it: who plays, whose turn
+// it is and how far along the game is.
+type GameListingResponse struct {
+	Key             string           `json:"key"`
+	CreatedAt       time.Time        `json:"created_at"`
+	CreatedBy       string           `json:"created_by"`
+	Status          model.GameStatus `json:"status"`
+	Invitee         string           `json:"invitee,omitempty"`
+	Public          bool             `json:"public"`
+	Player1Name     string           `json:"player1_name"`
+	Player1Email    string           `json:"player1_email"`
+	Player2Name     string           `json:"player2_name,omitempty"`
+	Player2Email    string           `json:"player2_email,omitempty"`
+	PlayerTurn      int              `json:"player_turn,omitempty"`       // 1 or 2, while the game is played.
+	PlayerTurnEmail string           `json:"player_turn_email,omitempty"` // while the game is played.
+	Moves           int              `json:"moves"`
+	LastMoveAt      time.Time        `json:"last_move_at,omitzero"`
+	Outcome         string           `json:"outcome,omitempty"` // player1, player2, draw or aborted, once the game ended.
+}
+
+func NewGameListingResponse(game model.Game) GameListingResponse {
+	r := GameListingResponse{
+		Key:          game.Key,
+		CreatedAt:    game.CreatedAt,
+		CreatedBy:    game.Player1.Email,
+		Status:       game.Status,
+		Invitee:      game.Invitee.Email,
+		Public:       game.Public,
+		Player1Name:  game.Player1.Name,
+		Player1Email: game.Player1.Email,
+		Player2Name:  game.Player2.Name,
+		Player2Email: game.Player2.Email,
+		Moves:        game.Board.Moves(),
+		LastMoveAt:   game.LastMoveAt,
+		Outcome:      game.Outcome(),
+	}
+	if game.Status == model.Started {
+		r.PlayerTurn = game.PlayerTurn
+		r.PlayerTurnEmail = game.CurrentPlayerEmail()
+	}
+	return r
+}
+
+// GamePage is a page of a game listing. NextCursor is empty on the last page.
+type GamePage struct {
+	Games      []GameListingResponse
+	NextCursor string
+}
+
+type InvitationResponse struct {
+	Key       string    `json:"key"`
+	FromName  string    `json:"from_name"`
+	FromEmail string    `json:"from_email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewInvitationResponse(game model.Game) InvitationResponse {
+	return InvitationResponse{
+		Key:       game.Key,
+		FromName:  game.Player1.Name,
+		FromEmail: game.Player1.Email,
+		CreatedAt: game.CreatedAt,
 	}
 }
 
@@ -49,14 +116,314 @@ func NewGameStateResponse(game model.Game) GameStateResponse {
 	}
 }
 
+// TurnResponse is returned by the long-poll endpoint that bots use to wait for their turn.
+type TurnResponse struct {
+	YourTurn bool              `json:"your_turn"`
+	Disc     model.Disc        `json:"disc"` // the disc the calling player plays with.
+	State    GameStateResponse `json:"state"`
+}
+
+type FriendResponse struct {
+	Name     string             `json:"name"`
+	Email    string             `json:"email"`
+	Bot      bool               `json:"bot"`
+	Status   model.FriendStatus `json:"status"`
+	Incoming bool               `json:"incoming"` // true when the other player sent the friend request.
+	Presence model.Presence     `json:"presence,omitempty"`
+}
+
+func NewFriendResponse(f model.Friend) FriendResponse {
+	return FriendResponse{
+		Name:     f.User.Name,
+		Email:    f.User.Email,
+		Bot:      f.User.Bot,
+		Status:   f.Status,
+		Incoming: f.Incoming,
+	}
+}
+
 type CreateUserResponse struct {
 	Name  string `json:"name"`
 	Email string `json:"email"`
+	Bot   bool   `json:"bot"`
 }
 
 func NewCreateUserResponse(u model.User) CreateUserResponse {
 	return CreateUserResponse{
 		Name:  u.Name,
 		Email: u.Email,
+		Bot:   u.Bot,
 	}
 }
+
+type ProfileResponse struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Bot      bool   `json:"bot"`
+	Verified bool   `json:"verified"`
+	Token    string `json:"token,omitempty"` // a new JWT, when the name in the old one is outdated.
+}
+
+func NewProfileResponse(u model.User) ProfileResponse {
+	return ProfileResponse{
+		Name:     u.Name,
+		Email:    u.Email,
+		Bot:      u.Bot,
+		Verified: u.Verified,
+	}
+}
+
+// AdminUserResponse is a user as moderators and admins see it.
+type AdminUserResponse struct {
+	Id       int64      `json:"id"`
+	Name     string     `json:"name"`
+	Email    string     `json:"email"`
+	Bot      bool       `json:"bot"`
+	Verified bool       `json:"verified"`
+	Role     model.Role `json:"role"`
+	Banned   bool       `json:"banned"`
+}
+
+func NewAdminUserResponse(u model.User) AdminUserResponse {
+	return AdminUserResponse{
+		Id:       u.Id,
+		Name:     u.Name,
+		Email:    u.Email,
+		Bot:      u.Bot,
+		Verified: u.Verified,
+		Role:     u.Role,
+		Banned:   u.Banned,
+	}
+}
+
+type StatsResponse struct {
+	Users         int                      `json:"users"`
+	Bots          int                      `json:"bots"`
+	VerifiedUsers int                      `json:"verified_users"`
+	BannedUsers   int                      `json:"banned_users"`
+	OnlineUsers   int                      `json:"online_users"` // on this api instance.
+	Games         map[model.GameStatus]int `json:"games"`
+}
+
+// HealthResponse tells whether the api can reach the database, with the connections in its pool.
+type HealthResponse struct {
+	Status      string `json:"status"`   // "ok" or "unavailable".
+	Database    string `json:"database"` // "ok" or "unreachable".
+	Connections struct {
+		Open  int `json:"open"`
+		InUse int `json:"in_use"`
+		Idle  int `json:"idle"`
+		Max   int `json:"max"`
+	} `json:"connections"`
+}
+
+type AuditEntryResponse struct {
+	Id        int64             `json:"id"`
+	Time      time.Time         `json:"time"`
+	Action    model.AuditAction `json:"action"`
+	Actor     string            `json:"actor"`
+	Ip        string            `json:"ip"`
+	RequestId string            `json:"request_id"`
+	Subject   string            `json:"subject,omitempty"`
+	Details   string            `json:"details,omitempty"`
+}
+
+func NewAuditEntryResponse(e model.AuditEntry) AuditEntryResponse {
+	return AuditEntryResponse{
+		Id:        e.Id,
+		Time:      e.Time,
+		Action:    e.Action,
+		Actor:     e.Actor,
+		Ip:        e.Ip,
+		RequestId: e.RequestId,
+		Subject:   e.Subject,
+		Details:   e.Details,
+	}
+}
+
+type TournamentResponse struct {
+	Id           int64                  `json:"id"`
+	Name         string                 `json:"name"`
+	Format       model.TournamentFormat `json:"format"`
+	Status       model.TournamentStatus `json:"status"`
+	Organizer    string                 `json:"organizer"`
+	Rounds       int                    `json:"rounds"`
+	CurrentRound int                    `json:"current_round"`
+	CreatedAt    time.Time              `json:"created_at"`
+	Players      []string               `json:"players"`
+	Winner       string                 `json:"winner,omitempty"`
+	Standings    []StandingResponse     `json:"standings,omitempty"`
+	Pairings     []PairingResponse      `json:"pairings,omitempty"`
+}
+
+type StandingResponse struct {
+	Rank   int     `json:"rank"`
+	Name   string  `json:"name"`
+	Email  string  `json:"email"`
+	Played int     `json:"played"`
+	Wins   int     `json:"wins"`
+	Draws  int     `json:"draws"`
+	Losses int     `json:"losses"`
+	Points float64 `json:"points"`
+}
+
+type PairingResponse struct {
+	Round        int                 `json:"round"`
+	GameKey      string              `json:"game_key"`
+	Player1Name  string              `json:"player1_name"`
+	Player1Email string              `json:"player1_email"`
+	Player2Name  string              `json:"player2_name"`
+	Player2Email string              `json:"player2_email"`
+	Result       model.PairingResult `json:"result"`
+}
+
+// NewTournamentResponse returns the summary of a tournament, without standings and pairings.
+func NewTournamentResponse(t model.Tournament) TournamentResponse {
+	players := make([]string, 0, len(t.Players))
+	for _, p := range t.Players {
+		players = append(players, p.Name)
+	}
+	return TournamentResponse{
+		Id:           t.Id,
+		Name:         t.Name,
+		Format:       t.Format,
+		Status:       t.Status,
+		Organizer:    t.Organizer.Email,
+		Rounds:       t.Rounds,
+		CurrentRound: t.CurrentRound,
+		CreatedAt:    t.CreatedAt,
+		Players:      players,
+		Winner:       t.Winner().Name,
+	}
+}
+
+// NewTournamentDetailResponse returns the tournament including the standings and all pairings (the bracket).
+func NewTournamentDetailResponse(t model.Tournament) TournamentResponse {
+	resp := NewTournamentResponse(t)
+	resp.Standings = make([]StandingResponse, 0, len(t.Players))
+	for i, s := range t.Standings() {
+		resp.Standings = append(resp.Standings, StandingResponse{
+			Rank:   i + 1,
+			Name:   s.Player.Name,
+			Email:  s.Player.Email,
+			Played: s.Played,
+			Wins:   s.Wins,
+			Draws:  s.Draws,
+			Losses: s.Losses,
+			Points: s.Points,
+		})
+	}
+	resp.Pairings = make([]PairingResponse, 0, len(t.Pairings))
+	for _, p := range t.Pairings {
+		resp.Pairings = append(resp.Pairings, PairingResponse{
+			Round:        p.Round,
+			GameKey:      p.GameKey,
+			Player1Name:  p.Player1.Name,
+			Player1Email: p.Player1.Email,
+			Player2Name:  p.Player2.Name,
+			Player2Email: p.Player2.Email,
+			Result:       p.Result,
+		})
+	}
+	return resp
+}
+
+type NotificationResponse struct {
+	Id        int64                  `json:"id,omitempty"` // empty when the notification wasn't kept in the inbox.
+	Kind      model.NotificationKind `json:"kind"`
+	GameKey   string                 `json:"game_key"`
+	Message   string                 `json:"message"`
+	CreatedAt time.Time              `json:"created_at"`
+	Read      bool                   `json:"read"`
+}
+
+func NewNotificationResponse(n model.Notification) NotificationResponse {
+	return NotificationResponse{
+		Id:        n.Id,
+		Kind:      n.Kind,
+		GameKey:   n.GameKey,
+		Message:   n.Message,
+		CreatedAt: n.CreatedAt,
+		Read:      !n.ReadAt.IsZero(),
+	}
+}
+
+type NotificationPreferencesResponse struct {
+	Channels []model.NotificationChannel `json:"channels"`
+	Muted    []model.NotificationKind    `json:"muted"`
+}
+
+func NewNotificationPreferencesResponse(p model.NotificationPreferences) NotificationPreferencesResponse {
+	return NotificationPreferencesResponse{
+		Channels: p.Channels,
+		Muted:    p.Muted,
+	}
+}
+
+type WebhookResponse struct {
+	Id        int64     `json:"id"`
+	Url       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"` // only returned when the webhook is registered.
+	Events    []string  `json:"events"`
+	AllGames  bool      `json:"all_games"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewWebhookResponse(w model.Webhook) WebhookResponse {
+	return WebhookResponse{
+		Id:        w.Id,
+		Url:       w.Url,
+		Events:    w.Events,
+		AllGames:  w.AllGames,
+		CreatedAt: w.CreatedAt,
+	}
+}
+
+type WebhookDeliveryResponse struct {
+	Id             int64                `json:"id"`
+	Event          string               `json:"event"`
+	GameKey        string               `json:"game_key"`
+	Status         model.DeliveryStatus `json:"status"`
+	Attempts       int                  `json:"attempts"`
+	ResponseStatus int                  `json:"response_status,omitempty"`
+	Error          string               `json:"error,omitempty"`
+	CreatedAt      time.Time            `json:"created_at"`
+	LastAttemptAt  *time.Time           `json:"last_attempt_at,omitempty"`
+	NextAttemptAt  *time.Time           `json:"next_attempt_at,omitempty"`
+}
+
+func NewWebhookDeliveryResponse(d model.WebhookDelivery) WebhookDeliveryResponse {
+	resp := WebhookDeliveryResponse{
+		Id:             d.Id,
+		Event:          d.Event,
+		GameKey:        d.GameKey,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		ResponseStatus: d.ResponseStatus,
+		Error:          d.Error,
+		CreatedAt:      d.CreatedAt,
+	}
+	if !d.LastAttemptAt.IsZero() {
+		resp.LastAttemptAt = &d.LastAttemptAt
+	}
+	if !d.NextAttemptAt.IsZero() {
+		resp.NextAttemptAt = &d.NextAttemptAt
+	}
+	return resp
+}
+
+// WebhookPayload is what is posted to a webhook. The game has the shape of the game state that GET /games/{key}
+// returns.
+type WebhookPayload struct {
+	Event      GameEventType     `json:"event"`
+	Actor      string            `json:"actor"` // the e-mail address of the player that caused the event.
+	OccurredAt time.Time         `json:"occurred_at"`
+	Game       GameStateResponse `json:"game"`
+}
+
+// WebhookNotificationPayload is what the webhook channel of the notifications posts to a webhook.
+type WebhookNotificationPayload struct {
+	Event        GameEventType        `json:"event"` // always NotificationEvent.
+	OccurredAt   time.Time            `json:"occurred_at"`
+	Notification NotificationResponse `json:"notification"`
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"connectfour/internal/db"
+	"connectfour/internal/model"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"sync"
+	"time"
+)
+
+type TournamentService struct {
+	repo         db.TournamentRepository
+	userService  *UserService
+	gamesService *GamesService
+	mu           *sync.Mutex // results are processed one at a time, so a round can't be advanced twice.
+}
+
+func NewTournamentService(repo db.TournamentRepository, userService *UserService, gamesService *GamesService) *TournamentService {
+	s := &TournamentService{
+		repo:         repo,
+		userService:  userService,
+		gamesService: gamesService,
+		mu:           &sync.Mutex{},
+	}
+	gamesService.Subscribe(s.onGameEvent)
+	return s
+}
+
+func (s TournamentService) List(ctx context.Context) []TournamentResponse {
+	tournaments, err := s.repo.List(ctx)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error listing tournaments: %v", err)
+		return []TournamentResponse{}
+	}
+	output := make([]TournamentResponse, 0, len(tournaments))
+	for _, t := range tournaments {
+		output = append(output, NewTournamentResponse(t))
+	}
+	return output
+}
+
+func (s TournamentService) Get(ctx context.Context, id int64) (TournamentResponse, error) {
+	t, err := s.repo.Fetch(ctx, id)
+	if err != nil {
+		return TournamentResponse{}, fmt.Errorf("tournament %d could not be loaded", id)
+	}
+	return NewTournamentDetailResponse(t), nil
+}
+
+func (s TournamentService) Create(ctx context.Context, organizerEmail string, name string, format model.TournamentFormat) (TournamentResponse, error) {
+	if strings.TrimSpace(name) == "" {
+		return TournamentResponse{}, errors.New("a tournament needs a name")
+	}
+	if !model.ValidTournamentFormat(format) {
+		return TournamentResponse{}, fmt.Errorf("unknown tournament format '%s'", format)
+	}
+
+	organizer, err := s.userService.FindUserByEmail(ctx, organizerEmail)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error fetching user: %v", err)
+		return TournamentResponse{}, err
+	}
+
+	t, err := s.repo.Create(ctx, model.NewTournament(organizer, name, format))
+	if err != nil {
+		return TournamentResponse{}, err
+	}
+	log.WithContext(ctx).Debugf("Tournament %d (%s) created by %s", t.Id, t.Name, organizerEmail)
+	return NewTournamentResponse(t), nil
+}
+
+func (s TournamentService) Register(ctx context.Context, id int64, email string) (TournamentResponse, error) {
+	player, err := s.userService.FindUserByEmail(ctx, email)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error fetching user: %v", err)
+		return TournamentResponse{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.repo.Fetch(ctx, id)
+	if err != nil {
+		return TournamentResponse{}, fmt.Errorf("tournament %d could not be loaded", id)
+	}
+	if err = t.Register(player); err != nil {
+		return TournamentResponse{}, err
+	}
+	if err = s.repo.AddPlayer(ctx, t.Id, player); err != nil {
+		return TournamentResponse{}, err
+	}
+	return NewTournamentResponse(t), nil
+}
+
+// Start closes the registration and creates the games for the first round. Only the organizer can start a tournament.
+func (s TournamentService) Start(ctx context.Context, id int64, email string, rounds int) (TournamentResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.repo.Fetch(ctx, id)
+	if err != nil {
+		return TournamentResponse{}, fmt.Errorf("tournament %d could not be loaded", id)
+	}
+	if !strings.EqualFold(t.Organizer.Email, email) {
+		return TournamentResponse{}, errors.New("only the organizer can start the tournament")
+	}
+	if err = t.Start(rounds); err != nil {
+		return TournamentResponse{}, err
+	}
+	if err = s.nextRound(ctx, &t); err != nil {
+		return TournamentResponse{}, err
+	}
+	return NewTournamentDetailResponse(t), nil
+}
+
+func (s TournamentService) onGameEvent(event GameEvent) {
+	if event.Type != GameFinishedEvent {
+		return
+	}
+	// not bound to the request that finished the game: the next round must start, also when that request is
+	// cancelled right after the move.
+	if err := s.GameFinished(context.Background(), event.Game); err != nil {
+		log.Errorf("Error processing the result of game %s for its tournament: %v", event.Game.Key, err)
+	}
+}
+
+// GameFinished records the result of a finished game when it's part of a tournament, and starts the next round
+// when it was the last game of the current round.
+func (s TournamentService) GameFinished(ctx context.Context, game model.Game) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.repo.FetchByGameKey(ctx, game.Key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil // just a regular game.
+	}
+	if err != nil {
+		return err
+	}
+
+	pairing := t.PairingByGameKey(game.Key)
+	if pairing == nil || pairing.Result != model.Pending {
+		return nil
+	}
+
+	winner := game.Winner()
+	switch {
+	case winner.Empty():
+		pairing.Result = model.Draw
+	case winner.Is(pairing.Player1):
+		pairing.Result = model.Player1Wins
+	default:
+		pairing.Result = model.Player2Wins
+	}
+	if _, err = s.repo.SavePairing(ctx, t.Id, *pairing); err != nil {
+		return err
+	}
+	log.WithContext(ctx).Debugf("Tournament %d: game %s ended with result '%s'", t.Id, game.Key, pairing.Result)
+
+	// A knockout game needs a winner, so a draw is replayed with the colors swapped.
+	if pairing.Result == model.Draw && t.Format == model.Knockout {
+		replay := model.Pairing{
+			Round:   pairing.Round,
+			Player1: pairing.Player2,
+			Player2: pairing.Player1,
+		}
+		return s.addPairing(ctx, &t, replay)
+	}
+
+	if t.RoundFinished() {
+		return s.nextRound(ctx, &t)
+	}
+	return nil
+}
+
+// nextRound creates the pairings (and games) of the next round, or completes the tournament when all rounds have
+// been played.
+func (s TournamentService) nextRound(ctx context.Context, t *model.Tournament) error {
+	pairings := t.NextPairings()
+	if pairings == nil {
+		t.Status = model.Completed
+		t.FinishedAt = time.Now()
+		log.WithContext(ctx).Debugf("Tournament %d completed, the winner is %s", t.Id, t.Winner().Email)
+		return s.repo.Update(ctx, *t)
+	}
+
+	t.CurrentRound++
+	if err := s.repo.Update(ctx, *t); err != nil {
+		return err
+	}
+	for _, p := range pairings {
+		if err := s.addPairing(ctx, t, p); err != nil {
+			return err
+		}
+	}
+	log.WithContext(ctx).Debugf("Tournament %d: started round %d of %d", t.Id, t.CurrentRound, t.Rounds)
+
+	// A round with only byes is over right away (which doesn't happen with two or more players, but better safe).
+	if t.RoundFinished() {
+		return s.nextRound(ctx, t)
+	}
+	return nil
+}
+
+// addPairing starts the game for the pairing (unless it's a bye) and stores it.
+func (s TournamentService) addPairing(ctx context.Context, t *model.Tournament, p model.Pairing) error {
+	if p.Result != model.Bye {
+		game, err := s.gamesService.StartGame(ctx, p.Player1, p.Player2)
+		if err != nil {
+			return err
+		}
+		p.GameKey = game.Key
+	}
+	p, err := s.repo.SavePairing(ctx, t.Id, p)
+	if err != nil {
+		return err
+	}
+	t.Pairings = append(t.Pairings, p)
+	return nil
+}
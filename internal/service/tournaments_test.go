@@ -0,0 +1,88 @@
+package service
+
+import (
+	"connectfour/internal/db"
+	"connectfour/internal/model"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+)
+
+func mockedTournamentService() (*TournamentService, *db.MockTournamentRepository, *db.MockGameRepository) {
+	gs, _, gr := mockedGamesService()
+	tr := db.NewMockTournamentRepository()
+	return NewTournamentService(tr, gs.userService, gs), tr, gr
+}
+
+func registeredTournament(format model.TournamentFormat) model.Tournament {
+	t := model.NewTournament(user1, "Test cup", format)
+	t.Id = 1
+	_ = t.Register(user1)
+	_ = t.Register(user2)
+	return t
+}
+
+func TestTournamentService_Start_CreatesGamesForFirstRound(t *testing.T) {
+	// Arrange
+	s, tr, gr := mockedTournamentService()
+	tr.On("Fetch", mock.Anything, int64(1)).Return(registeredTournament(model.RoundRobin), nil)
+	tr.On("Update", mock.Anything, mock.AnythingOfType("model.Tournament")).Return(nil)
+	tr.On("SavePairing", mock.Anything, int64(1), mock.AnythingOfType("model.Pairing")).
+		Return(func(_ int64, p model.Pairing) (model.Pairing, error) { return p, nil })
+	gr.On("Save", mock.Anything, mock.AnythingOfType("model.Game")).Return(true)
+
+	// Act
+	resp, err := s.Start(context.Background(), 1, user1.Email, 0)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, model.Running, resp.Status)
+	assert.Equal(t, 1, resp.CurrentRound)
+	assert.Len(t, resp.Pairings, 1)
+	assert.NotEmpty(t, resp.Pairings[0].GameKey, "Expected a game to be created for the pairing")
+	gr.AssertNumberOfCalls(t, "Save", 1)
+}
+
+func TestTournamentService_Start_OnlyByOrganizer(t *testing.T) {
+	// Arrange
+	s, tr, _ := mockedTournamentService()
+	tr.On("Fetch", mock.Anything, int64(1)).Return(registeredTournament(model.RoundRobin), nil)
+
+	// Act
+	_, err := s.Start(context.Background(), 1, user2.Email, 0)
+
+	// Assert
+	assert.Error(t, err, "Expected only the organizer to be able to start the tournament")
+}
+
+func TestTournamentService_GameFinished_CompletesTournament(t *testing.T) {
+	// Arrange
+	tournament := registeredTournament(model.Knockout)
+	_ = tournament.Start(0)
+	game := model.NewGame(user1, false)
+	_ = game.Join(user2)
+	for _, col := range []int{1, 2, 1, 2, 1, 2, 1} {
+		_ = game.Play(*game.CurrentPlayer(), col)
+	}
+	tournament.CurrentRound = 1
+	tournament.Pairings = []model.Pairing{{Id: 1, Round: 1, GameKey: game.Key, Player1: user1, Player2: user2}}
+
+	s, tr, _ := mockedTournamentService()
+	tr.On("FetchByGameKey", mock.Anything, game.Key).Return(tournament, nil)
+	tr.On("SavePairing", mock.Anything, int64(1), mock.AnythingOfType("model.Pairing")).
+		Return(func(_ int64, p model.Pairing) (model.Pairing, error) { return p, nil })
+	tr.On("Update", mock.Anything, mock.AnythingOfType("model.Tournament")).Return(nil)
+
+	// Act
+	err := s.GameFinished(context.Background(), game)
+
+	// Assert
+	assert.NoError(t, err)
+	tr.AssertCalled(t, "SavePairing", mock.Anything, int64(1), mock.MatchedBy(func(p model.Pairing) bool {
+		return p.Result == model.Player1Wins
+	}))
+	tr.AssertCalled(t, "Update", mock.Anything, mock.MatchedBy(func(t model.Tournament) bool {
+		return t.Status == model.Completed
+	}))
+}
@@ -0,0 +1,51 @@
+package service
+
+import "sync"
+
+// turnNotifier lets goroutines wait for a change in a game's state. It only knows about changes that were made
+// through this api instance, so waiters should still re-check the game every now and then.
+type turnNotifier struct {
+	mu      sync.Mutex
+	waiters map[string][]chan struct{}
+}
+
+func newTurnNotifier() *turnNotifier {
+	return &turnNotifier{
+		waiters: make(map[string][]chan struct{}),
+	}
+}
+
+// wait returns a channel that is closed on the next change of the game with the specified key.
+func (n *turnNotifier) wait(key string) <-chan struct{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	ch := make(chan struct{})
+	n.waiters[key] = append(n.waiters[key], ch)
+	return ch
+}
+
+// cancel removes a channel that was returned by wait, for when the waiter gave up.
+func (n *turnNotifier) cancel(key string, ch <-chan struct{}) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	waiters := n.waiters[key]
+	for i, w := range waiters {
+		if w == ch {
+			n.waiters[key] = append(waiters[:i], waiters[i+1:]...)
+			break
+		}
+	}
+	if len(n.waiters[key]) == 0 {
+		delete(n.waiters, key)
+	}
+}
+
+// notify wakes up everybody that is waiting for the game with the specified key.
+func (n *turnNotifier) notify(key string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, ch := range n.waiters[key] {
+		close(ch)
+	}
+	delete(n.waiters, key)
+}
@@ -3,17 +3,30 @@ package service
 import (
 	"connectfour/internal/db"
 	"connectfour/internal/model"
+	"connectfour/internal/tracing"
+	"context"
 	"errors"
 	"fmt"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 	"net/mail"
 	"strings"
+	"sync"
 	"time"
 )
 
 type UserService struct {
-	repo      db.UserRepository
-	userCache *Cache[string, *model.User]
+	repo        db.UserRepository
+	userCache   *Cache[string, *model.User]
+	presence    *PresenceTracker
+	invalidated *invalidationHooks
+}
+
+// invalidationHooks are called with the e-mail address of a user whose profile changed, so that the caches that keep
+// (parts of) the user can drop them.
+type invalidationHooks struct {
+	mu    sync.RWMutex
+	hooks []func(email string)
 }
 
 type UserExistsError struct {
@@ -28,26 +41,74 @@ func NewUserExistsError(email string) UserExistsError {
 	return UserExistsError{email: email}
 }
 
+// NameTakenError is returned when another user already uses the display name.
+type NameTakenError struct {
+	name string
+}
+
+func (e NameTakenError) Error() string {
+	return fmt.Sprintf("the name %s is already taken", e.name)
+}
+
+const (
+	minNameLength = 2
+	maxNameLength = 40
+
+	// userCacheSize is the most users that are cached, which is plenty for the players that are online at once.
+	userCacheSize = 10_000
+)
+
 func NewUserService(repo db.UserRepository, cacheTtl time.Duration) *UserService {
 	return &UserService{
-		repo:      repo,
-		userCache: NewCache[string, *model.User](cacheTtl),
+		repo:        repo,
+		userCache:   NewCache[string, *model.User]("users", userCacheSize, cacheTtl),
+		presence:    NewPresenceTracker(),
+		invalidated: &invalidationHooks{},
 	}
 }
 
+// Presence returns the tracker that knows which users are online.
+func (s UserService) Presence() *PresenceTracker {
+	return s.presence
+}
+
 func (s UserService) Cache(user *model.User) {
 	s.userCache.Store(strings.ToLower(user.Email), user)
 }
 
-func (s UserService) FindUserByEmail(email string) (model.User, error) {
+// Invalidate drops the cached user, after their profile changed, and calls the hooks that were registered with
+// OnInvalidate.
+func (s UserService) Invalidate(email string) {
+	email = strings.ToLower(email)
+	s.userCache.Delete(email)
+
+	s.invalidated.mu.RLock()
+	defer s.invalidated.mu.RUnlock()
+	for _, hook := range s.invalidated.hooks {
+		hook(email)
+	}
+}
+
+// OnInvalidate registers a hook that is called with the (lower case) e-mail address of every user whose profile
+// changed, for the caches that keep users outside of the UserService.
+func (s UserService) OnInvalidate(hook func(email string)) {
+	s.invalidated.mu.Lock()
+	defer s.invalidated.mu.Unlock()
+	s.invalidated.hooks = append(s.invalidated.hooks, hook)
+}
+
+func (s UserService) FindUserByEmail(ctx context.Context, email string) (model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.FindUserByEmail")
+	defer span.End()
 
 	// Normalize
 	email = strings.ToLower(email)
 
 	// first look in the cache
 	user, ok := s.userCache.Load(email)
+	span.SetAttributes(attribute.Bool("cache.hit", ok))
 	if !ok {
-		user, err := s.repo.FindByEmail(email)
+		user, err := s.repo.FindByEmail(ctx, email)
 		if err == nil {
 			s.userCache.Store(email, &user)
 		}
@@ -56,17 +117,21 @@ func (s UserService) FindUserByEmail(email string) (model.User, error) {
 	return *user, nil
 }
 
-func (s UserService) CreateUser(email string, name string, token string) (model.User, error) {
+// CreateUser creates a player. Bot accounts can't be registered; an admin makes a player a bot with AdminService.SetBot.
+func (s UserService) CreateUser(ctx context.Context, email string, name string, token string) (model.User, error) {
 
-	log.Debugf("Creating user %s (%s)...", name, email)
+	log.WithContext(ctx).Debugf("Creating user %s (%s)...", name, email)
 	email = strings.ToLower(email)
 	if !validateEmail(email) {
 		return model.User{}, errors.New("invalid email address")
 	}
+	if strings.EqualFold(strings.TrimSpace(name), model.DeletedUserName) {
+		return model.User{}, NameTakenError{name: name}
+	}
 
-	user, err := s.FindUserByEmail(email)
+	user, err := s.FindUserByEmail(ctx, email)
 	if err != nil {
-		log.Errorf("Could not determine if user %s already exists: %v", email, err)
+		log.WithContext(ctx).Errorf("Could not determine if user %s already exists: %v", email, err)
 		return model.User{}, fmt.Errorf("could not determine if the user already exists")
 	}
 	if !user.Empty() {
@@ -78,12 +143,196 @@ func (s UserService) CreateUser(email string, name string, token string) (model.
 		Email: strings.ToLower(email),
 		Token: token,
 	}
-	user, err = s.repo.Create(user)
+	user, err = s.repo.Create(ctx, user)
+	if errors.Is(err, db.ErrNameTaken) {
+		return model.User{}, NameTakenError{name: name}
+	}
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error creating user %s (%s): %v", name, email, err)
+		return model.User{}, err
+	}
+	log.WithContext(ctx).Debugf("Succeeded creating user %s (%s)...", name, email)
+	return user, nil
+}
+
+// Rename changes the display name of the user. Names are unique, ignoring the case, which the database enforces too
+// for the renames and registrations that happen at the same time.
+func (s UserService) Rename(ctx context.Context, email string, name string) (model.User, error) {
+	user, err := s.existingUser(ctx, email)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	name = strings.TrimSpace(name)
+	if l := len([]rune(name)); l < minNameLength || l > maxNameLength {
+		return model.User{}, fmt.Errorf("a name must have %d to %d characters", minNameLength, maxNameLength)
+	}
+	if strings.EqualFold(name, model.DeletedUserName) {
+		return model.User{}, NameTakenError{name: name}
+	}
+	other, err := s.repo.FindByName(ctx, name)
+	if err != nil {
+		return model.User{}, err
+	}
+	if !other.Empty() && !other.Is(user) {
+		return model.User{}, NameTakenError{name: name}
+	}
+
+	user.Name = name
+	err = s.repo.Update(ctx, user)
+	if errors.Is(err, db.ErrNameTaken) {
+		return model.User{}, NameTakenError{name: name}
+	}
+	if err != nil {
+		return model.User{}, err
+	}
+	s.Invalidate(email)
+	log.WithContext(ctx).Debugf("User %s is now called %s", email, name)
+	return user, nil
+}
+
+// ChangePassword stores the new password hash of the user. The old password must be verified by the caller.
+func (s UserService) ChangePassword(ctx context.Context, email string, token string) error {
+	user, err := s.existingUser(ctx, email)
+	if err != nil {
+		return err
+	}
+	user.Token = token
+	if err = s.repo.Update(ctx, user); err != nil {
+		return err
+	}
+	s.Invalidate(email)
+	return nil
+}
+
+// RevokeTokens refuses all the tokens that were issued to the user so far. The user needs a new token to continue.
+func (s UserService) RevokeTokens(ctx context.Context, email string) (model.User, error) {
+	user, err := s.existingUser(ctx, email)
+	if err != nil {
+		return model.User{}, err
+	}
+	if err = s.repo.RevokeTokens(ctx, user.Id); err != nil {
+		return model.User{}, err
+	}
+	s.Invalidate(email)
+	user.TokenVersion++
+	log.WithContext(ctx).Debugf("The tokens of user %s were revoked", email)
+	return user, nil
+}
+
+// DeleteUser deletes the account of the user. Their games are kept, but anonymized.
+func (s UserService) DeleteUser(ctx context.Context, email string) error {
+	user, err := s.existingUser(ctx, email)
+	if err != nil {
+		return err
+	}
+	if err = s.repo.Delete(ctx, user.Id); err != nil {
+		return err
+	}
+	s.Invalidate(email)
+	log.WithContext(ctx).Debugf("User %d (%s) deleted their account", user.Id, email)
+	return nil
+}
+
+// Friends returns the friends (and open friend requests) of the user. The presence is only shared between
+// accepted friends.
+func (s UserService) Friends(ctx context.Context, email string) ([]FriendResponse, error) {
+	user, err := s.existingUser(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	friends, err := s.repo.ListFriends(ctx, user.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	output := make([]FriendResponse, 0, len(friends))
+	for _, f := range friends {
+		resp := NewFriendResponse(f)
+		if f.Status == model.FriendAccepted {
+			resp.Presence = s.presence.Presence(f.User.Email)
+		}
+		output = append(output, resp)
+	}
+	return output, nil
+}
+
+// RequestFriend sends a friend request. When the other user already sent a request, it is accepted instead.
+func (s UserService) RequestFriend(ctx context.Context, email string, friendEmail string) error {
+	user, err := s.existingUser(ctx, email)
+	if err != nil {
+		return err
+	}
+	friend, err := s.existingUser(ctx, friendEmail)
+	if err != nil {
+		return err
+	}
+	if user.Is(friend) {
+		return errors.New("you can't befriend yourself")
+	}
+
+	existing, err := s.findFriend(ctx, user, friend)
 	if err != nil {
-		log.Errorf("Error creating user %s (%s): %v", name, email, err)
+		return err
+	}
+	if existing != nil {
+		if existing.Status == model.FriendRequested && existing.Incoming {
+			return s.repo.AcceptFriend(ctx, user.Id, friend.Id)
+		}
+		return nil
+	}
+	return s.repo.AddFriend(ctx, user.Id, friend.Id)
+}
+
+// AcceptFriend accepts the friend request that was sent by friendEmail.
+func (s UserService) AcceptFriend(ctx context.Context, email string, friendEmail string) error {
+	user, err := s.existingUser(ctx, email)
+	if err != nil {
+		return err
+	}
+	friend, err := s.existingUser(ctx, friendEmail)
+	if err != nil {
+		return err
+	}
+	return s.repo.AcceptFriend(ctx, user.Id, friend.Id)
+}
+
+// RemoveFriend ends the friendship, or withdraws or rejects a friend request.
+func (s UserService) RemoveFriend(ctx context.Context, email string, friendEmail string) error {
+	user, err := s.existingUser(ctx, email)
+	if err != nil {
+		return err
+	}
+	friend, err := s.existingUser(ctx, friendEmail)
+	if err != nil {
+		return err
+	}
+	return s.repo.RemoveFriend(ctx, user.Id, friend.Id)
+}
+
+func (s UserService) findFriend(ctx context.Context, user model.User, friend model.User) (*model.Friend, error) {
+	friends, err := s.repo.ListFriends(ctx, user.Id)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range friends {
+		if f.User.Is(friend) {
+			return &f, nil
+		}
+	}
+	return nil, nil
+}
+
+// existingUser returns the user with the email, or an error when there's no such user.
+func (s UserService) existingUser(ctx context.Context, email string) (model.User, error) {
+	user, err := s.FindUserByEmail(ctx, email)
+	if err != nil {
+		log.WithContext(ctx).Errorf("Error fetching user: %v", err)
 		return model.User{}, err
 	}
-	log.Debugf("Succeeded creating user %s (%s)...", name, email)
+	if user.Empty() {
+		return model.User{}, fmt.Errorf("there is no player with e-mail address %s", email)
+	}
 	return user, nil
 }
 
@@ -3,6 +3,7 @@ package service
 import (
 	"connectfour/internal/db"
 	"connectfour/internal/model"
+	"context"
 	"errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -26,13 +27,13 @@ func TestUserService_CreateUser(t *testing.T) {
 	expected.Id = 0
 
 	repo := db.NewMockUserRepository()
-	repo.On("FindByEmail", user1.Email).Return(model.User{}, nil)
-	repo.On("Create", expected).Return(expected, nil)
+	repo.On("FindByEmail", mock.Anything, user1.Email).Return(model.User{}, nil)
+	repo.On("Create", mock.Anything, expected).Return(expected, nil)
 	s := NewUserService(repo, time.Minute*5)
 
 	// Act
-	u1, err1 := s.CreateUser(expected.Email, expected.Name, expected.Token)
-	u2, err2 := s.CreateUser("not.an.email", expected.Name, expected.Token)
+	u1, err1 := s.CreateUser(context.Background(), expected.Email, expected.Name, expected.Token)
+	u2, err2 := s.CreateUser(context.Background(), "not.an.email", expected.Name, expected.Token)
 
 	// Assert
 	assert.EqualValues(t, expected, u1, "Expected the generated user to match the input values.")
@@ -46,14 +47,14 @@ func TestUserService_FindUserByEmail(t *testing.T) {
 
 	// Arrange
 	repo := db.NewMockUserRepository()
-	repo.On("FindByEmail", "dick@evilnerd.nl").Return(user1, nil)
-	repo.On("FindByEmail", mock.Anything).Return(model.User{}, errors.New("user not found"))
+	repo.On("FindByEmail", mock.Anything, "dick@evilnerd.nl").Return(user1, nil)
+	repo.On("FindByEmail", mock.Anything, mock.Anything).Return(model.User{}, errors.New("user not found"))
 
 	s := NewUserService(repo, time.Minute*5)
 
 	// Act
-	u1, err1 := s.FindUserByEmail(user1.Email)
-	u2, err2 := s.FindUserByEmail("other@evilnerd.nl")
+	u1, err1 := s.FindUserByEmail(context.Background(), user1.Email)
+	u2, err2 := s.FindUserByEmail(context.Background(), "other@evilnerd.nl")
 
 	// Assert
 	assert.EqualValues(t, u1, user1, "Expected the returned user to have the same values as the mock database user")
@@ -66,15 +67,170 @@ func TestUserService_FindUserByEmail(t *testing.T) {
 func TestUserService_Cache(t *testing.T) {
 	// Arrange
 	repo := db.NewMockUserRepository()
-	repo.On("Create", user1).Panic("this should not be called")
+	repo.On("Create", mock.Anything, user1).Panic("this should not be called")
 	s := NewUserService(repo, time.Minute*5)
 
 	// Act
 	s.Cache(&user1)
-	u1, err1 := s.FindUserByEmail(user1.Email)
+	u1, err1 := s.FindUserByEmail(context.Background(), user1.Email)
 
 	// Assert
 	assert.NoError(t, err1, "Expected the error to be nil")
 	assert.EqualValues(t, user1, u1, "Expected the returned user to match the input values")
-	repo.AssertNotCalled(t, "FindByEmail")
+	repo.AssertNotCalled(t, "FindByEmail", mock.Anything, mock.Anything)
+}
+
+func TestUserService_RequestFriend_AcceptsIncomingRequest(t *testing.T) {
+	// Arrange
+	repo := db.NewMockUserRepository()
+	repo.On("FindByEmail", mock.Anything, user1.Email).Return(user1, nil)
+	repo.On("FindByEmail", mock.Anything, user2.Email).Return(user2, nil)
+	repo.On("ListFriends", mock.Anything, user1.Id).Return([]model.Friend{{User: user2, Status: model.FriendRequested, Incoming: true}}, nil)
+	repo.On("AcceptFriend", mock.Anything, user1.Id, user2.Id).Return(nil)
+	s := NewUserService(repo, time.Minute*5)
+
+	// Act
+	err := s.RequestFriend(context.Background(), user1.Email, user2.Email)
+
+	// Assert
+	assert.NoError(t, err)
+	repo.AssertCalled(t, "AcceptFriend", mock.Anything, user1.Id, user2.Id)
+	repo.AssertNotCalled(t, "AddFriend", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserService_RequestFriend_NotYourself(t *testing.T) {
+	// Arrange
+	repo := db.NewMockUserRepository()
+	repo.On("FindByEmail", mock.Anything, user1.Email).Return(user1, nil)
+	s := NewUserService(repo, time.Minute*5)
+
+	// Act
+	err := s.RequestFriend(context.Background(), user1.Email, user1.Email)
+
+	// Assert
+	assert.Error(t, err, "Expected an error when befriending yourself")
+}
+
+func TestUserService_Friends_PresenceOnlyForAcceptedFriends(t *testing.T) {
+	// Arrange
+	user3 := model.User{Id: 3, Name: "Lucy", Email: "lucy@evilnerd.nl"}
+	repo := db.NewMockUserRepository()
+	repo.On("FindByEmail", mock.Anything, user1.Email).Return(user1, nil)
+	repo.On("ListFriends", mock.Anything, user1.Id).Return([]model.Friend{
+		{User: user2, Status: model.FriendAccepted},
+		{User: user3, Status: model.FriendRequested},
+	}, nil)
+	s := NewUserService(repo, time.Minute*5)
+	s.Presence().Touch(user2.Email)
+	s.Presence().Touch(user3.Email)
+
+	// Act
+	friends, err := s.Friends(context.Background(), user1.Email)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, friends, 2)
+	assert.Equal(t, model.Online, friends[0].Presence)
+	assert.Empty(t, friends[1].Presence, "Expected the presence to be hidden for a pending friend request")
+}
+
+func TestUserService_Rename(t *testing.T) {
+	// Arrange
+	repo := db.NewMockUserRepository()
+	repo.On("FindByEmail", mock.Anything, user1.Email).Return(user1, nil)
+	repo.On("FindByName", mock.Anything, "Richard").Return(model.User{}, nil)
+	repo.On("FindByName", mock.Anything, "sanae").Return(user2, nil)
+	repo.On("Update", mock.Anything, mock.AnythingOfType("model.User")).Return(nil)
+	s := NewUserService(repo, time.Minute*5)
+
+	// Act
+	renamed, err1 := s.Rename(context.Background(), user1.Email, " Richard ")
+	_, err2 := s.Rename(context.Background(), user1.Email, "sanae")
+	_, err3 := s.Rename(context.Background(), user1.Email, "x")
+
+	// Assert
+	assert.NoError(t, err1)
+	assert.Equal(t, "Richard", renamed.Name, "Expected the name to be trimmed")
+	repo.AssertCalled(t, "Update", mock.Anything, mock.MatchedBy(func(u model.User) bool { return u.Name == "Richard" }))
+
+	assert.ErrorAs(t, err2, &NameTakenError{}, "Expected the name of another user to be taken")
+	assert.Error(t, err3, "Expected a name that's too short to be refused")
+}
+
+func TestUserService_NameTakenByDatabase(t *testing.T) {
+	// Arrange
+	repo := db.NewMockUserRepository()
+	repo.On("FindByEmail", mock.Anything, user1.Email).Return(user1, nil)
+	repo.On("FindByEmail", mock.Anything, "new@evilnerd.nl").Return(model.User{}, nil)
+	// another user took the name between the check and the write.
+	repo.On("FindByName", mock.Anything, "Sanae").Return(model.User{}, nil)
+	repo.On("Update", mock.Anything, mock.AnythingOfType("model.User")).Return(db.ErrNameTaken)
+	repo.On("Create", mock.Anything, mock.AnythingOfType("model.User")).Return(model.User{}, db.ErrNameTaken)
+	s := NewUserService(repo, time.Minute*5)
+
+	// Act
+	_, errRename := s.Rename(context.Background(), user1.Email, "Sanae")
+	_, errCreate := s.CreateUser(context.Background(), "new@evilnerd.nl", "Sanae", "hash")
+	_, errDeleted := s.CreateUser(context.Background(), "new@evilnerd.nl", model.DeletedUserName, "hash")
+
+	// Assert
+	assert.ErrorAs(t, errRename, &NameTakenError{})
+	assert.ErrorAs(t, errCreate, &NameTakenError{})
+	assert.ErrorAs(t, errDeleted, &NameTakenError{}, "Expected the name of deleted users to be refused")
+}
+
+func TestUserService_DeleteUser(t *testing.T) {
+	// Arrange
+	repo := db.NewMockUserRepository()
+	repo.On("FindByEmail", mock.Anything, user1.Email).Return(user1, nil).Once()
+	repo.On("FindByEmail", mock.Anything, user1.Email).Return(model.User{}, nil)
+	repo.On("Delete", mock.Anything, user1.Id).Return(nil)
+	s := NewUserService(repo, time.Minute*5)
+
+	// Act
+	err := s.DeleteUser(context.Background(), user1.Email)
+	deleted, _ := s.FindUserByEmail(context.Background(), user1.Email)
+
+	// Assert
+	assert.NoError(t, err)
+	repo.AssertCalled(t, "Delete", mock.Anything, user1.Id)
+	assert.True(t, deleted.Empty(), "Expected the deleted user to be removed from the cache")
+}
+
+func TestUserService_RevokeTokens(t *testing.T) {
+	// Arrange
+	repo := db.NewMockUserRepository()
+	repo.On("FindByEmail", mock.Anything, user1.Email).Return(user1, nil)
+	repo.On("RevokeTokens", mock.Anything, user1.Id).Return(nil)
+	s := NewUserService(repo, time.Minute*5)
+
+	// Act
+	revoked, err := s.RevokeTokens(context.Background(), user1.Email)
+
+	// Assert
+	assert.NoError(t, err)
+	repo.AssertCalled(t, "RevokeTokens", mock.Anything, user1.Id)
+	assert.Equal(t, user1.TokenVersion+1, revoked.TokenVersion, "Expected the new tokens to get the next version")
+}
+
+func TestUserService_Invalidate(t *testing.T) {
+	// Arrange
+	repo := db.NewMockUserRepository()
+	repo.On("FindByEmail", mock.Anything, user1.Email).Return(user1, nil)
+	repo.On("FindByName", mock.Anything, "Richard").Return(model.User{}, nil)
+	repo.On("Update", mock.Anything, mock.AnythingOfType("model.User")).Return(nil)
+	s := NewUserService(repo, time.Minute*5)
+	var invalidated []string
+	s.OnInvalidate(func(email string) { invalidated = append(invalidated, email) })
+	_, _ = s.FindUserByEmail(context.Background(), user1.Email)
+
+	// Act
+	_, err := s.Rename(context.Background(), "Dick@EvilNerd.nl", "Richard")
+	_, _ = s.FindUserByEmail(context.Background(), user1.Email)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []string{user1.Email}, invalidated, "Expected the hook to be called with the lower case address")
+	// the rename finds the user in the cache, so only the lookups before and after it reach the repository.
+	repo.AssertNumberOfCalls(t, "FindByEmail", 2)
 }
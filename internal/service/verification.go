@@ -0,0 +1,161 @@
+package service
+
+import (
+	"connectfour/internal/mail"
+	"connectfour/internal/model"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	verificationCodeLifetime = 24 * time.Hour
+	minResendInterval        = time.Minute
+	maxResendsPerHour        = 5
+)
+
+// InvalidVerificationCodeError is returned when a verification code doesn't match the e-mail address, or has expired.
+type InvalidVerificationCodeError struct{}
+
+func (e InvalidVerificationCodeError) Error() string {
+	return "the verification code is invalid or has expired"
+}
+
+// TooManyRequestsError is returned when a request may only be repeated after some time.
+type TooManyRequestsError struct {
+	RetryAfter time.Duration
+}
+
+func (e TooManyRequestsError) Error() string {
+	return fmt.Sprintf("too many requests, try again in %d seconds", int(e.RetryAfter.Seconds()))
+}
+
+// VerificationService proves that users own their e-mail address, by mailing them a code that is signed with a
+// secret. The code contains its expiry time, so nothing needs to be stored until the address is verified.
+type VerificationService struct {
+	userService *UserService
+	mailer      mail.Mailer
+	secret      []byte
+	now         func() time.Time
+	mu          *sync.Mutex
+	sent        map[string][]time.Time // email -> the times a code was resent within the last hour.
+}
+
+func NewVerificationService(userService *UserService, mailer mail.Mailer, secret []byte) *VerificationService {
+	return &VerificationService{
+		userService: userService,
+		mailer:      mailer,
+		secret:      secret,
+		now:         time.Now,
+		mu:          &sync.Mutex{},
+		sent:        make(map[string][]time.Time),
+	}
+}
+
+// Send mails a verification code to the user, e.g. right after registration.
+func (s VerificationService) Send(user model.User) error {
+	code := s.code(user.Email, s.now().Add(verificationCodeLifetime))
+	return s.mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Verify your ConnectFour e-mail address",
+		Body: fmt.Sprintf(`Hi %s,
+
+Welcome to ConnectFour! Use this code to verify your e-mail address within %d hours:
+
+    %s
+
+Until then, you can't create public games. If you didn't register, you can ignore this e-mail.
+`, user.Name, int(verificationCodeLifetime.Hours()), code),
+	})
+}
+
+// Resend mails a new verification code. It's rate limited per user, so it can't be used to flood someone's inbox.
+func (s VerificationService) Resend(ctx context.Context, email string) error {
+	user, err := s.userService.existingUser(ctx, email)
+	if err != nil {
+		return err
+	}
+	if user.Verified {
+		return errors.New("your e-mail address is already verified")
+	}
+	if err = s.allowResend(user.Email); err != nil {
+		return err
+	}
+	return s.Send(user)
+}
+
+// allowResend registers the resend, or returns a TooManyRequestsError when the limit is reached.
+func (s VerificationService) allowResend(email string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	recent := make([]time.Time, 0, maxResendsPerHour)
+	for _, t := range s.sent[email] {
+		if now.Sub(t) < time.Hour {
+			recent = append(recent, t)
+		}
+	}
+	if n := len(recent); n > 0 && now.Sub(recent[n-1]) < minResendInterval {
+		s.sent[email] = recent
+		return TooManyRequestsError{RetryAfter: minResendInterval - now.Sub(recent[n-1])}
+	}
+	if len(recent) >= maxResendsPerHour {
+		s.sent[email] = recent
+		return TooManyRequestsError{RetryAfter: time.Hour - now.Sub(recent[0])}
+	}
+	s.sent[email] = append(recent, now)
+	return nil
+}
+
+// Verify checks the code and marks the e-mail address of the user as verified.
+func (s VerificationService) Verify(ctx context.Context, email string, code string) error {
+	user, err := s.userService.existingUser(ctx, email)
+	if err != nil {
+		return err
+	}
+	if user.Verified {
+		return nil
+	}
+	if !s.valid(user.Email, strings.TrimSpace(code)) {
+		return InvalidVerificationCodeError{}
+	}
+	if err = s.userService.repo.SetVerified(ctx, user.Id); err != nil {
+		return err
+	}
+	s.userService.Invalidate(user.Email)
+	log.WithContext(ctx).Debugf("User %s verified their e-mail address", user.Email)
+	return nil
+}
+
+// code returns "<expiry>.<signature>", where the expiry is a base 36 unix timestamp.
+func (s VerificationService) code(email string, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 36)
+	return exp + "." + s.sign(email, exp)
+}
+
+func (s VerificationService) valid(email string, code string) bool {
+	exp, sig, found := strings.Cut(code, ".")
+	if !found {
+		return false
+	}
+	unix, err := strconv.ParseInt(exp, 36, 64)
+	if err != nil || s.now().After(time.Unix(unix, 0)) {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(s.sign(email, exp)))
+}
+
+func (s VerificationService) sign(email string, exp string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(strings.ToLower(email) + "|" + exp))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
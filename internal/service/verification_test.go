@@ -0,0 +1,85 @@
+package service
+
+import (
+	"connectfour/internal/db"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"strings"
+	"testing"
+	"time"
+)
+
+func mockedVerificationService() (*VerificationService, *db.MockUserRepository, *recordingMailer) {
+	repo := db.NewMockUserRepository()
+	mailer := &recordingMailer{}
+	return NewVerificationService(NewUserService(repo, 0), mailer, []byte("test secret")), repo, mailer
+}
+
+func TestVerificationService_Verify(t *testing.T) {
+	// Arrange
+	s, repo, _ := mockedVerificationService()
+	repo.On("FindByEmail", mock.Anything, user1.Email).Return(user1, nil)
+	repo.On("SetVerified", mock.Anything, user1.Id).Return(nil)
+	valid := s.code(user1.Email, time.Now().Add(time.Hour))
+	expired := s.code(user1.Email, time.Now().Add(-time.Minute))
+	otherUser := s.code(user2.Email, time.Now().Add(time.Hour))
+
+	// Act
+	errExpired := s.Verify(context.Background(), user1.Email, expired)
+	errOther := s.Verify(context.Background(), user1.Email, otherUser)
+	errTampered := s.Verify(context.Background(), user1.Email, strings.Replace(valid, ".", "0.", 1))
+	errValid := s.Verify(context.Background(), user1.Email, valid)
+
+	// Assert
+	assert.ErrorAs(t, errExpired, &InvalidVerificationCodeError{})
+	assert.ErrorAs(t, errOther, &InvalidVerificationCodeError{}, "Expected the code of another user to be refused")
+	assert.ErrorAs(t, errTampered, &InvalidVerificationCodeError{}, "Expected a changed expiry to be refused")
+	assert.NoError(t, errValid)
+	repo.AssertNumberOfCalls(t, "SetVerified", 1)
+}
+
+func TestVerificationService_Send_MailsValidCode(t *testing.T) {
+	// Arrange
+	s, _, mailer := mockedVerificationService()
+
+	// Act
+	err := s.Send(user1)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, mailer.sent, 1)
+	found := false
+	for _, line := range strings.Split(mailer.sent[0].Body, "\n") {
+		if s.valid(user1.Email, strings.TrimSpace(line)) {
+			found = true
+		}
+	}
+	assert.True(t, found, "Expected the mail to contain a valid code")
+}
+
+func TestVerificationService_Resend_IsRateLimited(t *testing.T) {
+	// Arrange
+	s, repo, mailer := mockedVerificationService()
+	repo.On("FindByEmail", mock.Anything, user1.Email).Return(user1, nil)
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	// Act
+	errFirst := s.Resend(context.Background(), user1.Email)
+	errTooSoon := s.Resend(context.Background(), user1.Email)
+	for i := 0; i < maxResendsPerHour; i++ {
+		now = now.Add(minResendInterval)
+		_ = s.Resend(context.Background(), user1.Email)
+	}
+	now = now.Add(minResendInterval)
+	errTooMany := s.Resend(context.Background(), user1.Email)
+
+	// Assert
+	assert.NoError(t, errFirst)
+	var tooSoon TooManyRequestsError
+	assert.ErrorAs(t, errTooSoon, &tooSoon)
+	assert.Equal(t, minResendInterval, tooSoon.RetryAfter)
+	assert.ErrorAs(t, errTooMany, &TooManyRequestsError{})
+	assert.Len(t, mailer.sent, maxResendsPerHour)
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"bytes"
+	"connectfour/internal/db"
+	"connectfour/internal/metrics"
+	"connectfour/internal/model"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// webhookWorkers is the number of deliveries that are posted at once.
+	webhookWorkers = 4
+	// webhookQueueSize is the most deliveries that wait for a worker. When the queue is full, deliveries are dropped.
+	webhookQueueSize = 1_000
+	// maxWebhookAttempts is how often a delivery is tried before it's given up.
+	maxWebhookAttempts = 6
+	// WebhookSignatureTolerance is how old the timestamp of a signed delivery may be when the receiver gets it. Older
+	// deliveries should be refused, since they may have been captured and posted again.
+	WebhookSignatureTolerance = 5 * time.Minute
+)
+
+// WebhookQueue posts the deliveries to the webhooks in the background. A delivery that fails because the webhook
+// couldn't be reached, or responded with 429 or a 5xx status, is tried again after a backoff that doubles with every
+// attempt. Every attempt is recorded in the delivery log.
+type WebhookQueue struct {
+	repo        db.WebhookRepository
+	client      *http.Client
+	backoff     time.Duration // the wait before the second attempt.
+	maxAttempts int
+	now         func() time.Time
+
+	jobs    chan webhookJob
+	stop    chan struct{}
+	closed  sync.Once
+	workers sync.WaitGroup
+}
+
+type webhookJob struct {
+	webhook  model.Webhook
+	delivery model.WebhookDelivery
+}
+
+func NewWebhookQueue(repo db.WebhookRepository, client *http.Client, backoff time.Duration) *WebhookQueue {
+	q := &WebhookQueue{
+		repo:        repo,
+		client:      client,
+		backoff:     backoff,
+		maxAttempts: maxWebhookAttempts,
+		now:         time.Now,
+		jobs:        make(chan webhookJob, webhookQueueSize),
+		stop:        make(chan struct{}),
+	}
+	q.workers.Add(webhookWorkers)
+	for range webhookWorkers {
+		go q.work()
+	}
+	return q
+}
+
+// Deliver adds the payload to the delivery log of the webhook, and queues it.
+func (q *WebhookQueue) Deliver(ctx context.Context, webhook model.Webhook, event GameEventType, gameKey string, payload []byte, at time.Time) error {
+	d, err := q.repo.AddDelivery(ctx, model.WebhookDelivery{
+		WebhookId:     webhook.Id,
+		Event:         string(event),
+		GameKey:       gameKey,
+		Payload:       string(payload),
+		Status:        model.DeliveryPending,
+		CreatedAt:     at,
+		NextAttemptAt: at,
+	})
+	if err != nil {
+		return err
+	}
+	q.Enqueue(webhook, d)
+	return nil
+}
+
+// Enqueue queues the delivery to the webhook. The delivery must be in the log already.
+func (q *WebhookQueue) Enqueue(webhook model.Webhook, delivery model.WebhookDelivery) {
+	select {
+	case <-q.stop:
+		return
+	default:
+	}
+	select {
+	case q.jobs <- webhookJob{webhook: webhook, delivery: delivery}:
+	default:
+		log.Warnf("The webhook queue is full, dropping delivery %d to webhook %d", delivery.Id, webhook.Id)
+		metrics.WebhookDelivery("dropped")
+		delivery.Status = model.DeliveryFailed
+		delivery.Error = "the delivery queue was full"
+		delivery.NextAttemptAt = time.Time{}
+		_ = q.repo.UpdateDelivery(context.Background(), delivery)
+	}
+}
+
+// Resume queues the deliveries that were pending when the api stopped: the ones that are due right away, the others
+// when they are due.
+func (q *WebhookQueue) Resume(ctx context.Context) error {
+	pending, err := q.repo.ListPending(ctx)
+	if err != nil {
+		return err
+	}
+	for _, p := range pending {
+		q.schedule(p.Webhook, p.Delivery)
+	}
+	if len(pending) > 0 {
+		log.WithContext(ctx).Infof("Resumed %d pending webhook deliveries", len(pending))
+	}
+	return nil
+}
+
+// schedule queues the delivery at its next attempt, or right away when that's due.
+func (q *WebhookQueue) schedule(webhook model.Webhook, delivery model.WebhookDelivery) {
+	wait := delivery.NextAttemptAt.Sub(q.now())
+	if wait <= 0 {
+		q.Enqueue(webhook, delivery)
+		return
+	}
+	time.AfterFunc(wait, func() { q.Enqueue(webhook, delivery) })
+}
+
+// Close stops the workers and the retries, and waits for the attempts in flight to be recorded. Deliveries that are
+// still waiting stay pending in the log, and are resumed when the api starts again.
+func (q *WebhookQueue) Close() {
+	q.closed.Do(func() { close(q.stop) })
+	q.workers.Wait()
+}
+
+func (q *WebhookQueue) work() {
+	defer q.workers.Done()
+	for {
+		select {
+		case <-q.stop:
+			return
+		default:
+		}
+		select {
+		case job := <-q.jobs:
+			q.attempt(job)
+		case <-q.stop:
+			return
+		}
+	}
+}
+
+// attempt posts the delivery once, records the outcome and schedules the next attempt when it failed.
+func (q *WebhookQueue) attempt(job webhookJob) {
+	ctx := context.Background()
+	d := job.delivery
+	d.Attempts++
+	d.LastAttemptAt = q.now()
+	d.NextAttemptAt = time.Time{}
+
+	status, err := q.post(ctx, job.webhook, d)
+	d.ResponseStatus = status
+	result := "delivered"
+	var wait time.Duration
+	switch {
+	case err == nil:
+		d.Status = model.DeliveryDelivered
+		d.Error = ""
+	case retryable(status) && d.Attempts < q.maxAttempts:
+		result = "retried"
+		wait = q.backoff << (d.Attempts - 1)
+		d.Status = model.DeliveryPending
+		d.Error = deliveryError(err)
+		d.NextAttemptAt = d.LastAttemptAt.Add(wait)
+	default:
+		result = "failed"
+		d.Status = model.DeliveryFailed
+		d.Error = deliveryError(err)
+	}
+	metrics.WebhookDelivery(result)
+	if err = q.repo.UpdateDelivery(ctx, d); err != nil {
+		log.Warnf("Could not record attempt %d of delivery %d: %v", d.Attempts, d.Id, err)
+	}
+	if d.Status == model.DeliveryPending {
+		q.schedule(job.webhook, d)
+	}
+}
+
+// post sends the payload of the delivery, signed with the secret of the webhook together with the time of the attempt.
+// It returns the http status, which is 0 when the webhook couldn't be reached.
+func (q *WebhookQueue) post(ctx context.Context, webhook model.Webhook, d model.WebhookDelivery) (int, error) {
+	body := []byte(d.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-ConnectFour-Event", d.Event)
+	req.Header.Set("X-ConnectFour-Delivery", strconv.FormatInt(d.Id, 10))
+	timestamp := strconv.FormatInt(d.LastAttemptAt.Unix(), 10)
+	req.Header.Set("X-ConnectFour-Timestamp", timestamp)
+	req.Header.Set("X-ConnectFour-Signature-256", "sha256="+signPayload(webhook.Secret, timestamp, body))
+	response, err := q.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = response.Body.Close() }()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("the webhook responded with %s", response.Status)
+	}
+	return response.StatusCode, nil
+}
+
+// signPayload returns the hex HMAC-SHA256 of "<timestamp>.<body>" with the secret. The receiver computes it too, to
+// check that the payload came from this api, and checks that the timestamp is recent, so that a delivery can't be
+// replayed later.
+func signPayload(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature checks the X-ConnectFour-Timestamp and X-ConnectFour-Signature-256 headers of a delivery,
+// for receivers that are written in Go. It refuses timestamps that are further than WebhookSignatureTolerance from
+// now.
+func VerifyWebhookSignature(secret string, timestamp string, signature string, body []byte, now time.Time) bool {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || now.Sub(time.Unix(seconds, 0)).Abs() > WebhookSignatureTolerance {
+		return false
+	}
+	expected := "sha256=" + signPayload(secret, timestamp, body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// deliveryError returns the error as it's kept in the delivery log, which has room for 1000 characters.
+func deliveryError(err error) string {
+	message := []rune(err.Error())
+	return string(message[:min(len(message), 1000)])
+}
+
+// retryable returns true when a delivery that failed with the status may succeed later.
+func retryable(status int) bool {
+	return status == 0 || status == http.StatusTooManyRequests || status >= 500
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"connectfour/internal/db"
+	"connectfour/internal/model"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"slices"
+	"time"
+)
+
+// maxWebhookDeliveries is the most deliveries that are returned from the delivery log at once.
+const maxWebhookDeliveries = 50
+
+// WebhookService posts the events of games to the webhooks that players registered: when a game is created or
+// joined, when a move is played and when it finishes. Players get the events of their own games, admins can register
+// webhooks for all games.
+type WebhookService struct {
+	userService *UserService
+	repo        db.WebhookRepository
+	queue       *WebhookQueue
+	now         func() time.Time
+	lookup      lookupIP // resolves the host of a webhook url, which must be public.
+	// dispatch runs the lookup of the webhooks of an event, in the background so a move isn't slowed down by it.
+	dispatch func(deliver func())
+}
+
+func NewWebhookService(userService *UserService, repo db.WebhookRepository, queue *WebhookQueue) *WebhookService {
+	return &WebhookService{
+		userService: userService,
+		repo:        repo,
+		queue:       queue,
+		now:         time.Now,
+		lookup:      defaultLookupIP,
+		dispatch:    func(deliver func()) { go deliver() },
+	}
+}
+
+// Register adds a webhook for the user. The response has the secret that the payloads are signed with, which isn't
+// returned again.
+func (s WebhookService) Register(ctx context.Context, email string, req WebhookRequest) (WebhookResponse, error) {
+	user, err := s.userService.existingUser(ctx, email)
+	if err != nil {
+		return WebhookResponse{}, err
+	}
+	if req.AllGames && !user.Role.AtLeast(model.RoleAdmin) {
+		return WebhookResponse{}, errors.New("only admins can register a webhook for all games")
+	}
+	if err = s.validateWebhook(ctx, req); err != nil {
+		return WebhookResponse{}, err
+	}
+	secret := make([]byte, 32)
+	if _, err = rand.Read(secret); err != nil {
+		return WebhookResponse{}, err
+	}
+	events := make([]string, 0, len(req.Events))
+	for _, event := range slices.Compact(slices.Sorted(slices.Values(req.Events))) {
+		events = append(events, string(event))
+	}
+	w, err := s.repo.Create(ctx, model.Webhook{
+		OwnerId:   user.Id,
+		Url:       req.Url,
+		Secret:    hex.EncodeToString(secret),
+		Events:    events,
+		AllGames:  req.AllGames,
+		CreatedAt: s.now(),
+	})
+	if err != nil {
+		return WebhookResponse{}, errors.New("the webhook could not be saved")
+	}
+	resp := NewWebhookResponse(w)
+	resp.Secret = w.Secret
+	return resp, nil
+}
+
+func (s WebhookService) validateWebhook(ctx context.Context, req WebhookRequest) error {
+	if err := checkWebhookUrl(ctx, req.Url, s.lookup); err != nil {
+		return err
+	}
+	for _, event := range req.Events {
+		switch event {
+		case GameCreatedEvent, GameJoinedEvent, MovePlayedEvent, GameFinishedEvent, NotificationEvent:
+		default:
+			return fmt.Errorf("there's no game event %q", event)
+		}
+	}
+	return nil
+}
+
+// List returns the webhooks of the user.
+func (s WebhookService) List(ctx context.Context, email string) ([]WebhookResponse, error) {
+	webhooks, err := s.webhooksOf(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	output := make([]WebhookResponse, 0, len(webhooks))
+	for _, w := range webhooks {
+		output = append(output, NewWebhookResponse(w))
+	}
+	return output, nil
+}
+
+// Delete removes the webhook of the user, with its delivery log.
+func (s WebhookService) Delete(ctx context.Context, email string, id int64) error {
+	user, err := s.userService.existingUser(ctx, email)
+	if err != nil {
+		return err
+	}
+	deleted, err := s.repo.Delete(ctx, user.Id, id)
+	if err != nil {
+		return errors.New("the webhook could not be deleted")
+	}
+	if !deleted {
+		return fmt.Errorf("you have no webhook %d", id)
+	}
+	return nil
+}
+
+// Deliveries returns the newest deliveries to the webhook of the user.
+func (s WebhookService) Deliveries(ctx context.Context, email string, id int64) ([]WebhookDeliveryResponse, error) {
+	webhooks, err := s.webhooksOf(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.ContainsFunc(webhooks, func(w model.Webhook) bool { return w.Id == id }) {
+		return nil, fmt.Errorf("you have no webhook %d", id)
+	}
+	deliveries, err := s.repo.ListDeliveries(ctx, id, maxWebhookDeliveries)
+	if err != nil {
+		return nil, errors.New("the deliveries could not be read")
+	}
+	output := make([]WebhookDeliveryResponse, 0, len(deliveries))
+	for _, d := range deliveries {
+		output = append(output, NewWebhookDeliveryResponse(d))
+	}
+	return output, nil
+}
+
+func (s WebhookService) webhooksOf(ctx context.Context, email string) ([]model.Webhook, error) {
+	user, err := s.userService.existingUser(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	webhooks, err := s.repo.ListByOwner(ctx, user.Id)
+	if err != nil {
+		return nil, errors.New("the webhooks could not be read")
+	}
+	return webhooks, nil
+}
+
+// OnGameEvent queues the event for the webhooks that want it. Subscribe it with GamesService.Subscribe.
+func (s WebhookService) OnGameEvent(event GameEvent) {
+	occurredAt := s.now()
+	s.dispatch(func() {
+		if err := s.deliver(context.Background(), event, occurredAt); err != nil {
+			log.Warnf("Could not post %s of game %s to the webhooks: %v", event.Type, event.Game.Key, err)
+		}
+	})
+}
+
+func (s WebhookService) deliver(ctx context.Context, event GameEvent, occurredAt time.Time) error {
+	game := event.Game
+	// the invitee isn't playing yet, but hears about the game it's invited to.
+	var players []int64
+	for _, user := range []model.User{game.Player1, game.Player2, game.Invitee} {
+		if !user.Empty() && user.Id != 0 {
+			players = append(players, user.Id)
+		}
+	}
+	webhooks, err := s.repo.ListForPlayers(ctx, players...)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(WebhookPayload{
+		Event:      event.Type,
+		Actor:      event.Actor.Email,
+		OccurredAt: occurredAt,
+		Game:       NewGameStateResponse(game),
+	})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, w := range webhooks {
+		if !w.Wants(string(event.Type)) {
+			continue
+		}
+		if err = s.queue.Deliver(ctx, w, event.Type, game.Key, payload, occurredAt); err != nil {
+			errs = append(errs, fmt.Errorf("webhook %d: %w", w.Id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,341 @@
+package service
+
+import (
+	"connectfour/internal/db"
+	"connectfour/internal/model"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookReceiver records the requests that are posted to it, and responds with the statuses in turn, the last one
+// over and over.
+type webhookReceiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, request *http.Request) {
+	body, _ := io.ReadAll(request.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, request)
+	r.bodies = append(r.bodies, body)
+	status := r.statuses[min(len(r.requests), len(r.statuses))-1]
+	w.WriteHeader(status)
+}
+
+func (r *webhookReceiver) received() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+// mockedWebhookService looks up the webhooks right away instead of in the background, and retries after a millisecond.
+func mockedWebhookService(t *testing.T) (*WebhookService, *db.MockUserRepository, *db.MockWebhookRepository) {
+	ur := db.NewMockUserRepository()
+	repo := db.NewMockWebhookRepository()
+	queue := NewWebhookQueue(repo, http.DefaultClient, time.Millisecond)
+	t.Cleanup(queue.Close)
+	s := NewWebhookService(NewUserService(ur, 0), repo, queue)
+	s.dispatch = func(deliver func()) { deliver() }
+	s.lookup = fakeLookup
+	return s, ur, repo
+}
+
+// fakeLookup resolves example.com to its public address and internal.example.com to a private one, without DNS.
+func fakeLookup(_ context.Context, host string) ([]net.IP, error) {
+	switch host {
+	case "example.com":
+		return []net.IP{net.ParseIP("93.184.215.14")}, nil
+	case "internal.example.com":
+		return []net.IP{net.ParseIP("93.184.215.14"), net.ParseIP("10.0.0.7")}, nil
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+	return nil, errors.New("no such host")
+}
+
+// deliveryLog records the deliveries that the queue updates, which it does from its workers.
+type deliveryLog struct {
+	mu      sync.Mutex
+	updates []model.WebhookDelivery
+}
+
+func recordDeliveries(repo *db.MockWebhookRepository) *deliveryLog {
+	l := &deliveryLog{}
+	repo.On("UpdateDelivery", mock.Anything, mock.AnythingOfType("model.WebhookDelivery")).Return(nil).Run(func(args mock.Arguments) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.updates = append(l.updates, args.Get(1).(model.WebhookDelivery))
+	})
+	return l
+}
+
+func (l *deliveryLog) all() []model.WebhookDelivery {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.updates)
+}
+
+func (l *deliveryLog) last() model.WebhookDelivery {
+	if updates := l.all(); len(updates) > 0 {
+		return updates[len(updates)-1]
+	}
+	return model.WebhookDelivery{}
+}
+
+func (l *deliveryLog) reaches(status model.DeliveryStatus) func() bool {
+	return func() bool { return l.last().Status == status }
+}
+
+func TestWebhookService_OnGameEvent_SignsThePayload(t *testing.T) {
+	// Arrange
+	receiver := &webhookReceiver{statuses: []int{http.StatusOK}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	game, dick, sanae := startedGame()
+	s, _, repo := mockedWebhookService(t)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	payload, _ := json.Marshal(WebhookPayload{Event: MovePlayedEvent, Actor: dick.Email, OccurredAt: now, Game: NewGameStateResponse(game)})
+	webhook := model.Webhook{Id: 3, OwnerId: sanae.Id, Url: server.URL, Secret: "s3cr3t"}
+	queued := model.WebhookDelivery{
+		WebhookId:     webhook.Id,
+		Event:         string(MovePlayedEvent),
+		GameKey:       game.Key,
+		Payload:       string(payload),
+		Status:        model.DeliveryPending,
+		CreatedAt:     now,
+		NextAttemptAt: now,
+	}
+	repo.On("ListForPlayers", mock.Anything, []int64{dick.Id, sanae.Id}).Return([]model.Webhook{webhook}, nil)
+	added := queued
+	added.Id = 12
+	repo.On("AddDelivery", mock.Anything, queued).Return(added, nil)
+	deliveries := recordDeliveries(repo)
+
+	// Act
+	s.OnGameEvent(GameEvent{Type: MovePlayedEvent, Game: game, Actor: dick})
+
+	// Assert
+	assert.Eventually(t, deliveries.reaches(model.DeliveryDelivered), time.Second, time.Millisecond)
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	request, body := receiver.requests[0], receiver.bodies[0]
+	timestamp := request.Header.Get("X-ConnectFour-Timestamp")
+	assert.Equal(t, strconv.FormatInt(deliveries.last().LastAttemptAt.Unix(), 10), timestamp)
+	mac := hmac.New(sha256.New, []byte("s3cr3t"))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), request.Header.Get("X-ConnectFour-Signature-256"))
+	assert.Equal(t, "game.move", request.Header.Get("X-ConnectFour-Event"))
+	assert.Equal(t, "12", request.Header.Get("X-ConnectFour-Delivery"))
+	assert.JSONEq(t, string(payload), string(body))
+	assert.Equal(t, 1, deliveries.last().Attempts)
+}
+
+func TestWebhookService_OnGameEvent_OnlyWantedEvents(t *testing.T) {
+	// Arrange
+	game, dick, _ := startedGame()
+	s, _, repo := mockedWebhookService(t)
+	repo.On("ListForPlayers", mock.Anything, mock.Anything).Return([]model.Webhook{
+		{Id: 3, OwnerId: dick.Id, Url: "http://localhost", Events: []string{string(GameFinishedEvent)}},
+	}, nil)
+
+	// Act
+	s.OnGameEvent(GameEvent{Type: MovePlayedEvent, Game: game, Actor: dick})
+
+	// Assert
+	repo.AssertNotCalled(t, "AddDelivery", mock.Anything, mock.Anything)
+}
+
+func TestWebhookQueue_RetriesWithBackoff(t *testing.T) {
+	// Arrange
+	receiver := &webhookReceiver{statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusNoContent}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	repo := db.NewMockWebhookRepository()
+	deliveries := recordDeliveries(repo)
+	q := NewWebhookQueue(repo, http.DefaultClient, time.Millisecond)
+	defer q.Close()
+
+	// Act
+	q.Enqueue(model.Webhook{Id: 3, Url: server.URL}, model.WebhookDelivery{Id: 12, Payload: "{}", Status: model.DeliveryPending})
+
+	// Assert
+	assert.Eventually(t, deliveries.reaches(model.DeliveryDelivered), time.Second, time.Millisecond)
+	assert.Equal(t, 3, receiver.received())
+	updates := deliveries.all()
+	assert.Len(t, updates, 3, "Expected every attempt to be logged")
+	first, second := updates[0], updates[1]
+	assert.Equal(t, model.DeliveryPending, first.Status)
+	assert.Equal(t, http.StatusServiceUnavailable, first.ResponseStatus)
+	assert.Equal(t, time.Millisecond, first.NextAttemptAt.Sub(first.LastAttemptAt))
+	assert.Equal(t, 2*time.Millisecond, second.NextAttemptAt.Sub(second.LastAttemptAt), "Expected the backoff to double")
+	delivered := deliveries.last()
+	assert.Equal(t, 3, delivered.Attempts)
+	assert.Empty(t, delivered.Error)
+	assert.True(t, delivered.NextAttemptAt.IsZero())
+}
+
+func TestWebhookQueue_GivesUp(t *testing.T) {
+	// Arrange
+	refusing := &webhookReceiver{statuses: []int{http.StatusGone}}
+	failing := &webhookReceiver{statuses: []int{http.StatusInternalServerError}}
+	refusingServer, failingServer := httptest.NewServer(refusing), httptest.NewServer(failing)
+	defer refusingServer.Close()
+	defer failingServer.Close()
+	refusingRepo, failingRepo := db.NewMockWebhookRepository(), db.NewMockWebhookRepository()
+	refused, failed := recordDeliveries(refusingRepo), recordDeliveries(failingRepo)
+	refusingQueue := NewWebhookQueue(refusingRepo, http.DefaultClient, time.Millisecond)
+	failingQueue := NewWebhookQueue(failingRepo, http.DefaultClient, time.Millisecond)
+	defer refusingQueue.Close()
+	defer failingQueue.Close()
+
+	// Act
+	refusingQueue.Enqueue(model.Webhook{Id: 3, Url: refusingServer.URL}, model.WebhookDelivery{Id: 12, Payload: "{}"})
+	failingQueue.Enqueue(model.Webhook{Id: 4, Url: failingServer.URL}, model.WebhookDelivery{Id: 13, Payload: "{}"})
+
+	// Assert
+	assert.Eventually(t, refused.reaches(model.DeliveryFailed), time.Second, time.Millisecond)
+	assert.Eventually(t, failed.reaches(model.DeliveryFailed), time.Second, time.Millisecond)
+	assert.Equal(t, 1, refusing.received(), "Expected a delivery that the webhook refused not to be retried")
+	assert.Equal(t, maxWebhookAttempts, failing.received())
+	assert.Contains(t, failed.last().Error, "500")
+}
+
+func TestWebhookQueue_Resume(t *testing.T) {
+	// Arrange
+	receiver := &webhookReceiver{statuses: []int{http.StatusOK}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	repo := db.NewMockWebhookRepository()
+	deliveries := recordDeliveries(repo)
+	webhook := model.Webhook{Id: 3, Url: server.URL}
+	repo.On("ListPending", mock.Anything).Return([]model.PendingDelivery{
+		{Webhook: webhook, Delivery: model.WebhookDelivery{Id: 12, Payload: "{}", Status: model.DeliveryPending, Attempts: 2,
+			NextAttemptAt: time.Now().Add(-time.Minute)}},
+		{Webhook: webhook, Delivery: model.WebhookDelivery{Id: 13, Payload: "{}", Status: model.DeliveryPending, Attempts: 1,
+			NextAttemptAt: time.Now().Add(time.Hour)}},
+	}, nil)
+	q := NewWebhookQueue(repo, http.DefaultClient, time.Millisecond)
+	defer q.Close()
+
+	// Act
+	err := q.Resume(context.Background())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Eventually(t, deliveries.reaches(model.DeliveryDelivered), time.Second, time.Millisecond)
+	assert.Equal(t, 1, receiver.received(), "Expected only the delivery that is due to be posted right away")
+	assert.Equal(t, int64(12), deliveries.last().Id)
+	assert.Equal(t, 3, deliveries.last().Attempts)
+}
+
+func TestWebhookService_Register(t *testing.T) {
+	// Arrange
+	admin := user2
+	admin.Role = model.RoleAdmin
+	s, ur, repo := mockedWebhookService(t)
+	ur.On("FindByEmail", mock.Anything, user1.Email).Return(user1, nil)
+	ur.On("FindByEmail", mock.Anything, admin.Email).Return(admin, nil)
+	repo.On("Create", mock.Anything, mock.AnythingOfType("model.Webhook")).Return(model.Webhook{Id: 3, Secret: "s3cr3t"}, nil)
+
+	// Act
+	webhook, err := s.Register(context.Background(), user1.Email, WebhookRequest{
+		Url:    "https://example.com/hook",
+		Events: []GameEventType{GameFinishedEvent, GameCreatedEvent, GameFinishedEvent},
+	})
+	_, errAllGames := s.Register(context.Background(), user1.Email, WebhookRequest{Url: "https://example.com/hook", AllGames: true})
+	_, errAdmin := s.Register(context.Background(), admin.Email, WebhookRequest{Url: "https://example.com/hook", AllGames: true})
+	_, errUrl := s.Register(context.Background(), user1.Email, WebhookRequest{Url: "file:///etc/passwd"})
+	_, errEvent := s.Register(context.Background(), user1.Email, WebhookRequest{Url: "https://example.com/hook", Events: []GameEventType{"game.lost"}})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), webhook.Id)
+	assert.Equal(t, "s3cr3t", webhook.Secret, "Expected the secret to be returned when the webhook is registered")
+	created := repo.Calls[0].Arguments.Get(1).(model.Webhook)
+	assert.Equal(t, user1.Id, created.OwnerId)
+	assert.Len(t, created.Secret, 64)
+	assert.Equal(t, []string{"game.created", "game.finished"}, created.Events)
+	assert.ErrorContains(t, errAllGames, "only admins")
+	assert.NoError(t, errAdmin)
+	assert.Error(t, errUrl)
+	assert.Error(t, errEvent)
+	repo.AssertNumberOfCalls(t, "Create", 2)
+}
+
+func TestWebhookService_Register_OnlyPublicAddresses(t *testing.T) {
+	// Arrange
+	s, ur, _ := mockedWebhookService(t)
+	ur.On("FindByEmail", mock.Anything, user1.Email).Return(user1, nil)
+
+	// Act & Assert
+	for _, url := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://[::1]/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://192.168.1.1/hook",
+		"http://0.0.0.0/hook",
+		"https://internal.example.com/hook",
+		"https://unknown.example.com/hook",
+	} {
+		_, err := s.Register(context.Background(), user1.Email, WebhookRequest{Url: url})
+		assert.Error(t, err, "Expected %s to be refused", url)
+	}
+}
+
+func TestWebhookTransport_RefusesNonPublicAddresses(t *testing.T) {
+	// Arrange
+	receiver := &webhookReceiver{statuses: []int{http.StatusOK}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	client := &http.Client{Transport: WebhookTransport()}
+
+	// Act
+	_, err := client.Post(server.URL, "application/json", strings.NewReader("{}"))
+
+	// Assert
+	assert.ErrorContains(t, err, "isn't public")
+	assert.Equal(t, 0, receiver.received())
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	// Arrange
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	body := []byte(`{"event": "game.move"}`)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	signature := "sha256=" + signPayload("s3cr3t", timestamp, body)
+
+	// Act
+	valid := VerifyWebhookSignature("s3cr3t", timestamp, signature, body, now.Add(time.Minute))
+	replayed := VerifyWebhookSignature("s3cr3t", timestamp, signature, body, now.Add(WebhookSignatureTolerance+time.Second))
+	otherTime := VerifyWebhookSignature("s3cr3t", strconv.FormatInt(now.Unix()+60, 10), signature, body, now)
+	otherSecret := VerifyWebhookSignature("other", timestamp, signature, body, now)
+
+	// Assert
+	assert.True(t, valid)
+	assert.False(t, replayed, "Expected a delivery that's too old to be refused")
+	assert.False(t, otherTime, "Expected the timestamp to be signed")
+	assert.False(t, otherSecret)
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// lookupIP resolves the host of a webhook url to its addresses.
+type lookupIP func(ctx context.Context, host string) ([]net.IP, error)
+
+func defaultLookupIP(ctx context.Context, host string) ([]net.IP, error) {
+	return net.DefaultResolver.LookupIP(ctx, "ip", host)
+}
+
+// nonPublicNetworks are the networks that aren't covered by the checks of net.IP, but that aren't public either:
+// "this network" and the shared address space of carrier-grade NAT.
+var nonPublicNetworks = []*net.IPNet{
+	{IP: net.IPv4(0, 0, 0, 0), Mask: net.CIDRMask(8, 32)},
+	{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)},
+}
+
+// checkWebhookUrl refuses urls that aren't http or https, and urls of hosts that resolve to an address that isn't
+// public, so the api can't be used to reach the services on its own network.
+func checkWebhookUrl(ctx context.Context, rawUrl string, lookup lookupIP) error {
+	u, err := url.Parse(rawUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("the webhook url must be a http or https url")
+	}
+	ips, err := lookup(ctx, u.Hostname())
+	if err != nil || len(ips) == 0 {
+		return fmt.Errorf("the host %s of the webhook url can't be resolved", u.Hostname())
+	}
+	for _, ip := range ips {
+		if !publicAddress(ip) {
+			return errors.New("the webhook url must be on a public address")
+		}
+	}
+	return nil
+}
+
+// publicAddress returns false for loopback, private, link-local (like the metadata service of a cloud), unspecified
+// and multicast addresses.
+func publicAddress(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// WebhookTransport returns the transport that posts to the webhooks. It refuses to connect to addresses that aren't
+// public, which are checked again when dialing because a host may resolve to another address than at registration.
+func WebhookTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   refuseNonPublicAddress,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // a proxy would be checked instead of the webhook.
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+// refuseNonPublicAddress is the Control of the dialer of the webhooks, which is called with the resolved address.
+func refuseNonPublicAddress(_ string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !publicAddress(ip) {
+		return fmt.Errorf("the webhook address %s isn't public", address)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+// Package tracing follows requests with OpenTelemetry spans, from the clients through the handlers and services to
+// the database queries, so that we can see where the time of a slow request went.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const instrumentation = "connectfour"
+
+// Setup installs the tracer provider of the service and the W3C trace context propagator. The exporter is chosen with
+// CONNECT_FOUR_TRACES_EXPORTER: "otlp" sends the spans to the collector that is configured with the standard
+// OTEL_EXPORTER_OTLP_* variables, "stdout" writes them to out as JSON, and "none" (the default) doesn't record spans,
+// but still passes the trace context of incoming requests on. The returned func flushes the spans that weren't
+// exported yet; call it before exiting.
+func Setup(ctx context.Context, service string, out io.Writer) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	name := strings.ToLower(os.Getenv("CONNECT_FOUR_TRACES_EXPORTER"))
+	if name == "" || name == "none" {
+		return func(context.Context) error { return nil }, nil
+	}
+	exporter, err := newExporter(ctx, name, out)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(service)))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+func newExporter(ctx context.Context, name string, out io.Writer) (sdktrace.SpanExporter, error) {
+	switch name {
+	case "otlp":
+		return otlptracehttp.New(ctx)
+	case "stdout":
+		return stdouttrace.New(stdouttrace.WithWriter(out), stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("unknown traces exporter '%s', use otlp, stdout or none", name)
+	}
+}
+
+// Start starts a span, as a child of the span in ctx (if any). End it with End.
+func Start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentation).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// StartQuery starts the span of a query of the repository method.
+func StartQuery(ctx context.Context, repository string, method string) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentation).Start(ctx, "db."+repository+"."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemNameMariaDB, semconv.DBCollectionName(repository), semconv.DBOperationName(method)))
+}
+
+// End ends the span, and marks it as failed when err isn't nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Middleware starts a server span for every request, which continues the trace of the client when the request has a
+// traceparent header. The span is named after the chi route pattern (like POST /games/{key}/play), so it must be
+// used on the router, before the routes.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := otel.Tracer(instrumentation).Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.HTTPRequestMethodKey.String(r.Method), semconv.URLPath(r.URL.Path)))
+		defer span.End()
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		if rctx := chi.RouteContext(ctx); rctx != nil && rctx.RoutePattern() != "" {
+			span.SetName(r.Method + " " + rctx.RoutePattern())
+			span.SetAttributes(semconv.HTTPRoute(rctx.RoutePattern()))
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
+
+// Transport wraps the base transport (http.DefaultTransport when nil) with a client span for every request, and passes
+// the trace context on to the api in the traceparent header.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return roundTripper{base: base}
+}
+
+type roundTripper struct {
+	base http.RoundTripper
+}
+
+func (t roundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx, span := otel.Tracer(instrumentation).Start(r.Context(), r.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.HTTPRequestMethodKey.String(r.Method), semconv.URLFull(r.URL.Redacted())))
+
+	// a RoundTripper mustn't change the request, so the headers go on a copy.
+	r = r.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(r.Header))
+	response, err := t.base.RoundTrip(r)
+	if err != nil {
+		End(span, err)
+		return nil, err
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(response.StatusCode))
+	if response.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(response.StatusCode))
+	}
+	span.End()
+	return response, nil
+}
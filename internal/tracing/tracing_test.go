@@ -0,0 +1,106 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// record installs a tracer provider that keeps the ended spans in memory.
+func record(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestMiddleware_ContinuesTheTraceOfTheClient(t *testing.T) {
+	// Arrange
+	recorder := record(t)
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Post("/games/{key}/play", func(w http.ResponseWriter, r *http.Request) {
+		_, span := Start(r.Context(), "GamesService.PlayMove")
+		span.End()
+	})
+	server := httptest.NewServer(r)
+	defer server.Close()
+	client := &http.Client{Transport: Transport(nil)}
+
+	// Act
+	response, err := client.Post(server.URL+"/games/sals-nil-abri/play", "application/json", nil)
+
+	// Assert
+	assert.NoError(t, err)
+	_ = response.Body.Close()
+	spans := recorder.Ended()
+	assert.Len(t, spans, 3)
+	names := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range spans {
+		names[span.Name()] = span
+		assert.Equal(t, spans[0].SpanContext().TraceID(), span.SpanContext().TraceID(), "Expected one trace")
+	}
+	assert.Contains(t, names, "POST")
+	assert.Contains(t, names, "POST /games/{key}/play")
+	assert.Contains(t, names, "GamesService.PlayMove")
+	assert.Equal(t, names["POST"].SpanContext().SpanID(), names["POST /games/{key}/play"].Parent().SpanID())
+	assert.Equal(t, names["POST /games/{key}/play"].SpanContext().SpanID(), names["GamesService.PlayMove"].Parent().SpanID())
+}
+
+func TestMiddleware_MarksServerErrors(t *testing.T) {
+	// Arrange
+	recorder := record(t)
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	// Act
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/readyz", nil))
+
+	// Assert
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "GET /readyz", spans[0].Name())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+}
+
+func TestEnd_RecordsTheError(t *testing.T) {
+	// Arrange
+	recorder := record(t)
+	_, span := Start(context.Background(), "UserService.FindUserByEmail")
+
+	// Act
+	End(span, errors.New("user not found"))
+
+	// Assert
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "user not found", spans[0].Status().Description)
+	assert.Len(t, spans[0].Events(), 1, "Expected the error to be recorded as an event")
+}
+
+func TestSetup_UnknownExporter(t *testing.T) {
+	// Arrange
+	t.Setenv("CONNECT_FOUR_TRACES_EXPORTER", "jaeger")
+
+	// Act
+	_, err := Setup(context.Background(), "connectfour-test", nil)
+
+	// Assert
+	assert.ErrorContains(t, err, "unknown traces exporter 'jaeger'")
+}